@@ -9,7 +9,9 @@ import (
 
 	"grove-studio/internal/config"
 	"grove-studio/internal/database"
+	"grove-studio/internal/gguf"
 	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
 	"grove-studio/internal/services"
 	"grove-studio/internal/utils"
 
@@ -22,6 +24,11 @@ type App struct {
 	logger               *utils.Logger
 	settingService       *services.SettingService
 	cloudLLMModelService *services.CloudLLMModelService
+	providerModelService *services.ProviderModelService
+	localModelService    *services.LocalModelService
+	localRuntimeService  *services.LocalRuntimeService
+	systemProbeService   *services.SystemProbeService
+	assistantService     *services.AssistantService
 	conversationService  *services.ConversationService
 	messageService       *services.MessageService
 }
@@ -37,9 +44,14 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.logger = utils.NewLogger(ctx)
 	a.settingService = services.NewSettingService(ctx)
-	a.cloudLLMModelService = services.NewCloudLLMModelService(ctx)
+	a.localRuntimeService = services.NewLocalRuntimeService(ctx)
+	a.cloudLLMModelService = services.NewCloudLLMModelService(ctx, a.localRuntimeService)
+	a.providerModelService = services.NewProviderModelService(ctx, a.localRuntimeService)
+	a.localModelService = services.NewLocalModelService(ctx, a.localRuntimeService)
+	a.systemProbeService = services.NewSystemProbeService(ctx)
+	a.assistantService = services.NewAssistantService(ctx)
 	a.conversationService = services.NewConversationService(ctx)
-	a.messageService = services.NewMessageService(ctx)
+	a.messageService = services.NewMessageService(ctx, a.localRuntimeService)
 
 	// 获取应用数据路径
 	appDataPath, err := utils.GetAppDataPath()
@@ -84,6 +96,9 @@ func (a *App) startup(ctx context.Context) {
 	dst := []interface{}{
 		&models.Setting{},
 		&models.CloudLLMModel{},
+		&models.ProviderModel{},
+		&models.LocalModel{},
+		&models.Assistant{},
 		&models.Conversation{},
 		&models.Message{},
 	}
@@ -92,6 +107,14 @@ func (a *App) startup(ctx context.Context) {
 		return
 	}
 
+	// 上次退出时未完成的下载改为暂停，由用户决定是否继续
+	if err := a.localModelService.ResetInterrupted(); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("恢复本地模型下载状态失败: %v", err))
+	}
+	if err := a.localRuntimeService.ResetManaged(); err != nil {
+		runtime.LogError(ctx, fmt.Sprintf("重置本地模型配置失败: %v", err))
+	}
+
 	runtime.LogInfo(ctx, "应用初始化完成")
 }
 
@@ -112,6 +135,11 @@ func (a *App) beforeClose(ctx context.Context) (prevent bool) {
 
 // shutdown is called at application termination
 func (a *App) shutdown(ctx context.Context) {
+	// 暂停正在进行的下载，保留已下载的部分
+	a.localModelService.Shutdown()
+	// 停止本地推理服务，需要在关闭数据库之前完成
+	a.localRuntimeService.Shutdown()
+
 	// 关闭数据库连接
 	if err := database.CloseDB(); err != nil {
 		runtime.LogError(ctx, fmt.Sprintf("关闭数据库连接失败: %v", err))
@@ -166,6 +194,140 @@ func (a *App) ToggleCloudLLMModelEnabled(id uint, enabled bool) error {
 	return a.cloudLLMModelService.ToggleEnabled(id, enabled)
 }
 
+// TestCloudLLMModel 测试云端模型配置是否可用，结果会保存到模型上
+func (a *App) TestCloudLLMModel(id uint) (*services.CloudLLMHealth, error) {
+	return a.cloudLLMModelService.Test(id)
+}
+
+// GetLocalServerStatus 检查本地模型服务是否可以访问，并列出已安装的模型
+func (a *App) GetLocalServerStatus(id uint) (*services.LocalServerStatus, error) {
+	return a.cloudLLMModelService.ServerStatus(id)
+}
+
+// PullLocalModel 让本地模型服务下载模型，进度通过 model-pull-progress 事件返回
+func (a *App) PullLocalModel(id uint, model string) error {
+	return a.cloudLLMModelService.PullModel(id, model)
+}
+
+// CancelLocalModelPull 取消正在进行的模型下载
+func (a *App) CancelLocalModelPull(id uint, model string) error {
+	return a.cloudLLMModelService.CancelPull(id, model)
+}
+
+// GetProviderPresets 获取内置的提供商预设
+func (a *App) GetProviderPresets() []providers.Preset {
+	return a.providerModelService.Presets()
+}
+
+// GetProviderModels 获取已保存的提供商模型列表
+func (a *App) GetProviderModels(cloudLLMId uint) ([]models.ProviderModel, error) {
+	return a.providerModelService.GetList(cloudLLMId)
+}
+
+// RefreshProviderModels 从提供商接口刷新模型列表
+func (a *App) RefreshProviderModels(cloudLLMId uint) ([]models.ProviderModel, error) {
+	return a.providerModelService.Refresh(cloudLLMId)
+}
+
+// GetModelCapabilities 获取模型的能力和支持的参数
+func (a *App) GetModelCapabilities(cloudLLMId uint, modelName string) (*providers.Capabilities, error) {
+	return a.providerModelService.Capabilities(cloudLLMId, modelName)
+}
+
+// ----------------------------- 本地模型相关API -----------------------------
+
+// GetLocalModels 获取本地模型列表
+func (a *App) GetLocalModels() ([]models.LocalModel, error) {
+	return a.localModelService.GetList()
+}
+
+// AddLocalModel 添加本地模型并开始下载，进度通过 local-model-progress 事件发送
+func (a *App) AddLocalModel(params services.LocalModelParams) (*models.LocalModel, error) {
+	return a.localModelService.Add(params)
+}
+
+// StartLocalModelDownload 开始或继续下载本地模型
+func (a *App) StartLocalModelDownload(id uint) error {
+	return a.localModelService.Start(id)
+}
+
+// PauseLocalModelDownload 暂停下载本地模型
+func (a *App) PauseLocalModelDownload(id uint) error {
+	return a.localModelService.Pause(id)
+}
+
+// CancelLocalModelDownload 取消下载并删除已下载的部分
+func (a *App) CancelLocalModelDownload(id uint) error {
+	return a.localModelService.Cancel(id)
+}
+
+// DeleteLocalModel 删除本地模型及其文件
+func (a *App) DeleteLocalModel(id uint) error {
+	return a.localModelService.Delete(id)
+}
+
+// GetLocalModelInfo 读取已下载模型的GGUF信息，如架构、参数量、量化类型和对话模板
+func (a *App) GetLocalModelInfo(id uint) (*gguf.Metadata, error) {
+	return a.localModelService.Info(id)
+}
+
+// GetLocalModelDiskUsage 获取本地模型占用的磁盘空间
+func (a *App) GetLocalModelDiskUsage() (*services.LocalModelDiskUsage, error) {
+	return a.localModelService.DiskUsage()
+}
+
+// StartLocalModel 启动本地模型的推理服务，启动后会出现在对话模型列表中
+func (a *App) StartLocalModel(id uint) (*services.LocalRuntimeStatus, error) {
+	return a.localRuntimeService.Start(id)
+}
+
+// StopLocalModel 停止本地模型的推理服务
+func (a *App) StopLocalModel(id uint) error {
+	return a.localRuntimeService.Stop(id)
+}
+
+// GetLocalRuntimeStatus 获取所有本地推理服务的状态
+func (a *App) GetLocalRuntimeStatus() []services.LocalRuntimeStatus {
+	return a.localRuntimeService.GetStatusList()
+}
+
+// GetSystemInfo 获取本机的CPU、内存和磁盘信息
+func (a *App) GetSystemInfo() (*services.SystemInfo, error) {
+	return a.systemProbeService.Probe()
+}
+
+// GetModelRecommendations 根据本机硬件为可下载的本地模型评分，按推荐程度排序
+func (a *App) GetModelRecommendations() ([]services.ModelRecommendation, error) {
+	return a.systemProbeService.Recommend()
+}
+
+// ----------------------------- 助手相关API -----------------------------
+
+// GetAssistants 分页获取助手列表
+func (a *App) GetAssistants(page, size int) (*services.AssistantPageResult, error) {
+	return a.assistantService.GetList(page, size)
+}
+
+// GetAssistantByID 获取助手详情
+func (a *App) GetAssistantByID(id uint) (*models.Assistant, error) {
+	return a.assistantService.GetByID(id)
+}
+
+// CreateAssistant 创建助手
+func (a *App) CreateAssistant(assistant *models.Assistant) error {
+	return a.assistantService.Create(assistant)
+}
+
+// UpdateAssistant 更新助手
+func (a *App) UpdateAssistant(assistant *models.Assistant) error {
+	return a.assistantService.Update(assistant)
+}
+
+// DeleteAssistant 删除助手
+func (a *App) DeleteAssistant(id uint) error {
+	return a.assistantService.Delete(id)
+}
+
 // ----------------------------- 会话相关API -----------------------------
 
 // GetConversationList 分页查询会话列表
@@ -178,6 +340,16 @@ func (a *App) DestroyConversation(id int) error {
 	return a.conversationService.Destroy(id)
 }
 
+// RenameConversation 修改会话标题
+func (a *App) RenameConversation(id int, title string) error {
+	return a.conversationService.Rename(id, title)
+}
+
+// SetConversationAssistant 为会话绑定助手
+func (a *App) SetConversationAssistant(id, assistantId int) error {
+	return a.conversationService.BindAssistant(id, assistantId)
+}
+
 // ----------------------------- 聊天相关API -----------------------------
 
 // GetMessageList 获取历史消息记录
@@ -189,3 +361,33 @@ func (a *App) GetMessageList(conversationId, minId, size int) (*services.Message
 func (a *App) StreamRequestMessage(params services.MessageRequestParams) (int64, error) {
 	return a.messageService.Request(params)
 }
+
+// RetryMessage 重新生成失败的回答，可以指定其他模型
+func (a *App) RetryMessage(messageId int, params services.MessageRequestParams) (int64, error) {
+	return a.messageService.Retry(messageId, params)
+}
+
+// RegenerateMessage 为同一问题生成新的回答分支
+func (a *App) RegenerateMessage(messageId int, params services.MessageRequestParams) (int64, error) {
+	return a.messageService.Regenerate(messageId, params)
+}
+
+// EditMessage 修改问题后重新发送，生成新的分支
+func (a *App) EditMessage(messageId int, params services.MessageRequestParams) (int64, error) {
+	return a.messageService.Edit(messageId, params)
+}
+
+// GetMessageSiblings 获取同一位置的所有分支消息
+func (a *App) GetMessageSiblings(messageId int) ([]models.Message, error) {
+	return a.messageService.Siblings(messageId)
+}
+
+// SwitchMessageBranch 切换到指定消息所在的分支
+func (a *App) SwitchMessageBranch(messageId int) error {
+	return a.messageService.SwitchBranch(messageId)
+}
+
+// StopGeneration 中止正在生成的回答
+func (a *App) StopGeneration(requestId string) error {
+	return a.messageService.Stop(requestId)
+}
@@ -5,6 +5,7 @@ import './style.css';
 import router from './router/index'
 import { toast } from './plugins/toast'
 import { initGlobalToast } from './utils/toast'
+import { loadProviderPresets } from './constants/LLMProviders'
 
 const pinia = createPinia()
 const app = createApp(App)
@@ -14,3 +15,6 @@ app.mount('#app')
 
 // 初始化全局 toast
 initGlobalToast()
+
+// 加载提供商预设
+loadProviderPresets().catch(error => console.error('加载提供商预设失败:', error))
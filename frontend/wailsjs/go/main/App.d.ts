@@ -1,31 +1,101 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {services} from '../models';
 import {models} from '../models';
 import {config} from '../models';
-import {services} from '../models';
+import {gguf} from '../models';
+import {providers} from '../models';
+
+export function AddLocalModel(arg1:services.LocalModelParams):Promise<models.LocalModel>;
+
+export function CancelLocalModelDownload(arg1:number):Promise<void>;
+
+export function CancelLocalModelPull(arg1:number,arg2:string):Promise<void>;
+
+export function CreateAssistant(arg1:models.Assistant):Promise<void>;
 
 export function CreateCloudLLMModel(arg1:models.CloudLLMModel):Promise<void>;
 
+export function DeleteAssistant(arg1:number):Promise<void>;
+
 export function DeleteCloudLLMModel(arg1:number):Promise<void>;
 
+export function DeleteLocalModel(arg1:number):Promise<void>;
+
 export function DestroyConversation(arg1:number):Promise<void>;
 
+export function EditMessage(arg1:number,arg2:services.MessageRequestParams):Promise<number>;
+
 export function GetAppConfig():Promise<config.AppConfig>;
 
+export function GetAssistantByID(arg1:number):Promise<models.Assistant>;
+
+export function GetAssistants(arg1:number,arg2:number):Promise<services.AssistantPageResult>;
+
 export function GetCloudLLMModelByID(arg1:number):Promise<models.CloudLLMModel>;
 
 export function GetCloudLLMModels(arg1:number,arg2:number):Promise<services.CloudLLMModelPageResult>;
 
 export function GetConversationList(arg1:number,arg2:number,arg3:string):Promise<services.ConversationPageResult>;
 
+export function GetLocalModelDiskUsage():Promise<services.LocalModelDiskUsage>;
+
+export function GetLocalModelInfo(arg1:number):Promise<gguf.Metadata>;
+
+export function GetLocalModels():Promise<Array<models.LocalModel>>;
+
+export function GetLocalRuntimeStatus():Promise<Array<services.LocalRuntimeStatus>>;
+
+export function GetLocalServerStatus(arg1:number):Promise<services.LocalServerStatus>;
+
 export function GetMessageList(arg1:number,arg2:number,arg3:number):Promise<services.MessagePageResult>;
 
+export function GetMessageSiblings(arg1:number):Promise<Array<models.Message>>;
+
+export function GetModelCapabilities(arg1:number,arg2:string):Promise<providers.Capabilities>;
+
+export function GetModelRecommendations():Promise<Array<services.ModelRecommendation>>;
+
+export function GetProviderModels(arg1:number):Promise<Array<models.ProviderModel>>;
+
+export function GetProviderPresets():Promise<Array<providers.Preset>>;
+
 export function GetSetting(arg1:string):Promise<string>;
 
+export function GetSystemInfo():Promise<services.SystemInfo>;
+
+export function PauseLocalModelDownload(arg1:number):Promise<void>;
+
+export function PullLocalModel(arg1:number,arg2:string):Promise<void>;
+
+export function RefreshProviderModels(arg1:number):Promise<Array<models.ProviderModel>>;
+
+export function RegenerateMessage(arg1:number,arg2:services.MessageRequestParams):Promise<number>;
+
+export function RenameConversation(arg1:number,arg2:string):Promise<void>;
+
+export function RetryMessage(arg1:number,arg2:services.MessageRequestParams):Promise<number>;
+
+export function SetConversationAssistant(arg1:number,arg2:number):Promise<void>;
+
 export function SetSetting(arg1:string,arg2:string):Promise<void>;
 
+export function StartLocalModel(arg1:number):Promise<services.LocalRuntimeStatus>;
+
+export function StartLocalModelDownload(arg1:number):Promise<void>;
+
+export function StopGeneration(arg1:string):Promise<void>;
+
+export function StopLocalModel(arg1:number):Promise<void>;
+
 export function StreamRequestMessage(arg1:services.MessageRequestParams):Promise<number>;
 
+export function SwitchMessageBranch(arg1:number):Promise<void>;
+
+export function TestCloudLLMModel(arg1:number):Promise<services.CloudLLMHealth>;
+
 export function ToggleCloudLLMModelEnabled(arg1:number,arg2:boolean):Promise<void>;
 
+export function UpdateAssistant(arg1:models.Assistant):Promise<void>;
+
 export function UpdateCloudLLMModel(arg1:models.CloudLLMModel):Promise<void>;
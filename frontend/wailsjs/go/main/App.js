@@ -2,22 +2,58 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddLocalModel(arg1) {
+  return window['go']['main']['App']['AddLocalModel'](arg1);
+}
+
+export function CancelLocalModelDownload(arg1) {
+  return window['go']['main']['App']['CancelLocalModelDownload'](arg1);
+}
+
+export function CancelLocalModelPull(arg1, arg2) {
+  return window['go']['main']['App']['CancelLocalModelPull'](arg1, arg2);
+}
+
+export function CreateAssistant(arg1) {
+  return window['go']['main']['App']['CreateAssistant'](arg1);
+}
+
 export function CreateCloudLLMModel(arg1) {
   return window['go']['main']['App']['CreateCloudLLMModel'](arg1);
 }
 
+export function DeleteAssistant(arg1) {
+  return window['go']['main']['App']['DeleteAssistant'](arg1);
+}
+
 export function DeleteCloudLLMModel(arg1) {
   return window['go']['main']['App']['DeleteCloudLLMModel'](arg1);
 }
 
+export function DeleteLocalModel(arg1) {
+  return window['go']['main']['App']['DeleteLocalModel'](arg1);
+}
+
 export function DestroyConversation(arg1) {
   return window['go']['main']['App']['DestroyConversation'](arg1);
 }
 
+export function EditMessage(arg1, arg2) {
+  return window['go']['main']['App']['EditMessage'](arg1, arg2);
+}
+
 export function GetAppConfig() {
   return window['go']['main']['App']['GetAppConfig']();
 }
 
+export function GetAssistantByID(arg1) {
+  return window['go']['main']['App']['GetAssistantByID'](arg1);
+}
+
+export function GetAssistants(arg1, arg2) {
+  return window['go']['main']['App']['GetAssistants'](arg1, arg2);
+}
+
 export function GetCloudLLMModelByID(arg1) {
   return window['go']['main']['App']['GetCloudLLMModelByID'](arg1);
 }
@@ -30,26 +66,126 @@ export function GetConversationList(arg1, arg2, arg3) {
   return window['go']['main']['App']['GetConversationList'](arg1, arg2, arg3);
 }
 
+export function GetLocalModelDiskUsage() {
+  return window['go']['main']['App']['GetLocalModelDiskUsage']();
+}
+
+export function GetLocalModelInfo(arg1) {
+  return window['go']['main']['App']['GetLocalModelInfo'](arg1);
+}
+
+export function GetLocalModels() {
+  return window['go']['main']['App']['GetLocalModels']();
+}
+
+export function GetLocalRuntimeStatus() {
+  return window['go']['main']['App']['GetLocalRuntimeStatus']();
+}
+
+export function GetLocalServerStatus(arg1) {
+  return window['go']['main']['App']['GetLocalServerStatus'](arg1);
+}
+
 export function GetMessageList(arg1, arg2, arg3) {
   return window['go']['main']['App']['GetMessageList'](arg1, arg2, arg3);
 }
 
+export function GetMessageSiblings(arg1) {
+  return window['go']['main']['App']['GetMessageSiblings'](arg1);
+}
+
+export function GetModelCapabilities(arg1, arg2) {
+  return window['go']['main']['App']['GetModelCapabilities'](arg1, arg2);
+}
+
+export function GetModelRecommendations() {
+  return window['go']['main']['App']['GetModelRecommendations']();
+}
+
+export function GetProviderModels(arg1) {
+  return window['go']['main']['App']['GetProviderModels'](arg1);
+}
+
+export function GetProviderPresets() {
+  return window['go']['main']['App']['GetProviderPresets']();
+}
+
 export function GetSetting(arg1) {
   return window['go']['main']['App']['GetSetting'](arg1);
 }
 
+export function GetSystemInfo() {
+  return window['go']['main']['App']['GetSystemInfo']();
+}
+
+export function PauseLocalModelDownload(arg1) {
+  return window['go']['main']['App']['PauseLocalModelDownload'](arg1);
+}
+
+export function PullLocalModel(arg1, arg2) {
+  return window['go']['main']['App']['PullLocalModel'](arg1, arg2);
+}
+
+export function RefreshProviderModels(arg1) {
+  return window['go']['main']['App']['RefreshProviderModels'](arg1);
+}
+
+export function RegenerateMessage(arg1, arg2) {
+  return window['go']['main']['App']['RegenerateMessage'](arg1, arg2);
+}
+
+export function RenameConversation(arg1, arg2) {
+  return window['go']['main']['App']['RenameConversation'](arg1, arg2);
+}
+
+export function RetryMessage(arg1, arg2) {
+  return window['go']['main']['App']['RetryMessage'](arg1, arg2);
+}
+
+export function SetConversationAssistant(arg1, arg2) {
+  return window['go']['main']['App']['SetConversationAssistant'](arg1, arg2);
+}
+
 export function SetSetting(arg1, arg2) {
   return window['go']['main']['App']['SetSetting'](arg1, arg2);
 }
 
+export function StartLocalModel(arg1) {
+  return window['go']['main']['App']['StartLocalModel'](arg1);
+}
+
+export function StartLocalModelDownload(arg1) {
+  return window['go']['main']['App']['StartLocalModelDownload'](arg1);
+}
+
+export function StopGeneration(arg1) {
+  return window['go']['main']['App']['StopGeneration'](arg1);
+}
+
+export function StopLocalModel(arg1) {
+  return window['go']['main']['App']['StopLocalModel'](arg1);
+}
+
 export function StreamRequestMessage(arg1) {
   return window['go']['main']['App']['StreamRequestMessage'](arg1);
 }
 
+export function SwitchMessageBranch(arg1) {
+  return window['go']['main']['App']['SwitchMessageBranch'](arg1);
+}
+
+export function TestCloudLLMModel(arg1) {
+  return window['go']['main']['App']['TestCloudLLMModel'](arg1);
+}
+
 export function ToggleCloudLLMModelEnabled(arg1, arg2) {
   return window['go']['main']['App']['ToggleCloudLLMModelEnabled'](arg1, arg2);
 }
 
+export function UpdateAssistant(arg1) {
+  return window['go']['main']['App']['UpdateAssistant'](arg1);
+}
+
 export function UpdateCloudLLMModel(arg1) {
   return window['go']['main']['App']['UpdateCloudLLMModel'](arg1);
 }
@@ -19,8 +19,135 @@ export namespace config {
 
 }
 
+export namespace gguf {
+	
+	export class Tokenizer {
+	    model: string;
+	    pre: string;
+	    vocab_size: number;
+	    bos_token: string;
+	    eos_token: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Tokenizer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.pre = source["pre"];
+	        this.vocab_size = source["vocab_size"];
+	        this.bos_token = source["bos_token"];
+	        this.eos_token = source["eos_token"];
+	    }
+	}
+	export class Metadata {
+	    version: number;
+	    name: string;
+	    architecture: string;
+	    parameter_count: number;
+	    quantization: string;
+	    context_length: number;
+	    embedding_length: number;
+	    block_count: number;
+	    tensor_count: number;
+	    tokenizer: Tokenizer;
+	    chat_template: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Metadata(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.name = source["name"];
+	        this.architecture = source["architecture"];
+	        this.parameter_count = source["parameter_count"];
+	        this.quantization = source["quantization"];
+	        this.context_length = source["context_length"];
+	        this.embedding_length = source["embedding_length"];
+	        this.block_count = source["block_count"];
+	        this.tensor_count = source["tensor_count"];
+	        this.tokenizer = this.convertValues(source["tokenizer"], Tokenizer);
+	        this.chat_template = source["chat_template"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace models {
 	
+	export class Assistant {
+	    id: number;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	    name: string;
+	    description: string;
+	    system_prompt: string;
+	    cloud_llm_model_id: number;
+	    model_name: string;
+	    temperature?: number;
+	    max_tokens: number;
+	    history_length: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Assistant(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.system_prompt = source["system_prompt"];
+	        this.cloud_llm_model_id = source["cloud_llm_model_id"];
+	        this.model_name = source["model_name"];
+	        this.temperature = source["temperature"];
+	        this.max_tokens = source["max_tokens"];
+	        this.history_length = source["history_length"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class CloudLLMModel {
 	    id: number;
 	    // Go type: time
@@ -32,6 +159,20 @@ export namespace models {
 	    endpoint: string;
 	    api_key: string;
 	    enabled: boolean;
+	    local_model_id: number;
+	    proxy: string;
+	    headers: Record<string, string>;
+	    request_timeout: number;
+	    idle_timeout: number;
+	    ca_cert_path: string;
+	    requests_per_minute: number;
+	    tokens_per_minute: number;
+	    max_concurrency: number;
+	    health_status: string;
+	    health_message: string;
+	    health_latency_ms: number;
+	    // Go type: time
+	    health_checked_at?: any;
 	
 	    static createFrom(source: any = {}) {
 	        return new CloudLLMModel(source);
@@ -47,6 +188,19 @@ export namespace models {
 	        this.endpoint = source["endpoint"];
 	        this.api_key = source["api_key"];
 	        this.enabled = source["enabled"];
+	        this.local_model_id = source["local_model_id"];
+	        this.proxy = source["proxy"];
+	        this.headers = source["headers"];
+	        this.request_timeout = source["request_timeout"];
+	        this.idle_timeout = source["idle_timeout"];
+	        this.ca_cert_path = source["ca_cert_path"];
+	        this.requests_per_minute = source["requests_per_minute"];
+	        this.tokens_per_minute = source["tokens_per_minute"];
+	        this.max_concurrency = source["max_concurrency"];
+	        this.health_status = source["health_status"];
+	        this.health_message = source["health_message"];
+	        this.health_latency_ms = source["health_latency_ms"];
+	        this.health_checked_at = this.convertValues(source["health_checked_at"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -74,6 +228,9 @@ export namespace models {
 	    // Go type: time
 	    updated_at: any;
 	    title: string;
+	    title_edited: boolean;
+	    assistant_id: number;
+	    active_message_id: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new Conversation(source);
@@ -85,6 +242,75 @@ export namespace models {
 	        this.created_at = this.convertValues(source["created_at"], null);
 	        this.updated_at = this.convertValues(source["updated_at"], null);
 	        this.title = source["title"];
+	        this.title_edited = source["title_edited"];
+	        this.assistant_id = source["assistant_id"];
+	        this.active_message_id = source["active_message_id"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LocalModel {
+	    id: number;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	    name: string;
+	    url: string;
+	    file_name: string;
+	    sha256: string;
+	    size: number;
+	    downloaded: number;
+	    status: string;
+	    error: string;
+	    architecture: string;
+	    parameter_count: number;
+	    quantization: string;
+	    context_length: number;
+	    tokenizer_model: string;
+	    vocab_size: number;
+	    chat_template: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalModel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.file_name = source["file_name"];
+	        this.sha256 = source["sha256"];
+	        this.size = source["size"];
+	        this.downloaded = source["downloaded"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.architecture = source["architecture"];
+	        this.parameter_count = source["parameter_count"];
+	        this.quantization = source["quantization"];
+	        this.context_length = source["context_length"];
+	        this.tokenizer_model = source["tokenizer_model"];
+	        this.vocab_size = source["vocab_size"];
+	        this.chat_template = source["chat_template"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -112,8 +338,24 @@ export namespace models {
 	    // Go type: time
 	    updated_at: any;
 	    conversation_id: number;
+	    parent_id: number;
 	    role: string;
 	    content: string;
+	    reasoning_content: string;
+	    status: string;
+	    error: string;
+	    cloud_llm_model_id: number;
+	    model_name: string;
+	    temperature?: number;
+	    max_tokens: number;
+	    prompt_tokens: number;
+	    completion_tokens: number;
+	    first_token_ms: number;
+	    latency_ms: number;
+	    finish_reason: string;
+	    attempts: number;
+	    fallback_from: string;
+	    sibling_ids: number[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Message(source);
@@ -125,8 +367,24 @@ export namespace models {
 	        this.created_at = this.convertValues(source["created_at"], null);
 	        this.updated_at = this.convertValues(source["updated_at"], null);
 	        this.conversation_id = source["conversation_id"];
+	        this.parent_id = source["parent_id"];
 	        this.role = source["role"];
 	        this.content = source["content"];
+	        this.reasoning_content = source["reasoning_content"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.cloud_llm_model_id = source["cloud_llm_model_id"];
+	        this.model_name = source["model_name"];
+	        this.temperature = source["temperature"];
+	        this.max_tokens = source["max_tokens"];
+	        this.prompt_tokens = source["prompt_tokens"];
+	        this.completion_tokens = source["completion_tokens"];
+	        this.first_token_ms = source["first_token_ms"];
+	        this.latency_ms = source["latency_ms"];
+	        this.finish_reason = source["finish_reason"];
+	        this.attempts = source["attempts"];
+	        this.fallback_from = source["fallback_from"];
+	        this.sibling_ids = source["sibling_ids"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -147,11 +405,216 @@ export namespace models {
 		    return a;
 		}
 	}
+	export class ProviderModel {
+	    id: number;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	    cloud_llm_model_id: number;
+	    model_id: string;
+	    owned_by: string;
+	    context_window: number;
+	    vision: boolean;
+	    reasoning: boolean;
+	    tools: boolean;
+	    json_mode: boolean;
+	    embedding: boolean;
+	    released_at: number;
+	    // Go type: time
+	    refreshed_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderModel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.cloud_llm_model_id = source["cloud_llm_model_id"];
+	        this.model_id = source["model_id"];
+	        this.owned_by = source["owned_by"];
+	        this.context_window = source["context_window"];
+	        this.vision = source["vision"];
+	        this.reasoning = source["reasoning"];
+	        this.tools = source["tools"];
+	        this.json_mode = source["json_mode"];
+	        this.embedding = source["embedding"];
+	        this.released_at = source["released_at"];
+	        this.refreshed_at = this.convertValues(source["refreshed_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace providers {
+	
+	export class Capabilities {
+	    context_window: number;
+	    max_output_tokens: number;
+	    vision: boolean;
+	    tools: boolean;
+	    reasoning: boolean;
+	    json_mode: boolean;
+	    temperature: boolean;
+	    max_temperature: number;
+	    legacy_max_tokens: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Capabilities(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.context_window = source["context_window"];
+	        this.max_output_tokens = source["max_output_tokens"];
+	        this.vision = source["vision"];
+	        this.tools = source["tools"];
+	        this.reasoning = source["reasoning"];
+	        this.json_mode = source["json_mode"];
+	        this.temperature = source["temperature"];
+	        this.max_temperature = source["max_temperature"];
+	        this.legacy_max_tokens = source["legacy_max_tokens"];
+	    }
+	}
+	export class ModelInfo {
+	    id: string;
+	    owned_by: string;
+	    created: number;
+	    context_window: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.owned_by = source["owned_by"];
+	        this.created = source["created"];
+	        this.context_window = source["context_window"];
+	    }
+	}
+	export class Preset {
+	    id: string;
+	    name: string;
+	    icon: string;
+	    endpoint: string;
+	    models: string[];
+	    local: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Preset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.icon = source["icon"];
+	        this.endpoint = source["endpoint"];
+	        this.models = source["models"];
+	        this.local = source["local"];
+	    }
+	}
 
 }
 
 export namespace services {
 	
+	export class AssistantPageResult {
+	    total: number;
+	    items: models.Assistant[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AssistantPageResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.total = source["total"];
+	        this.items = this.convertValues(source["items"], models.Assistant);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CloudLLMHealth {
+	    status: string;
+	    hint: string;
+	    message: string;
+	    model: string;
+	    latency_ms: number;
+	    // Go type: time
+	    checked_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new CloudLLMHealth(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.hint = source["hint"];
+	        this.message = source["message"];
+	        this.model = source["model"];
+	        this.latency_ms = source["latency_ms"];
+	        this.checked_at = this.convertValues(source["checked_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class CloudLLMModelPageResult {
 	    total: number;
 	    items: models.CloudLLMModel[];
@@ -216,6 +679,102 @@ export namespace services {
 		    return a;
 		}
 	}
+	export class LocalModelDiskUsage {
+	    path: string;
+	    total_bytes: number;
+	    ready_bytes: number;
+	    partial_bytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalModelDiskUsage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.total_bytes = source["total_bytes"];
+	        this.ready_bytes = source["ready_bytes"];
+	        this.partial_bytes = source["partial_bytes"];
+	    }
+	}
+	export class LocalModelParams {
+	    name: string;
+	    url: string;
+	    file_name: string;
+	    sha256: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalModelParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.file_name = source["file_name"];
+	        this.sha256 = source["sha256"];
+	    }
+	}
+	export class LocalRuntimeStatus {
+	    local_model_id: number;
+	    cloud_llm_id: number;
+	    status: string;
+	    port: number;
+	    pid: number;
+	    restarts: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalRuntimeStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.local_model_id = source["local_model_id"];
+	        this.cloud_llm_id = source["cloud_llm_id"];
+	        this.status = source["status"];
+	        this.port = source["port"];
+	        this.pid = source["pid"];
+	        this.restarts = source["restarts"];
+	        this.error = source["error"];
+	    }
+	}
+	export class LocalServerStatus {
+	    reachable: boolean;
+	    version: string;
+	    models: providers.ModelInfo[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalServerStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.reachable = source["reachable"];
+	        this.version = source["version"];
+	        this.models = this.convertValues(source["models"], providers.ModelInfo);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class MessagePageResult {
 	    items: models.Message[];
 	
@@ -247,11 +806,13 @@ export namespace services {
 		}
 	}
 	export class MessageRequestParams {
+	    request_id: string;
 	    cloud_llm_id: number;
 	    conversation_id: number;
+	    assistant_id: number;
 	    question: string;
 	    model_name: string;
-	    temperature: number;
+	    temperature?: number;
 	    max_completion_tokens: number;
 	    history_length: number;
 	
@@ -261,8 +822,10 @@ export namespace services {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.request_id = source["request_id"];
 	        this.cloud_llm_id = source["cloud_llm_id"];
 	        this.conversation_id = source["conversation_id"];
+	        this.assistant_id = source["assistant_id"];
 	        this.question = source["question"];
 	        this.model_name = source["model_name"];
 	        this.temperature = source["temperature"];
@@ -270,6 +833,76 @@ export namespace services {
 	        this.history_length = source["history_length"];
 	    }
 	}
+	export class ModelRecommendation {
+	    id: string;
+	    family: string;
+	    name: string;
+	    url: string;
+	    file_name: string;
+	    parameters: number;
+	    quantization: string;
+	    size: number;
+	    context_length: number;
+	    kv_bytes_per_token: number;
+	    fits: boolean;
+	    recommended: boolean;
+	    score: number;
+	    context: number;
+	    memory_required: number;
+	    reasons: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelRecommendation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.family = source["family"];
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.file_name = source["file_name"];
+	        this.parameters = source["parameters"];
+	        this.quantization = source["quantization"];
+	        this.size = source["size"];
+	        this.context_length = source["context_length"];
+	        this.kv_bytes_per_token = source["kv_bytes_per_token"];
+	        this.fits = source["fits"];
+	        this.recommended = source["recommended"];
+	        this.score = source["score"];
+	        this.context = source["context"];
+	        this.memory_required = source["memory_required"];
+	        this.reasons = source["reasons"];
+	    }
+	}
+	export class SystemInfo {
+	    os: string;
+	    arch: string;
+	    cpu_name: string;
+	    cpu_cores: number;
+	    cpu_features: string[];
+	    total_memory: number;
+	    available_memory: number;
+	    data_path: string;
+	    free_disk: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SystemInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.os = source["os"];
+	        this.arch = source["arch"];
+	        this.cpu_name = source["cpu_name"];
+	        this.cpu_cores = source["cpu_cores"];
+	        this.cpu_features = source["cpu_features"];
+	        this.total_memory = source["total_memory"];
+	        this.available_memory = source["available_memory"];
+	        this.data_path = source["data_path"];
+	        this.free_disk = source["free_disk"];
+	    }
+	}
 
 }
 
@@ -0,0 +1,171 @@
+package chattemplate
+
+import (
+	"strings"
+)
+
+// Family 内置的对话模板，模型文件和服务端都没有提供模板时按模型名称选用
+type Family struct {
+	Name     string
+	Template string
+	BOSToken string
+	EOSToken string
+}
+
+// 内置模板的名称
+const (
+	FamilyChatML   = "chatml"
+	FamilyLlama3   = "llama3"
+	FamilyLlama2   = "llama2"
+	FamilyMistral  = "mistral"
+	FamilyGemma    = "gemma"
+	FamilyPhi3     = "phi3"
+	FamilyDeepSeek = "deepseek"
+)
+
+var families = map[string]Family{
+	FamilyChatML: {
+		Name: FamilyChatML,
+		Template: `{%- for message in messages %}
+{{- '<|im_start|>' + message.role + '\n' + message.content + '<|im_end|>\n' }}
+{%- endfor %}
+{%- if add_generation_prompt %}
+{{- '<|im_start|>assistant\n' }}
+{%- endif %}`,
+		EOSToken: "<|im_end|>",
+	},
+	FamilyLlama3: {
+		Name: FamilyLlama3,
+		Template: `{{- bos_token }}
+{%- for message in messages %}
+{%- set role = 'ipython' if message.role == 'tool' else message.role %}
+{{- '<|start_header_id|>' + role + '<|end_header_id|>\n\n' + message.content | trim + '<|eot_id|>' }}
+{%- endfor %}
+{%- if add_generation_prompt %}
+{{- '<|start_header_id|>assistant<|end_header_id|>\n\n' }}
+{%- endif %}`,
+		BOSToken: "<|begin_of_text|>",
+		EOSToken: "<|eot_id|>",
+	},
+	FamilyLlama2: {
+		Name: FamilyLlama2,
+		Template: `{%- if messages and messages[0].role == 'system' %}
+{%- set system = '<<SYS>>\n' + messages[0].content | trim + '\n<</SYS>>\n\n' %}
+{%- set messages = messages[1:] %}
+{%- else %}
+{%- set system = '' %}
+{%- endif %}
+{%- for message in messages %}
+{%- if message.role == 'assistant' %}
+{{- ' ' + message.content | trim + ' ' + eos_token }}
+{%- else %}
+{{- bos_token + '[INST] ' + (system if loop.first else '') + message.content | trim + ' [/INST]' }}
+{%- endif %}
+{%- endfor %}`,
+		BOSToken: "<s>",
+		EOSToken: "</s>",
+	},
+	FamilyMistral: {
+		Name: FamilyMistral,
+		Template: `{%- if messages and messages[0].role == 'system' %}
+{%- set system = messages[0].content | trim + '\n\n' %}
+{%- set messages = messages[1:] %}
+{%- else %}
+{%- set system = '' %}
+{%- endif %}
+{{- bos_token }}
+{%- for message in messages %}
+{%- if message.role == 'assistant' %}
+{{- ' ' + message.content | trim + eos_token }}
+{%- else %}
+{{- '[INST] ' + (system if loop.first else '') + message.content | trim + ' [/INST]' }}
+{%- endif %}
+{%- endfor %}`,
+		BOSToken: "<s>",
+		EOSToken: "</s>",
+	},
+	FamilyGemma: {
+		Name: FamilyGemma,
+		Template: `{{- bos_token }}
+{%- if messages and messages[0].role == 'system' %}
+{%- set system = messages[0].content | trim + '\n\n' %}
+{%- set messages = messages[1:] %}
+{%- else %}
+{%- set system = '' %}
+{%- endif %}
+{%- for message in messages %}
+{%- set role = 'model' if message.role == 'assistant' else 'user' %}
+{{- '<start_of_turn>' + role + '\n' + (system if loop.first else '') + message.content | trim + '<end_of_turn>\n' }}
+{%- endfor %}
+{%- if add_generation_prompt %}
+{{- '<start_of_turn>model\n' }}
+{%- endif %}`,
+		BOSToken: "<bos>",
+		EOSToken: "<eos>",
+	},
+	FamilyPhi3: {
+		Name: FamilyPhi3,
+		Template: `{%- for message in messages %}
+{{- '<|' + message.role + '|>\n' + message.content + '<|end|>\n' }}
+{%- endfor %}
+{%- if add_generation_prompt %}
+{{- '<|assistant|>\n' }}
+{%- endif %}`,
+		EOSToken: "<|endoftext|>",
+	},
+	FamilyDeepSeek: {
+		Name: FamilyDeepSeek,
+		Template: `{{- bos_token }}
+{%- for message in messages %}
+{%- if message.role == 'system' %}
+{{- message.content + '\n\n' }}
+{%- elif message.role == 'user' %}
+{{- '<｜User｜>' + message.content }}
+{%- elif message.role == 'assistant' %}
+{{- '<｜Assistant｜>' + message.content.split('</think>')[-1] | trim + '<｜end▁of▁sentence｜>' }}
+{%- else %}
+{{- '<｜tool▁outputs▁begin｜><｜tool▁output▁begin｜>' + message.content + '<｜tool▁output▁end｜><｜tool▁outputs▁end｜>' }}
+{%- endif %}
+{%- endfor %}
+{%- if add_generation_prompt %}
+{{- '<｜Assistant｜>' }}
+{%- endif %}`,
+		BOSToken: "<｜begin▁of▁sentence｜>",
+		EOSToken: "<｜end▁of▁sentence｜>",
+	},
+}
+
+// familyKeywords 模型名称中的关键字对应的模板，按顺序匹配
+var familyKeywords = []struct {
+	keyword string
+	family  string
+}{
+	{"deepseek-r1", FamilyDeepSeek},
+	{"deepseek-v3", FamilyDeepSeek},
+	{"llama-3", FamilyLlama3},
+	{"llama3", FamilyLlama3},
+	{"llama-2", FamilyLlama2},
+	{"llama2", FamilyLlama2},
+	{"mistral", FamilyMistral},
+	{"mixtral", FamilyMistral},
+	{"gemma", FamilyGemma},
+	{"phi-3", FamilyPhi3},
+	{"phi3", FamilyPhi3},
+}
+
+// LookupFamily 按名称获取内置模板
+func LookupFamily(name string) (Family, bool) {
+	family, ok := families[name]
+	return family, ok
+}
+
+// FamilyFor 根据模型名称或文件名猜测对话模板，无法判断时使用 ChatML
+func FamilyFor(model string) Family {
+	name := strings.NewReplacer(" ", "-", "_", "-").Replace(strings.ToLower(model))
+	for _, item := range familyKeywords {
+		if strings.Contains(name, item.keyword) {
+			return families[item.family]
+		}
+	}
+	return families[FamilyChatML]
+}
@@ -0,0 +1,131 @@
+// Package chattemplate 用模型自带的对话模板把消息渲染为提示词，
+// 支持常见GGUF对话模板用到的Jinja子集，供只提供 /completion 接口的本地服务使用
+package chattemplate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message 一条对话消息，Role 为 system、user、assistant 或 tool
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Options 渲染参数
+type Options struct {
+	BOSToken string
+	EOSToken string
+	// AddGenerationPrompt 在末尾加上助手回复的开头，让模型接着生成回复
+	AddGenerationPrompt bool
+	// Now strftime_now 使用的时间，为nil时使用当前时间
+	Now func() time.Time
+	// Tools 可供模型调用的工具，OpenAI 格式的函数定义，为空时模板中的 tools 为 none
+	Tools []map[string]any
+}
+
+// Prompt 渲染结果
+type Prompt struct {
+	Text string   `json:"text"`
+	Stop []string `json:"stop"` // 生成到这些文本时应当停止
+}
+
+// TemplateError 模板通过 raise_exception 主动报告的错误，通常是消息的顺序不符合模型要求
+type TemplateError struct {
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	return "对话模板报错: " + e.Message
+}
+
+// Template 解析后的对话模板，可以并发使用
+type Template struct {
+	source string
+	nodes  []node
+}
+
+// Parse 解析对话模板
+func Parse(source string) (*Template, error) {
+	nodes, err := parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("解析对话模板失败: %w", err)
+	}
+	return &Template{source: source, nodes: nodes}, nil
+}
+
+// Render 渲染提示词，模板中可以使用 messages、bos_token、eos_token、add_generation_prompt 和 tools
+func (t *Template) Render(messages []Message, opts Options) (*Prompt, error) {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	builtins := newScope(nil)
+	for name, value := range globals(now) {
+		builtins.vars[name] = value
+	}
+
+	items := make([]any, len(messages))
+	for i, message := range messages {
+		item := newDict()
+		item.set("role", message.Role)
+		item.set("content", message.Content)
+		items[i] = item
+	}
+	sc := newScope(builtins)
+	sc.vars["messages"] = items
+	sc.vars["bos_token"] = opts.BOSToken
+	sc.vars["eos_token"] = opts.EOSToken
+	sc.vars["add_generation_prompt"] = opts.AddGenerationPrompt
+	sc.vars["tools"] = nil
+	if len(opts.Tools) > 0 {
+		tools := make([]any, len(opts.Tools))
+		for i, tool := range opts.Tools {
+			tools[i] = fromGo(tool)
+		}
+		sc.vars["tools"] = tools
+	}
+
+	var builder strings.Builder
+	err := renderNodes(t.nodes, sc, &builder)
+	if err != nil && !errors.Is(err, errBreak) && !errors.Is(err, errContinue) {
+		var templateErr *TemplateError
+		if errors.As(err, &templateErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("渲染对话模板失败: %w", err)
+	}
+	return &Prompt{
+		Text: builder.String(),
+		Stop: StopSequences(t.source, opts.EOSToken),
+	}, nil
+}
+
+// endMarkers 常见模板中表示一轮对话结束的特殊token
+var endMarkers = []string{
+	"<|im_end|>",
+	"<|eot_id|>",
+	"<|eom_id|>",
+	"<end_of_turn>",
+	"<|end|>",
+	"<|endoftext|>",
+	"<|END_OF_TURN_TOKEN|>",
+	"<｜end▁of▁sentence｜>",
+}
+
+// StopSequences 模板中出现的轮次结束标记和EOS，服务端不会自动在这些位置停止时需要作为停止词传入
+func StopSequences(source, eosToken string) []string {
+	var stop []string
+	if eosToken != "" {
+		stop = append(stop, eosToken)
+	}
+	for _, marker := range endMarkers {
+		if marker != eosToken && strings.Contains(source, marker) {
+			stop = append(stop, marker)
+		}
+	}
+	return stop
+}
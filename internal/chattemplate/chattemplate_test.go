@@ -0,0 +1,309 @@
+package chattemplate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// conversation 测试用的多轮对话，开头带系统提示词
+var conversation = []Message{
+	{Role: "system", Content: "Be brief."},
+	{Role: "user", Content: "Hi"},
+	{Role: "assistant", Content: "Hello!"},
+	{Role: "user", Content: "How are you?"},
+}
+
+func render(t *testing.T, source string, messages []Message, opts Options) (*Prompt, error) {
+	t.Helper()
+	tmpl, err := Parse(source)
+	if err != nil {
+		t.Fatalf("Parse 返回错误: %v", err)
+	}
+	return tmpl.Render(messages, opts)
+}
+
+func TestBuiltinFamilies(t *testing.T) {
+	tests := []struct {
+		family   string
+		messages []Message
+		want     string
+		wantStop []string
+	}{
+		{
+			family: FamilyChatML,
+			want: "<|im_start|>system\nBe brief.<|im_end|>\n<|im_start|>user\nHi<|im_end|>\n" +
+				"<|im_start|>assistant\nHello!<|im_end|>\n<|im_start|>user\nHow are you?<|im_end|>\n<|im_start|>assistant\n",
+			wantStop: []string{"<|im_end|>"},
+		},
+		{
+			family: FamilyLlama3,
+			// trim 只作用于消息内容，不影响前后拼接的标记
+			messages: []Message{
+				{Role: "system", Content: " Be brief.\n"},
+				{Role: "user", Content: "\n Hi  "},
+				{Role: "tool", Content: `{"ok": true}`},
+			},
+			want: "<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|>" +
+				"<|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|>" +
+				"<|start_header_id|>ipython<|end_header_id|>\n\n{\"ok\": true}<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\n",
+			wantStop: []string{"<|eot_id|>"},
+		},
+		{
+			family: FamilyLlama2,
+			want: "<s>[INST] <<SYS>>\nBe brief.\n<</SYS>>\n\nHi [/INST] Hello! </s>" +
+				"<s>[INST] How are you? [/INST]",
+			wantStop: []string{"</s>"},
+		},
+		{
+			family:   FamilyMistral,
+			want:     "<s>[INST] Be brief.\n\nHi [/INST] Hello!</s>[INST] How are you? [/INST]",
+			wantStop: []string{"</s>"},
+		},
+		{
+			family: FamilyGemma,
+			want: "<bos><start_of_turn>user\nBe brief.\n\nHi<end_of_turn>\n<start_of_turn>model\nHello!<end_of_turn>\n" +
+				"<start_of_turn>user\nHow are you?<end_of_turn>\n<start_of_turn>model\n",
+			wantStop: []string{"<eos>", "<end_of_turn>"},
+		},
+		{
+			family: FamilyPhi3,
+			want: "<|system|>\nBe brief.<|end|>\n<|user|>\nHi<|end|>\n<|assistant|>\nHello!<|end|>\n" +
+				"<|user|>\nHow are you?<|end|>\n<|assistant|>\n",
+			wantStop: []string{"<|endoftext|>", "<|end|>"},
+		},
+		{
+			family: FamilyDeepSeek,
+			// 历史回答中的思考过程不再发送
+			messages: []Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "Hi"},
+				{Role: "assistant", Content: "<think>\nsay hello\n</think>\n\nHello!"},
+				{Role: "user", Content: "How are you?"},
+			},
+			want: "<｜begin▁of▁sentence｜>Be brief.\n\n<｜User｜>Hi<｜Assistant｜>Hello!<｜end▁of▁sentence｜>" +
+				"<｜User｜>How are you?<｜Assistant｜>",
+			wantStop: []string{"<｜end▁of▁sentence｜>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.family, func(t *testing.T) {
+			family, ok := LookupFamily(tt.family)
+			if !ok {
+				t.Fatalf("没有内置模板 %s", tt.family)
+			}
+			messages := tt.messages
+			if messages == nil {
+				messages = conversation
+			}
+			prompt, err := render(t, family.Template, messages, Options{
+				BOSToken:            family.BOSToken,
+				EOSToken:            family.EOSToken,
+				AddGenerationPrompt: true,
+			})
+			if err != nil {
+				t.Fatalf("Render 返回错误: %v", err)
+			}
+			if prompt.Text != tt.want {
+				t.Errorf("提示词不一致\n得到: %q\n期望: %q", prompt.Text, tt.want)
+			}
+			if !slices.Equal(prompt.Stop, tt.wantStop) {
+				t.Errorf("停止词 = %q，期望 %q", prompt.Stop, tt.wantStop)
+			}
+		})
+	}
+}
+
+// weatherTool OpenAI 格式的工具定义
+var weatherTool = map[string]any{
+	"type": "function",
+	"function": map[string]any{
+		"name":        "get_weather",
+		"description": "Get the weather",
+		"parameters": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			"required":   []string{"city"},
+		},
+	},
+}
+
+// TestModelTemplates 从模型文件中取出的对话模板，位于 testdata 目录，期望结果按 transformers 的渲染规则得出
+func TestModelTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		messages []Message
+		tools    []map[string]any
+		// noGenerationPrompt 不在末尾加助手回复的开头
+		noGenerationPrompt bool
+		want               string
+		wantError          string
+	}{
+		{
+			name:     "Qwen2.5 默认系统提示词",
+			file:     "qwen2.5.jinja",
+			messages: []Message{{Role: "user", Content: "Hi"}},
+			want: "<|im_start|>system\nYou are Qwen, created by Alibaba Cloud. You are a helpful assistant.<|im_end|>\n" +
+				"<|im_start|>user\nHi<|im_end|>\n<|im_start|>assistant\n",
+		},
+		{
+			name: "Qwen2.5 工具",
+			file: "qwen2.5.jinja",
+			messages: []Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "Weather in Paris?"},
+				{Role: "assistant", Content: "Checking."},
+				{Role: "tool", Content: `{"temp": 20}`},
+				{Role: "tool", Content: `{"rain": false}`},
+			},
+			// Go 的 map 没有顺序，工具定义的键按字母排序输出
+			tools: []map[string]any{weatherTool},
+			want: "<|im_start|>system\nBe brief.\n\n# Tools\n\nYou may call one or more functions to assist with the user query.\n\n" +
+				"You are provided with function signatures within <tools></tools> XML tags:\n<tools>\n" +
+				`{"function": {"description": "Get the weather", "name": "get_weather", "parameters": ` +
+				`{"properties": {"city": {"type": "string"}}, "required": ["city"], "type": "object"}}, "type": "function"}` +
+				"\n</tools>\n\nFor each function call, return a json object with function name and arguments within <tool_call></tool_call> XML tags:\n" +
+				"<tool_call>\n{\"name\": <function-name>, \"arguments\": <args-json-object>}\n</tool_call><|im_end|>\n" +
+				"<|im_start|>user\nWeather in Paris?<|im_end|>\n<|im_start|>assistant\nChecking.<|im_end|>\n" +
+				"<|im_start|>user\n<tool_response>\n{\"temp\": 20}\n</tool_response>\n<tool_response>\n{\"rain\": false}\n</tool_response><|im_end|>\n" +
+				"<|im_start|>assistant\n",
+		},
+		{
+			name: "Llama 3.1 过滤器优先于加号",
+			file: "llama3.1.jinja",
+			messages: []Message{
+				{Role: "system", Content: "  Be brief.\n"},
+				{Role: "user", Content: "\nHi  "},
+				{Role: "assistant", Content: " Hello! "},
+			},
+			want: "<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\n" +
+				"Cutting Knowledge Date: December 2023\nToday Date: 26 Jul 2024\n\nBe brief.<|eot_id|>" +
+				"<|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\nHello!<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\n",
+		},
+		{
+			name:     "DeepSeek-R1 namespace",
+			file:     "deepseek-r1.jinja",
+			messages: append(conversation[:2:2], Message{Role: "assistant", Content: "<think>\nsay hello\n</think>\n\nHello!"}, conversation[3]),
+			want: "<｜begin▁of▁sentence｜>Be brief.<｜User｜>Hi<｜Assistant｜>\n\nHello!<｜end▁of▁sentence｜>" +
+				"<｜User｜>How are you?<｜Assistant｜><think>\n",
+		},
+		{
+			name: "DeepSeek-R1 工具结果",
+			file: "deepseek-r1.jinja",
+			messages: []Message{
+				{Role: "user", Content: "Weather?"},
+				{Role: "tool", Content: "sunny"},
+				{Role: "tool", Content: "20C"},
+			},
+			// 最后一条是工具结果时 ns.is_tool 为真，不加生成提示
+			want: "<｜begin▁of▁sentence｜><｜User｜>Weather?" +
+				"<｜tool▁outputs▁begin｜><｜tool▁output▁begin｜>sunny<｜tool▁output▁end｜>" +
+				"\n<｜tool▁output▁begin｜>20C<｜tool▁output▁end｜><｜tool▁outputs▁end｜>",
+		},
+		{
+			name:     "Gemma2",
+			file:     "gemma2.jinja",
+			messages: conversation[1:],
+			want: "<bos><start_of_turn>user\nHi<end_of_turn>\n<start_of_turn>model\nHello!<end_of_turn>\n" +
+				"<start_of_turn>user\nHow are you?<end_of_turn>\n<start_of_turn>model\n",
+		},
+		{
+			name:      "Gemma2 不支持系统消息",
+			file:      "gemma2.jinja",
+			messages:  conversation,
+			wantError: "System role not supported",
+		},
+		{
+			name:     "Mistral",
+			file:     "mistral.jinja",
+			messages: conversation[1:],
+			want:     "<s>[INST] Hi [/INST]Hello!</s>[INST] How are you? [/INST]",
+		},
+		{
+			name:      "Mistral 角色需要交替",
+			file:      "mistral.jinja",
+			messages:  []Message{{Role: "user", Content: "Hi"}, {Role: "user", Content: "Hi again"}},
+			wantError: "Conversation roles must alternate",
+		},
+		{
+			name:               "Phi-3 不加生成提示时以EOS结尾",
+			file:               "phi3.jinja",
+			messages:           conversation[1:3],
+			noGenerationPrompt: true,
+			want:               "<|user|>\nHi<|end|>\n<|assistant|>\nHello!<|end|>\n<|endoftext|>",
+		},
+	}
+
+	bosTokens := map[string]string{
+		"llama3.1.jinja":    "<|begin_of_text|>",
+		"deepseek-r1.jinja": "<｜begin▁of▁sentence｜>",
+		"gemma2.jinja":      "<bos>",
+		"mistral.jinja":     "<s>",
+	}
+	eosTokens := map[string]string{
+		"mistral.jinja": "</s>",
+		"phi3.jinja":    "<|endoftext|>",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := Options{
+				BOSToken:            bosTokens[tt.file],
+				EOSToken:            eosTokens[tt.file],
+				AddGenerationPrompt: !tt.noGenerationPrompt,
+				Now:                 func() time.Time { return time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC) },
+				Tools:               tt.tools,
+			}
+
+			prompt, err := render(t, string(source), tt.messages, opts)
+			if tt.wantError != "" {
+				var templateErr *TemplateError
+				if !errors.As(err, &templateErr) || !strings.Contains(templateErr.Message, tt.wantError) {
+					t.Fatalf("错误 = %v，期望模板报错 %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render 返回错误: %v", err)
+			}
+			if prompt.Text != tt.want {
+				t.Errorf("提示词不一致\n得到: %q\n期望: %q", prompt.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestFamilyFor(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"Meta-Llama-3.1-8B-Instruct-Q4_K_M", FamilyLlama3},
+		{"llama_2_7b_chat", FamilyLlama2},
+		{"DeepSeek-R1-Distill-Qwen-7B", FamilyDeepSeek},
+		{"mistral-7b-instruct-v0.2", FamilyMistral},
+		{"Mixtral 8x7B", FamilyMistral},
+		{"gemma-2-9b-it", FamilyGemma},
+		{"Phi-3-mini-4k-instruct", FamilyPhi3},
+		{"qwen2.5-7b-instruct", FamilyChatML},
+		{"default", FamilyChatML},
+	}
+	for _, tt := range tests {
+		if got := FamilyFor(tt.model).Name; got != tt.want {
+			t.Errorf("FamilyFor(%q) = %s，期望 %s", tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,679 @@
+package chattemplate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errBreak    = errors.New("break")
+	errContinue = errors.New("continue")
+)
+
+// scope 变量作用域，for 循环和 macro 会创建新的作用域
+type scope struct {
+	vars   map[string]any
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: make(map[string]any), parent: parent}
+}
+
+func (s *scope) lookup(name string) any {
+	for current := s; current != nil; current = current.parent {
+		if value, ok := current.vars[name]; ok {
+			return value
+		}
+	}
+	return undefined{name: name}
+}
+
+// renderNodes 渲染节点到 out
+func renderNodes(nodes []node, sc *scope, out *strings.Builder) error {
+	for _, n := range nodes {
+		if err := renderNode(n, sc, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderNode(n node, sc *scope, out *strings.Builder) error {
+	switch n := n.(type) {
+	case textNode:
+		out.WriteString(n.text)
+	case outputNode:
+		value, err := evaluate(n.expr, sc)
+		if err != nil {
+			return err
+		}
+		out.WriteString(toString(value))
+	case ifNode:
+		for i, cond := range n.conds {
+			value, err := evaluate(cond, sc)
+			if err != nil {
+				return err
+			}
+			if truthy(value) {
+				return renderNodes(n.bodies[i], sc, out)
+			}
+		}
+		return renderNodes(n.elseBody, sc, out)
+	case forNode:
+		return renderFor(n, sc, out)
+	case setNode:
+		return renderSet(n, sc)
+	case macroNode:
+		sc.vars[n.name] = newMacro(n, sc)
+	case breakNode:
+		return errBreak
+	case continueNode:
+		return errContinue
+	}
+	return nil
+}
+
+func renderFor(n forNode, sc *scope, out *strings.Builder) error {
+	iter, err := evaluate(n.iter, sc)
+	if err != nil {
+		return err
+	}
+	items, err := iterate(iter)
+	if err != nil {
+		return err
+	}
+
+	// 先按条件过滤，loop.index 等只计算留下的元素
+	if n.filter != nil {
+		var filtered []any
+		for _, item := range items {
+			itemScope := newScope(sc)
+			if err := bindTargets(itemScope, n.targets, item); err != nil {
+				return err
+			}
+			value, err := evaluate(n.filter, itemScope)
+			if err != nil {
+				return err
+			}
+			if truthy(value) {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+	if len(items) == 0 {
+		return renderNodes(n.elseBody, sc, out)
+	}
+
+	for i, item := range items {
+		loopScope := newScope(sc)
+		if err := bindTargets(loopScope, n.targets, item); err != nil {
+			return err
+		}
+		loopScope.vars["loop"] = loopInfo(items, i)
+
+		err := renderNodes(n.body, loopScope, out)
+		if errors.Is(err, errBreak) {
+			break
+		}
+		if err != nil && !errors.Is(err, errContinue) {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindTargets 给循环变量赋值，多个变量时解包元素
+func bindTargets(sc *scope, targets []string, item any) error {
+	if len(targets) == 1 {
+		sc.vars[targets[0]] = item
+		return nil
+	}
+	values, ok := item.([]any)
+	if !ok || len(values) != len(targets) {
+		return fmt.Errorf("无法把 %s 解包为 %d 个变量", repr(item), len(targets))
+	}
+	for i, target := range targets {
+		sc.vars[target] = values[i]
+	}
+	return nil
+}
+
+// loopInfo 循环中的 loop 变量
+func loopInfo(items []any, i int) *dict {
+	loop := newDict()
+	loop.set("index", i+1)
+	loop.set("index0", i)
+	loop.set("revindex", len(items)-i)
+	loop.set("revindex0", len(items)-i-1)
+	loop.set("first", i == 0)
+	loop.set("last", i == len(items)-1)
+	loop.set("length", len(items))
+	if i > 0 {
+		loop.set("previtem", items[i-1])
+	}
+	if i < len(items)-1 {
+		loop.set("nextitem", items[i+1])
+	}
+	loop.set("cycle", function(func(args []any, kwargs map[string]any) (any, error) {
+		if len(args) == 0 {
+			return nil, errors.New("loop.cycle 需要参数")
+		}
+		return args[i%len(args)], nil
+	}))
+	return loop
+}
+
+func renderSet(n setNode, sc *scope) error {
+	var value any
+	if n.body != nil || n.value == nil {
+		var builder strings.Builder
+		if err := renderNodes(n.body, sc, &builder); err != nil {
+			return err
+		}
+		value = builder.String()
+	} else {
+		var err error
+		if value, err = evaluate(n.value, sc); err != nil {
+			return err
+		}
+	}
+
+	if n.attr == "" {
+		sc.vars[n.name] = value
+		return nil
+	}
+	target, ok := sc.lookup(n.name).(*dict)
+	if !ok {
+		return fmt.Errorf("%s 不是 namespace，不能设置属性", n.name)
+	}
+	target.set(n.attr, value)
+	return nil
+}
+
+// newMacro 把 macro 定义转换为函数，调用时在定义处的作用域下渲染
+func newMacro(n macroNode, sc *scope) function {
+	return func(args []any, kwargs map[string]any) (any, error) {
+		macroScope := newScope(sc)
+		for i, param := range n.params {
+			switch {
+			case i < len(args):
+				macroScope.vars[param] = args[i]
+			case kwargs[param] != nil:
+				macroScope.vars[param] = kwargs[param]
+			case n.defaults[i] != nil:
+				value, err := evaluate(n.defaults[i], sc)
+				if err != nil {
+					return nil, err
+				}
+				macroScope.vars[param] = value
+			default:
+				macroScope.vars[param] = undefined{name: param}
+			}
+		}
+		var builder strings.Builder
+		if err := renderNodes(n.body, macroScope, &builder); err != nil {
+			return nil, err
+		}
+		return builder.String(), nil
+	}
+}
+
+// evaluate 计算表达式的值
+func evaluate(e expr, sc *scope) (any, error) {
+	switch e := e.(type) {
+	case literal:
+		return e.value, nil
+	case nameExpr:
+		return sc.lookup(e.name), nil
+	case listExpr:
+		items := make([]any, len(e.items))
+		for i, item := range e.items {
+			value, err := evaluate(item, sc)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	case dictExpr:
+		result := newDict()
+		for i := range e.keys {
+			key, err := evaluate(e.keys[i], sc)
+			if err != nil {
+				return nil, err
+			}
+			value, err := evaluate(e.values[i], sc)
+			if err != nil {
+				return nil, err
+			}
+			result.set(toString(key), value)
+		}
+		return result, nil
+	case attrExpr:
+		target, err := evaluate(e.target, sc)
+		if err != nil {
+			return nil, err
+		}
+		return getAttr(target, e.name), nil
+	case indexExpr:
+		target, err := evaluate(e.target, sc)
+		if err != nil {
+			return nil, err
+		}
+		index, err := evaluate(e.index, sc)
+		if err != nil {
+			return nil, err
+		}
+		return getItem(target, index), nil
+	case sliceExpr:
+		return evalSlice(e, sc)
+	case callExpr:
+		return evalCall(e, sc)
+	case filterExpr:
+		return evalFilter(e, sc)
+	case testExpr:
+		return evalTest(e, sc)
+	case unaryExpr:
+		return evalUnary(e, sc)
+	case binaryExpr:
+		return evalBinary(e, sc)
+	case condExpr:
+		cond, err := evaluate(e.cond, sc)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return evaluate(e.then, sc)
+		}
+		if e.otherwise == nil {
+			return undefined{}, nil
+		}
+		return evaluate(e.otherwise, sc)
+	}
+	return nil, fmt.Errorf("无法计算的表达式 %T", e)
+}
+
+// getAttr 属性访问，字典先找键再找方法，不存在时返回未定义
+func getAttr(target any, name string) any {
+	if d, ok := target.(*dict); ok {
+		if value, ok := d.get(name); ok {
+			return value
+		}
+	}
+	if method := lookupMethod(target, name); method != nil {
+		return method
+	}
+	return undefined{name: name}
+}
+
+// getItem 下标访问，列表支持负数下标，越界时返回未定义
+func getItem(target, index any) any {
+	switch v := target.(type) {
+	case []any:
+		if i, ok := index.(int); ok {
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return v[i]
+			}
+		}
+	case string:
+		if i, ok := index.(int); ok {
+			runes := []rune(v)
+			if i < 0 {
+				i += len(runes)
+			}
+			if i >= 0 && i < len(runes) {
+				return string(runes[i])
+			}
+		}
+	case *dict:
+		if key, ok := index.(string); ok {
+			if value, ok := v.get(key); ok {
+				return value
+			}
+		}
+	}
+	if name, ok := index.(string); ok {
+		return getAttr(target, name)
+	}
+	return undefined{}
+}
+
+func evalSlice(e sliceExpr, sc *scope) (any, error) {
+	target, err := evaluate(e.target, sc)
+	if err != nil {
+		return nil, err
+	}
+	var bounds [3]*int
+	for i, part := range []expr{e.start, e.stop, e.step} {
+		if part == nil {
+			continue
+		}
+		value, err := evaluate(part, sc)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		number, ok := value.(int)
+		if !ok {
+			return nil, fmt.Errorf("切片参数必须是整数")
+		}
+		bounds[i] = &number
+	}
+
+	switch v := target.(type) {
+	case []any:
+		indexes, err := sliceIndexes(len(v), bounds)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]any, len(indexes))
+		for i, index := range indexes {
+			items[i] = v[index]
+		}
+		return items, nil
+	case string:
+		runes := []rune(v)
+		indexes, err := sliceIndexes(len(runes), bounds)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]rune, len(indexes))
+		for i, index := range indexes {
+			result[i] = runes[index]
+		}
+		return string(result), nil
+	case nil, undefined:
+		return undefined{}, nil
+	}
+	return nil, fmt.Errorf("%s 不支持切片", repr(target))
+}
+
+// sliceIndexes 计算 Python 切片选中的下标
+func sliceIndexes(size int, bounds [3]*int) ([]int, error) {
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return nil, errors.New("切片步长不能为0")
+	}
+
+	clamp := func(value *int, fallback, low, high int) int {
+		if value == nil {
+			return fallback
+		}
+		i := *value
+		if i < 0 {
+			i += size
+		}
+		return min(max(i, low), high)
+	}
+	var indexes []int
+	if step > 0 {
+		start, stop := clamp(bounds[0], 0, 0, size), clamp(bounds[1], size, 0, size)
+		for i := start; i < stop; i += step {
+			indexes = append(indexes, i)
+		}
+	} else {
+		start, stop := clamp(bounds[0], size-1, -1, size-1), clamp(bounds[1], -1, -1, size-1)
+		for i := start; i > stop; i += step {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// evalArgs 计算调用参数
+func evalArgs(args []expr, kwargs []kwarg, sc *scope) ([]any, map[string]any, error) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		value, err := evaluate(arg, sc)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[i] = value
+	}
+	named := make(map[string]any, len(kwargs))
+	for _, arg := range kwargs {
+		value, err := evaluate(arg.value, sc)
+		if err != nil {
+			return nil, nil, err
+		}
+		named[arg.name] = value
+	}
+	return values, named, nil
+}
+
+func evalCall(e callExpr, sc *scope) (any, error) {
+	fn, err := evaluate(e.fn, sc)
+	if err != nil {
+		return nil, err
+	}
+	args, kwargs, err := evalArgs(e.args, e.kwargs, sc)
+	if err != nil {
+		return nil, err
+	}
+	callable, ok := fn.(function)
+	if !ok {
+		name := "表达式"
+		switch target := e.fn.(type) {
+		case nameExpr:
+			name = target.name
+		case attrExpr:
+			name = target.name
+		}
+		return nil, fmt.Errorf("%s 不是函数", name)
+	}
+	return callable(args, kwargs)
+}
+
+func evalFilter(e filterExpr, sc *scope) (any, error) {
+	value, err := evaluate(e.target, sc)
+	if err != nil {
+		return nil, err
+	}
+	args, kwargs, err := evalArgs(e.args, e.kwargs, sc)
+	if err != nil {
+		return nil, err
+	}
+	filter, ok := filters[e.name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的过滤器 %s", e.name)
+	}
+	return filter(value, args, kwargs)
+}
+
+func evalTest(e testExpr, sc *scope) (any, error) {
+	value, err := evaluate(e.target, sc)
+	if err != nil {
+		return nil, err
+	}
+	args, _, err := evalArgs(e.args, nil, sc)
+	if err != nil {
+		return nil, err
+	}
+	result, err := runTest(e.name, value, args)
+	if err != nil {
+		return nil, err
+	}
+	return result != e.negate, nil
+}
+
+func evalUnary(e unaryExpr, sc *scope) (any, error) {
+	value, err := evaluate(e.operand, sc)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "not":
+		return !truthy(value), nil
+	case "-":
+		switch v := value.(type) {
+		case int:
+			return -v, nil
+		case float64:
+			return -v, nil
+		}
+		return nil, fmt.Errorf("%s 不能取负数", repr(value))
+	}
+	return value, nil
+}
+
+func evalBinary(e binaryExpr, sc *scope) (any, error) {
+	left, err := evaluate(e.left, sc)
+	if err != nil {
+		return nil, err
+	}
+	// and 和 or 短路求值，返回决定结果的那个值
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return evaluate(e.right, sc)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return evaluate(e.right, sc)
+	}
+
+	right, err := evaluate(e.right, sc)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", ">", "<=", ">=":
+		result, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case "<":
+			return result < 0, nil
+		case ">":
+			return result > 0, nil
+		case "<=":
+			return result <= 0, nil
+		}
+		return result >= 0, nil
+	case "in", "not in":
+		found, err := contains(right, left)
+		if err != nil {
+			return nil, err
+		}
+		return found == (e.op == "in"), nil
+	case "~":
+		return toString(left) + toString(right), nil
+	}
+	return arithmetic(e.op, left, right)
+}
+
+// arithmetic 算术运算，整数之间的运算结果仍是整数，除法除外
+func arithmetic(op string, left, right any) (any, error) {
+	switch op {
+	case "+":
+		switch l := left.(type) {
+		case string:
+			// 字符串与未定义的值相加时按空字符串处理
+			if _, ok := right.(undefined); ok {
+				return l, nil
+			}
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		case []any:
+			if r, ok := right.([]any); ok {
+				return append(append([]any{}, l...), r...), nil
+			}
+		case undefined:
+			if r, ok := right.(string); ok {
+				return r, nil
+			}
+		}
+	case "*":
+		if l, ok := left.(string); ok {
+			if r, ok := right.(int); ok {
+				return strings.Repeat(l, max(r, 0)), nil
+			}
+		}
+	}
+
+	l, okLeft := toNumber(left)
+	r, okRight := toNumber(right)
+	if !okLeft || !okRight {
+		return nil, fmt.Errorf("不支持的运算: %s %s %s", repr(left), op, repr(right))
+	}
+	li, leftInt := left.(int)
+	ri, rightInt := right.(int)
+	bothInt := leftInt && rightInt
+
+	switch op {
+	case "+":
+		if bothInt {
+			return li + ri, nil
+		}
+		return l + r, nil
+	case "-":
+		if bothInt {
+			return li - ri, nil
+		}
+		return l - r, nil
+	case "*":
+		if bothInt {
+			return li * ri, nil
+		}
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, errors.New("除数不能为0")
+		}
+		return l / r, nil
+	case "//", "%":
+		if r == 0 {
+			return nil, errors.New("除数不能为0")
+		}
+		// Python 的整除向下取整，取余的符号与除数相同
+		quotient := math.Floor(l / r)
+		if op == "//" {
+			if bothInt {
+				return int(quotient), nil
+			}
+			return quotient, nil
+		}
+		if bothInt {
+			return li - ri*int(quotient), nil
+		}
+		return l - r*quotient, nil
+	case "**":
+		result := math.Pow(l, r)
+		if bothInt && ri >= 0 {
+			return int(result), nil
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("不支持的运算符 %s", op)
+}
+
+// capitalize 首字母大写，其余小写
+func capitalize(value string) string {
+	if value == "" {
+		return value
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	return strings.ToUpper(string(r)) + strings.ToLower(value[size:])
+}
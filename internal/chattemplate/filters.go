@@ -0,0 +1,814 @@
+package chattemplate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filterFunc 过滤器，value 为 | 左边的值
+type filterFunc func(value any, args []any, kwargs map[string]any) (any, error)
+
+// filters 常见对话模板中用到的过滤器
+var filters map[string]filterFunc
+
+func init() {
+	filters = map[string]filterFunc{
+		"trim":       filterTrim,
+		"length":     filterLength,
+		"count":      filterLength,
+		"upper":      stringFilter(strings.ToUpper),
+		"lower":      stringFilter(strings.ToLower),
+		"title":      stringFilter(title),
+		"capitalize": stringFilter(capitalize),
+		"string":     func(value any, _ []any, _ map[string]any) (any, error) { return toString(value), nil },
+		"safe":       func(value any, _ []any, _ map[string]any) (any, error) { return value, nil },
+		"tojson":     filterToJSON,
+		"default":    filterDefault,
+		"d":          filterDefault,
+		"join":       filterJoin,
+		"first":      filterFirst,
+		"last":       filterLast,
+		"list":       filterList,
+		"items":      filterItems,
+		"reverse":    filterReverse,
+		"replace":    filterReplace,
+		"int":        filterInt,
+		"float":      filterFloat,
+		"abs":        filterAbs,
+		"round":      filterRound,
+		"unique":     filterUnique,
+		"sort":       filterSort,
+		"dictsort":   filterDictSort,
+		"indent":     filterIndent,
+		"map":        filterMap,
+		"select":     selectFilter(false, false),
+		"reject":     selectFilter(true, false),
+		"selectattr": selectFilter(false, true),
+		"rejectattr": selectFilter(true, true),
+	}
+}
+
+func stringFilter(fn func(string) string) filterFunc {
+	return func(value any, _ []any, _ map[string]any) (any, error) {
+		return fn(toString(value)), nil
+	}
+}
+
+// arg 取第 index 个位置参数，没有时使用同名的关键字参数
+func arg(args []any, kwargs map[string]any, index int, name string) (any, bool) {
+	if index < len(args) {
+		return args[index], true
+	}
+	value, ok := kwargs[name]
+	return value, ok
+}
+
+func filterTrim(value any, args []any, kwargs map[string]any) (any, error) {
+	chars := whitespace
+	if value, ok := arg(args, kwargs, 0, "chars"); ok && value != nil {
+		chars = toString(value)
+	}
+	return strings.Trim(toString(value), chars), nil
+}
+
+func filterLength(value any, _ []any, _ map[string]any) (any, error) {
+	return length(value)
+}
+
+func filterToJSON(value any, args []any, kwargs map[string]any) (any, error) {
+	indent := 0
+	if value, ok := arg(args, kwargs, 0, "indent"); ok && value != nil {
+		number, ok := value.(int)
+		if !ok {
+			return nil, errors.New("tojson 的 indent 必须是整数")
+		}
+		indent = number
+	}
+	sortKeys := truthy(kwargs["sort_keys"])
+	return toJSON(value, indent, sortKeys)
+}
+
+// filterDefault 值未定义时使用默认值，第二个参数为true时假值也使用默认值
+func filterDefault(value any, args []any, kwargs map[string]any) (any, error) {
+	fallback, _ := arg(args, kwargs, 0, "default_value")
+	if fallback == nil && len(args) == 0 {
+		fallback = ""
+	}
+	boolean, _ := arg(args, kwargs, 1, "boolean")
+	if _, ok := value.(undefined); ok || truthy(boolean) && !truthy(value) {
+		return fallback, nil
+	}
+	return value, nil
+}
+
+func filterJoin(value any, args []any, kwargs map[string]any) (any, error) {
+	items, err := iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	separator := ""
+	if value, ok := arg(args, kwargs, 0, "d"); ok {
+		separator = toString(value)
+	}
+	attribute, hasAttribute := arg(args, kwargs, 1, "attribute")
+	parts := make([]string, len(items))
+	for i, item := range items {
+		if hasAttribute {
+			item = getItem(item, attribute)
+		}
+		parts[i] = toString(item)
+	}
+	return strings.Join(parts, separator), nil
+}
+
+func filterFirst(value any, _ []any, _ map[string]any) (any, error) {
+	items, err := iterate(value)
+	if err != nil || len(items) == 0 {
+		return undefined{}, err
+	}
+	return items[0], nil
+}
+
+func filterLast(value any, _ []any, _ map[string]any) (any, error) {
+	items, err := iterate(value)
+	if err != nil || len(items) == 0 {
+		return undefined{}, err
+	}
+	return items[len(items)-1], nil
+}
+
+func filterList(value any, _ []any, _ map[string]any) (any, error) {
+	items, err := iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]any{}, items...), nil
+}
+
+// filterItems 字典的键值对列表
+func filterItems(value any, _ []any, _ map[string]any) (any, error) {
+	d, ok := value.(*dict)
+	if !ok {
+		if _, ok := value.(undefined); ok || value == nil {
+			return []any{}, nil
+		}
+		return nil, fmt.Errorf("%s 不是字典", repr(value))
+	}
+	items := make([]any, len(d.keys))
+	for i, key := range d.keys {
+		items[i] = []any{key, d.values[key]}
+	}
+	return items, nil
+}
+
+func filterReverse(value any, _ []any, _ map[string]any) (any, error) {
+	if text, ok := value.(string); ok {
+		runes := []rune(text)
+		slices.Reverse(runes)
+		return string(runes), nil
+	}
+	items, err := iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	result := append([]any{}, items...)
+	slices.Reverse(result)
+	return result, nil
+}
+
+func filterReplace(value any, args []any, kwargs map[string]any) (any, error) {
+	if len(args) < 2 {
+		return nil, errors.New("replace 需要两个参数")
+	}
+	count := -1
+	if value, ok := arg(args, kwargs, 2, "count"); ok {
+		if number, ok := value.(int); ok {
+			count = number
+		}
+	}
+	return strings.Replace(toString(value), toString(args[0]), toString(args[1]), count), nil
+}
+
+func filterInt(value any, args []any, kwargs map[string]any) (any, error) {
+	fallback := 0
+	if value, ok := arg(args, kwargs, 0, "default"); ok {
+		if number, ok := value.(int); ok {
+			fallback = number
+		}
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		if number, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return number, nil
+		}
+		if number, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return int(number), nil
+		}
+	}
+	return fallback, nil
+}
+
+func filterFloat(value any, _ []any, _ map[string]any) (any, error) {
+	if number, ok := toNumber(value); ok {
+		return number, nil
+	}
+	if text, ok := value.(string); ok {
+		if number, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil {
+			return number, nil
+		}
+	}
+	return 0.0, nil
+}
+
+func filterAbs(value any, _ []any, _ map[string]any) (any, error) {
+	switch v := value.(type) {
+	case int:
+		return max(v, -v), nil
+	case float64:
+		return math.Abs(v), nil
+	}
+	return nil, fmt.Errorf("%s 不是数字", repr(value))
+}
+
+func filterRound(value any, args []any, kwargs map[string]any) (any, error) {
+	number, ok := toNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是数字", repr(value))
+	}
+	precision := 0
+	if value, ok := arg(args, kwargs, 0, "precision"); ok {
+		if p, ok := value.(int); ok {
+			precision = p
+		}
+	}
+	scale := math.Pow(10, float64(precision))
+	return math.Round(number*scale) / scale, nil
+}
+
+func filterUnique(value any, _ []any, _ map[string]any) (any, error) {
+	items, err := iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	var result []any
+	for _, item := range items {
+		if !slices.ContainsFunc(result, func(existing any) bool { return equal(existing, item) }) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func filterSort(value any, args []any, kwargs map[string]any) (any, error) {
+	items, err := iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	reverse, _ := arg(args, kwargs, 0, "reverse")
+	attribute, hasAttribute := kwargs["attribute"]
+	result := append([]any{}, items...)
+	var sortErr error
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if hasAttribute {
+			a, b = getItem(a, attribute), getItem(b, attribute)
+		}
+		order, err := compare(a, b)
+		if err != nil {
+			sortErr = err
+		}
+		if truthy(reverse) {
+			return order > 0
+		}
+		return order < 0
+	})
+	return result, sortErr
+}
+
+// filterDictSort 按键排序的键值对列表
+func filterDictSort(value any, args []any, kwargs map[string]any) (any, error) {
+	items, err := filterItems(value, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := items.([]any)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].([]any)[0].(string) < result[j].([]any)[0].(string)
+	})
+	return result, nil
+}
+
+// filterIndent 除第一行外每行前加空格，first 为true时第一行也缩进
+func filterIndent(value any, args []any, kwargs map[string]any) (any, error) {
+	width := 4
+	if value, ok := arg(args, kwargs, 0, "width"); ok {
+		if number, ok := value.(int); ok {
+			width = number
+		}
+	}
+	first, _ := arg(args, kwargs, 1, "first")
+	prefix := strings.Repeat(" ", width)
+	lines := strings.Split(toString(value), "\n")
+	for i := range lines {
+		if (i > 0 || truthy(first)) && lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// filterMap 取每个元素的属性，或对每个元素应用过滤器
+func filterMap(value any, args []any, kwargs map[string]any) (any, error) {
+	items, err := iterate(value)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]any, len(items))
+	if attribute, ok := kwargs["attribute"]; ok {
+		fallback, hasDefault := kwargs["default"]
+		for i, item := range items {
+			result[i] = getItem(item, attribute)
+			if _, missing := result[i].(undefined); missing && hasDefault {
+				result[i] = fallback
+			}
+		}
+		return result, nil
+	}
+	if len(args) == 0 {
+		return nil, errors.New("map 需要过滤器名称或 attribute 参数")
+	}
+	filter, ok := filters[toString(args[0])]
+	if !ok {
+		return nil, fmt.Errorf("不支持的过滤器 %s", toString(args[0]))
+	}
+	for i, item := range items {
+		if result[i], err = filter(item, args[1:], nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// selectFilter select/reject/selectattr/rejectattr，没有指定测试时按真值判断
+func selectFilter(reject, byAttribute bool) filterFunc {
+	return func(value any, args []any, _ map[string]any) (any, error) {
+		items, err := iterate(value)
+		if err != nil {
+			return nil, err
+		}
+		var attribute any
+		if byAttribute {
+			if len(args) == 0 {
+				return nil, errors.New("selectattr 需要属性名")
+			}
+			attribute, args = args[0], args[1:]
+		}
+
+		var result []any
+		for _, item := range items {
+			subject := item
+			if byAttribute {
+				subject = getItem(item, attribute)
+			}
+			matched := truthy(subject)
+			if len(args) > 0 {
+				if matched, err = runTest(toString(args[0]), subject, args[1:]); err != nil {
+					return nil, err
+				}
+			}
+			if matched != reject {
+				result = append(result, item)
+			}
+		}
+		return result, nil
+	}
+}
+
+// runTest 执行 is 测试
+func runTest(name string, value any, args []any) (bool, error) {
+	argument := func() (any, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("测试 %s 需要参数", name)
+		}
+		return args[0], nil
+	}
+
+	switch name {
+	case "defined":
+		_, missing := value.(undefined)
+		return !missing, nil
+	case "undefined":
+		_, missing := value.(undefined)
+		return missing, nil
+	case "none":
+		return value == nil, nil
+	case "string":
+		_, ok := value.(string)
+		return ok, nil
+	case "number":
+		_, ok := toNumber(value)
+		return ok, nil
+	case "integer":
+		_, ok := value.(int)
+		return ok, nil
+	case "float":
+		_, ok := value.(float64)
+		return ok, nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok, nil
+	case "true":
+		return value == true, nil
+	case "false":
+		return value == false, nil
+	case "mapping":
+		_, ok := value.(*dict)
+		return ok, nil
+	case "sequence", "iterable":
+		switch value.(type) {
+		case string, []any, *dict:
+			return true, nil
+		}
+		return false, nil
+	case "callable":
+		_, ok := value.(function)
+		return ok, nil
+	case "lower", "upper":
+		text, ok := value.(string)
+		if name == "lower" {
+			return ok && strings.ToLower(text) == text, nil
+		}
+		return ok && strings.ToUpper(text) == text, nil
+	case "odd", "even":
+		number, ok := value.(int)
+		if !ok {
+			return false, nil
+		}
+		return (number%2 != 0) == (name == "odd"), nil
+	case "divisibleby":
+		other, err := argument()
+		if err != nil {
+			return false, err
+		}
+		a, aok := value.(int)
+		b, bok := other.(int)
+		return aok && bok && b != 0 && a%b == 0, nil
+	case "equalto", "eq", "==", "sameas":
+		other, err := argument()
+		return err == nil && equal(value, other), err
+	case "ne", "!=":
+		other, err := argument()
+		return err == nil && !equal(value, other), err
+	case "in":
+		other, err := argument()
+		if err != nil {
+			return false, err
+		}
+		return contains(other, value)
+	case "gt", "ge", "lt", "le", ">", ">=", "<", "<=":
+		other, err := argument()
+		if err != nil {
+			return false, err
+		}
+		order, err := compare(value, other)
+		if err != nil {
+			return false, err
+		}
+		switch name {
+		case "gt", ">":
+			return order > 0, nil
+		case "ge", ">=":
+			return order >= 0, nil
+		case "lt", "<":
+			return order < 0, nil
+		}
+		return order <= 0, nil
+	}
+	return false, fmt.Errorf("不支持的测试 %s", name)
+}
+
+// lookupMethod 字符串、字典和列表上常用的 Python 方法，不存在时返回nil
+func lookupMethod(target any, name string) function {
+	switch v := target.(type) {
+	case string:
+		return stringMethod(v, name)
+	case *dict:
+		return dictMethod(v, name)
+	case []any:
+		if name == "index" {
+			return func(args []any, _ map[string]any) (any, error) {
+				for i, item := range v {
+					if len(args) > 0 && equal(item, args[0]) {
+						return i, nil
+					}
+				}
+				return nil, errors.New("列表中没有这个元素")
+			}
+		}
+	}
+	return nil
+}
+
+func stringMethod(text, name string) function {
+	chars := func(args []any) string {
+		if len(args) > 0 && args[0] != nil {
+			return toString(args[0])
+		}
+		return whitespace
+	}
+	// prefixes startswith 和 endswith 的参数可以是字符串或字符串列表
+	prefixes := func(args []any) []string {
+		if len(args) == 0 {
+			return nil
+		}
+		if items, ok := args[0].([]any); ok {
+			result := make([]string, len(items))
+			for i, item := range items {
+				result[i] = toString(item)
+			}
+			return result
+		}
+		return []string{toString(args[0])}
+	}
+
+	var fn func(args []any, kwargs map[string]any) (any, error)
+	switch name {
+	case "strip":
+		fn = func(args []any, _ map[string]any) (any, error) { return strings.Trim(text, chars(args)), nil }
+	case "lstrip":
+		fn = func(args []any, _ map[string]any) (any, error) { return strings.TrimLeft(text, chars(args)), nil }
+	case "rstrip":
+		fn = func(args []any, _ map[string]any) (any, error) { return strings.TrimRight(text, chars(args)), nil }
+	case "upper":
+		fn = func(_ []any, _ map[string]any) (any, error) { return strings.ToUpper(text), nil }
+	case "lower":
+		fn = func(_ []any, _ map[string]any) (any, error) { return strings.ToLower(text), nil }
+	case "title":
+		fn = func(_ []any, _ map[string]any) (any, error) { return title(text), nil }
+	case "capitalize":
+		fn = func(_ []any, _ map[string]any) (any, error) { return capitalize(text), nil }
+	case "startswith", "endswith":
+		fn = func(args []any, _ map[string]any) (any, error) {
+			for _, prefix := range prefixes(args) {
+				if name == "startswith" && strings.HasPrefix(text, prefix) || name == "endswith" && strings.HasSuffix(text, prefix) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	case "split":
+		fn = func(args []any, kwargs map[string]any) (any, error) {
+			separator, _ := arg(args, kwargs, 0, "sep")
+			limit := -1
+			if value, ok := arg(args, kwargs, 1, "maxsplit"); ok {
+				if number, ok := value.(int); ok && number >= 0 {
+					limit = number + 1
+				}
+			}
+			var parts []string
+			if separator == nil {
+				parts = strings.Fields(text)
+				if limit > 0 && len(parts) > limit {
+					parts = append(parts[:limit-1], strings.Join(parts[limit-1:], " "))
+				}
+			} else {
+				if toString(separator) == "" {
+					return nil, errors.New("split 的分隔符不能为空")
+				}
+				parts = strings.SplitN(text, toString(separator), limit)
+			}
+			result := make([]any, len(parts))
+			for i, part := range parts {
+				result[i] = part
+			}
+			return result, nil
+		}
+	case "splitlines":
+		fn = func(_ []any, _ map[string]any) (any, error) {
+			lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+			if len(lines) > 0 && lines[len(lines)-1] == "" {
+				lines = lines[:len(lines)-1]
+			}
+			result := make([]any, len(lines))
+			for i, line := range lines {
+				result[i] = line
+			}
+			return result, nil
+		}
+	case "replace":
+		fn = func(args []any, kwargs map[string]any) (any, error) {
+			return filterReplace(text, args, kwargs)
+		}
+	case "find":
+		fn = func(args []any, _ map[string]any) (any, error) {
+			if len(args) == 0 {
+				return nil, errors.New("find 需要参数")
+			}
+			index := strings.Index(text, toString(args[0]))
+			if index < 0 {
+				return -1, nil
+			}
+			return len([]rune(text[:index])), nil
+		}
+	case "count":
+		fn = func(args []any, _ map[string]any) (any, error) {
+			if len(args) == 0 {
+				return nil, errors.New("count 需要参数")
+			}
+			return strings.Count(text, toString(args[0])), nil
+		}
+	case "join":
+		fn = func(args []any, _ map[string]any) (any, error) {
+			if len(args) == 0 {
+				return nil, errors.New("join 需要参数")
+			}
+			return filterJoin(args[0], []any{text}, nil)
+		}
+	default:
+		return nil
+	}
+	return fn
+}
+
+func dictMethod(d *dict, name string) function {
+	switch name {
+	case "get":
+		return func(args []any, _ map[string]any) (any, error) {
+			if len(args) == 0 {
+				return nil, errors.New("get 需要参数")
+			}
+			if value, ok := d.get(toString(args[0])); ok {
+				return value, nil
+			}
+			if len(args) > 1 {
+				return args[1], nil
+			}
+			return nil, nil
+		}
+	case "items":
+		return func(_ []any, _ map[string]any) (any, error) { return filterItems(d, nil, nil) }
+	case "keys":
+		return func(_ []any, _ map[string]any) (any, error) { return iterate(d) }
+	case "values":
+		return func(_ []any, _ map[string]any) (any, error) {
+			values := make([]any, len(d.keys))
+			for i, key := range d.keys {
+				values[i] = d.values[key]
+			}
+			return values, nil
+		}
+	}
+	return nil
+}
+
+// title 每个单词首字母大写
+func title(text string) string {
+	var builder strings.Builder
+	start := true
+	for _, r := range text {
+		letter := strings.ToUpper(string(r)) != strings.ToLower(string(r))
+		switch {
+		case letter && start:
+			builder.WriteString(strings.ToUpper(string(r)))
+		case letter:
+			builder.WriteString(strings.ToLower(string(r)))
+		default:
+			builder.WriteRune(r)
+		}
+		start = !letter
+	}
+	return builder.String()
+}
+
+// globals 模板中可以直接使用的函数
+func globals(now func() time.Time) map[string]any {
+	return map[string]any{
+		"range": function(func(args []any, _ map[string]any) (any, error) {
+			bounds := make([]int, len(args))
+			for i, value := range args {
+				number, ok := value.(int)
+				if !ok {
+					return nil, errors.New("range 的参数必须是整数")
+				}
+				bounds[i] = number
+			}
+			start, stop, step := 0, 0, 1
+			switch len(bounds) {
+			case 1:
+				stop = bounds[0]
+			case 2:
+				start, stop = bounds[0], bounds[1]
+			case 3:
+				start, stop, step = bounds[0], bounds[1], bounds[2]
+			default:
+				return nil, errors.New("range 需要1到3个参数")
+			}
+			if step == 0 {
+				return nil, errors.New("range 的步长不能为0")
+			}
+			var items []any
+			for i := start; step > 0 && i < stop || step < 0 && i > stop; i += step {
+				items = append(items, i)
+			}
+			return items, nil
+		}),
+		"raise_exception": function(func(args []any, _ map[string]any) (any, error) {
+			message := "模板主动报错"
+			if len(args) > 0 {
+				message = toString(args[0])
+			}
+			return nil, &TemplateError{Message: message}
+		}),
+		"namespace": function(func(args []any, kwargs map[string]any) (any, error) {
+			result := newDict()
+			if len(args) > 0 {
+				if initial, ok := args[0].(*dict); ok {
+					for _, key := range initial.keys {
+						result.set(key, initial.values[key])
+					}
+				}
+			}
+			keys := make([]string, 0, len(kwargs))
+			for key := range kwargs {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				result.set(key, kwargs[key])
+			}
+			return result, nil
+		}),
+		"dict": function(func(_ []any, kwargs map[string]any) (any, error) {
+			return fromGo(kwargs), nil
+		}),
+		"strftime_now": function(func(args []any, _ map[string]any) (any, error) {
+			if len(args) == 0 {
+				return nil, errors.New("strftime_now 需要格式参数")
+			}
+			return strftime(now(), toString(args[0])), nil
+		}),
+	}
+}
+
+// strftime 按 Python 的 strftime 格式输出时间，只支持常用的格式符
+func strftime(t time.Time, format string) string {
+	var builder strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			builder.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'Y':
+			builder.WriteString(strconv.Itoa(t.Year()))
+		case 'y':
+			fmt.Fprintf(&builder, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&builder, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&builder, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&builder, "%02d", t.Hour())
+		case 'I':
+			fmt.Fprintf(&builder, "%02d", (t.Hour()+11)%12+1)
+		case 'M':
+			fmt.Fprintf(&builder, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&builder, "%02d", t.Second())
+		case 'p':
+			builder.WriteString(t.Format("PM"))
+		case 'b':
+			builder.WriteString(t.Format("Jan"))
+		case 'B':
+			builder.WriteString(t.Format("January"))
+		case 'a':
+			builder.WriteString(t.Format("Mon"))
+		case 'A':
+			builder.WriteString(t.Format("Monday"))
+		case 'j':
+			fmt.Fprintf(&builder, "%03d", t.YearDay())
+		case '%':
+			builder.WriteByte('%')
+		default:
+			builder.WriteByte('%')
+			builder.WriteByte(format[i])
+		}
+	}
+	return builder.String()
+}
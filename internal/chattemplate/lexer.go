@@ -0,0 +1,280 @@
+package chattemplate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// whitespace Python 中 str.strip() 去掉的空白字符
+const whitespace = " \t\n\r\v\f"
+
+// segmentKind 模板片段的类型
+type segmentKind int
+
+const (
+	segmentText      segmentKind = iota
+	segmentOutput                // {{ ... }}
+	segmentStatement             // {% ... %}
+)
+
+// segment 模板按标签切分后的片段
+type segment struct {
+	kind segmentKind
+	text string
+	line int
+}
+
+// splitTemplate 把模板切分为文本和标签，同时处理空白控制。
+// 与 transformers 渲染对话模板时的设置一致，开启了 trim_blocks 和 lstrip_blocks：
+// 语句和注释标签后的第一个换行会被去掉，标签前同一行只有空白时也会被去掉
+func splitTemplate(source string) ([]segment, error) {
+	var segments []segment
+	pos := 0
+	trimNext := false    // 上一个标签以 - 结束，去掉后面文本开头的所有空白
+	dropNewline := false // 上一个是语句或注释标签，去掉后面文本开头的一个换行
+
+	for pos < len(source) {
+		start := indexTagStart(source, pos)
+		end := start
+		if start < 0 {
+			end = len(source)
+		}
+
+		text := source[pos:end]
+		lineStart := pos == 0 || source[pos-1] == '\n'
+		switch {
+		case trimNext:
+			text = strings.TrimLeft(text, whitespace)
+		case dropNewline && strings.HasPrefix(text, "\n"):
+			text, lineStart = text[1:], true
+		case dropNewline && strings.HasPrefix(text, "\r\n"):
+			text, lineStart = text[2:], true
+		}
+		trimNext, dropNewline = false, false
+
+		if start < 0 {
+			if text != "" {
+				segments = append(segments, segment{kind: segmentText, text: text})
+			}
+			break
+		}
+
+		kind := source[start+1]
+		inner := start + 2
+		if inner < len(source) && source[inner] == '-' {
+			text = strings.TrimRight(text, whitespace)
+			inner++
+		} else if inner < len(source) && source[inner] == '+' {
+			inner++
+		} else if kind != '{' {
+			text = lstripLine(text, lineStart)
+		}
+		if text != "" {
+			segments = append(segments, segment{kind: segmentText, text: text})
+		}
+
+		line := strings.Count(source[:start], "\n") + 1
+		closer := "}}"
+		switch kind {
+		case '%':
+			closer = "%}"
+		case '#':
+			closer = "#}"
+		}
+		closeAt := indexTagEnd(source, inner, closer, kind != '#')
+		if closeAt < 0 {
+			return nil, fmt.Errorf("第%d行: 标签没有结束", line)
+		}
+
+		body := source[inner:closeAt]
+		switch {
+		case strings.HasSuffix(body, "-"):
+			body = body[:len(body)-1]
+			trimNext = true
+		case strings.HasSuffix(body, "+"):
+			body = body[:len(body)-1]
+		case kind != '{':
+			dropNewline = true
+		}
+
+		switch kind {
+		case '{':
+			segments = append(segments, segment{kind: segmentOutput, text: body, line: line})
+		case '%':
+			segments = append(segments, segment{kind: segmentStatement, text: body, line: line})
+		}
+		pos = closeAt + len(closer)
+	}
+	return segments, nil
+}
+
+// indexTagStart 查找下一个 {{、{% 或 {#
+func indexTagStart(source string, from int) int {
+	for i := from; i < len(source)-1; i++ {
+		if source[i] == '{' && (source[i+1] == '{' || source[i+1] == '%' || source[i+1] == '#') {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexTagEnd 查找标签的结束位置，表达式中引号内的内容不算
+func indexTagEnd(source string, from int, closer string, quoted bool) int {
+	for i := from; i < len(source); i++ {
+		if quoted && (source[i] == '\'' || source[i] == '"') {
+			quote := source[i]
+			for i++; i < len(source) && source[i] != quote; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(source[i:], closer) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lstripLine 标签前同一行只有空白时去掉这些空白
+func lstripLine(text string, lineStart bool) string {
+	index := strings.LastIndexByte(text, '\n')
+	if index < 0 && !lineStart {
+		return text
+	}
+	if strings.Trim(text[index+1:], " \t") == "" {
+		return text[:index+1]
+	}
+	return text
+}
+
+// tokenType 表达式中的token类型
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenName
+	tokenString
+	tokenInt
+	tokenFloat
+	tokenOperator
+)
+
+type token struct {
+	typ   tokenType
+	value string
+}
+
+// operators 按长度从长到短排列，优先匹配双字符运算符
+var operators = []string{
+	"//", "**", "==", "!=", "<=", ">=",
+	"(", ")", "[", "]", "{", "}", ",", ":", ".", "|", "~", "+", "-", "*", "/", "%", "<", ">", "=",
+}
+
+// tokenize 把标签内的表达式切分为token
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case strings.IndexByte(whitespace, c) >= 0:
+			i++
+		case c == '\'' || c == '"':
+			value, n, err := readString(source[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, value})
+			i += n
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(source) && source[j] >= '0' && source[j] <= '9' {
+				j++
+			}
+			typ := tokenInt
+			if j+1 < len(source) && source[j] == '.' && source[j+1] >= '0' && source[j+1] <= '9' {
+				typ = tokenFloat
+				for j++; j < len(source) && source[j] >= '0' && source[j] <= '9'; j++ {
+				}
+			}
+			tokens = append(tokens, token{typ, source[i:j]})
+			i = j
+		case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(source) && (source[j] == '_' || source[j] < utf8.RuneSelf && (unicode.IsLetter(rune(source[j])) || unicode.IsDigit(rune(source[j])))) {
+				j++
+			}
+			tokens = append(tokens, token{tokenName, source[i:j]})
+			i = j
+		default:
+			matched := ""
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					matched = op
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("无法识别的字符 %q", c)
+			}
+			tokens = append(tokens, token{tokenOperator, matched})
+			i += len(matched)
+		}
+	}
+	return tokens, nil
+}
+
+// readString 读取一个带引号的字符串，返回内容和消耗的字节数，转义规则与 Python 一致
+func readString(source string) (string, int, error) {
+	quote := source[0]
+	var builder strings.Builder
+	for i := 1; i < len(source); i++ {
+		c := source[i]
+		if c == quote {
+			return builder.String(), i + 1, nil
+		}
+		if c != '\\' || i+1 >= len(source) {
+			builder.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch source[i] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 't':
+			builder.WriteByte('\t')
+		case 'r':
+			builder.WriteByte('\r')
+		case '0':
+			builder.WriteByte(0)
+		case '\\', '\'', '"':
+			builder.WriteByte(source[i])
+		case '\n':
+			// 行尾的反斜杠表示续行
+		case 'u', 'x':
+			size := 4
+			if source[i] == 'x' {
+				size = 2
+			}
+			if i+size < len(source) {
+				if code, err := strconv.ParseUint(source[i+1:i+1+size], 16, 32); err == nil {
+					builder.WriteRune(rune(code))
+					i += size
+					continue
+				}
+			}
+			builder.WriteByte('\\')
+			builder.WriteByte(source[i])
+		default:
+			// 未知的转义保持原样
+			builder.WriteByte('\\')
+			builder.WriteByte(source[i])
+		}
+	}
+	return "", 0, fmt.Errorf("字符串没有结束: %s", source)
+}
@@ -0,0 +1,849 @@
+package chattemplate
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// 模板节点
+type (
+	node any
+
+	textNode struct {
+		text string
+	}
+
+	outputNode struct {
+		expr expr
+	}
+
+	// ifNode if/elif 的每个分支一个条件
+	ifNode struct {
+		conds    []expr
+		bodies   [][]node
+		elseBody []node
+	}
+
+	forNode struct {
+		targets  []string
+		iter     expr
+		filter   expr // for x in items if cond 中的条件，没有时为nil
+		body     []node
+		elseBody []node // 没有元素时渲染
+	}
+
+	// setNode attr 不为空时给 namespace 的属性赋值，value 为nil时是 {% set x %}...{% endset %}
+	setNode struct {
+		name  string
+		attr  string
+		value expr
+		body  []node
+	}
+
+	macroNode struct {
+		name     string
+		params   []string
+		defaults []expr // 与 params 一一对应，没有默认值时为nil
+		body     []node
+	}
+
+	breakNode    struct{}
+	continueNode struct{}
+)
+
+// 表达式节点
+type (
+	expr any
+
+	literal struct {
+		value any
+	}
+
+	nameExpr struct {
+		name string
+	}
+
+	listExpr struct {
+		items []expr
+	}
+
+	dictExpr struct {
+		keys   []expr
+		values []expr
+	}
+
+	attrExpr struct {
+		target expr
+		name   string
+	}
+
+	indexExpr struct {
+		target expr
+		index  expr
+	}
+
+	sliceExpr struct {
+		target            expr
+		start, stop, step expr
+	}
+
+	callExpr struct {
+		fn     expr
+		args   []expr
+		kwargs []kwarg
+	}
+
+	filterExpr struct {
+		target expr
+		name   string
+		args   []expr
+		kwargs []kwarg
+	}
+
+	testExpr struct {
+		target expr
+		name   string
+		args   []expr
+		negate bool
+	}
+
+	unaryExpr struct {
+		op      string
+		operand expr
+	}
+
+	binaryExpr struct {
+		op          string
+		left, right expr
+	}
+
+	condExpr struct {
+		cond, then, otherwise expr // 没有 else 时 otherwise 为nil
+	}
+
+	kwarg struct {
+		name  string
+		value expr
+	}
+)
+
+// statement 一个 {% %} 标签，keyword 为第一个名称
+type statement struct {
+	keyword string
+	expr    *exprParser
+	line    int
+}
+
+// parser 把片段解析为节点树
+type parser struct {
+	segments []segment
+	pos      int
+}
+
+func parse(source string) ([]node, error) {
+	segments, err := splitTemplate(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{segments: segments}
+	nodes, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, fmt.Errorf("第%d行: 多余的标签 %s", end.line, end.keyword)
+	}
+	return nodes, nil
+}
+
+// parseBody 解析到 endTags 中的任意一个标签为止，返回遇到的结束标签，到模板末尾时为nil
+func (p *parser) parseBody(endTags ...string) ([]node, *statement, error) {
+	var nodes []node
+	for p.pos < len(p.segments) {
+		seg := p.segments[p.pos]
+		p.pos++
+
+		switch seg.kind {
+		case segmentText:
+			nodes = append(nodes, textNode{text: seg.text})
+		case segmentOutput:
+			ep, err := newExprParser(seg.text, seg.line)
+			if err != nil {
+				return nil, nil, err
+			}
+			value, err := ep.parseExpression()
+			if err == nil {
+				err = ep.expectEnd()
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, outputNode{expr: value})
+		case segmentStatement:
+			stmt, err := newStatement(seg)
+			if err != nil {
+				return nil, nil, err
+			}
+			if slices.Contains(endTags, stmt.keyword) {
+				return nodes, stmt, nil
+			}
+			parsed, err := p.parseStatement(stmt)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, parsed...)
+		}
+	}
+	if len(endTags) > 0 {
+		return nil, nil, fmt.Errorf("缺少结束标签 %s", endTags[len(endTags)-1])
+	}
+	return nodes, nil, nil
+}
+
+func newStatement(seg segment) (*statement, error) {
+	ep, err := newExprParser(seg.text, seg.line)
+	if err != nil {
+		return nil, err
+	}
+	keyword := ep.next()
+	if keyword.typ != tokenName {
+		return nil, ep.errorf("标签缺少名称")
+	}
+	return &statement{keyword: keyword.value, expr: ep, line: seg.line}, nil
+}
+
+// parseStatement 解析一个语句标签，generation 等只起标记作用的标签直接展开为内部的节点
+func (p *parser) parseStatement(stmt *statement) ([]node, error) {
+	ep := stmt.expr
+	switch stmt.keyword {
+	case "if":
+		result, err := p.parseIf(stmt)
+		return []node{result}, err
+	case "for":
+		result, err := p.parseFor(stmt)
+		return []node{result}, err
+	case "set":
+		result, err := p.parseSet(stmt)
+		return []node{result}, err
+	case "macro":
+		result, err := p.parseMacro(stmt)
+		return []node{result}, err
+	case "break", "continue":
+		if err := ep.expectEnd(); err != nil {
+			return nil, err
+		}
+		if stmt.keyword == "break" {
+			return []node{breakNode{}}, nil
+		}
+		return []node{continueNode{}}, nil
+	case "generation":
+		body, _, err := p.parseBody("endgeneration")
+		return body, err
+	}
+	return nil, fmt.Errorf("第%d行: 不支持的标签 %s", stmt.line, stmt.keyword)
+}
+
+func (p *parser) parseIf(stmt *statement) (node, error) {
+	result := ifNode{}
+	for {
+		cond, err := stmt.expr.parseExpression()
+		if err == nil {
+			err = stmt.expr.expectEnd()
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, end, err := p.parseBody("elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		result.conds = append(result.conds, cond)
+		result.bodies = append(result.bodies, body)
+
+		switch end.keyword {
+		case "elif":
+			stmt = end
+		case "else":
+			if err := end.expr.expectEnd(); err != nil {
+				return nil, err
+			}
+			result.elseBody, _, err = p.parseBody("endif")
+			return result, err
+		default:
+			return result, nil
+		}
+	}
+}
+
+func (p *parser) parseFor(stmt *statement) (node, error) {
+	ep := stmt.expr
+	result := forNode{}
+	for {
+		name := ep.next()
+		if name.typ != tokenName {
+			return nil, ep.errorf("for 后面需要变量名")
+		}
+		result.targets = append(result.targets, name.value)
+		if !ep.skipOperator(",") {
+			break
+		}
+	}
+	if !ep.skipName("in") {
+		return nil, ep.errorf("for 语句缺少 in")
+	}
+
+	// 可迭代对象不能是条件表达式，否则会吞掉后面的 if 过滤条件
+	iter, err := ep.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	result.iter = iter
+	if ep.skipName("if") {
+		if result.filter, err = ep.parseExpression(); err != nil {
+			return nil, err
+		}
+	}
+	ep.skipName("recursive")
+	if err := ep.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	body, end, err := p.parseBody("else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	result.body = body
+	if end.keyword == "else" {
+		result.elseBody, _, err = p.parseBody("endfor")
+	}
+	return result, err
+}
+
+func (p *parser) parseSet(stmt *statement) (node, error) {
+	ep := stmt.expr
+	name := ep.next()
+	if name.typ != tokenName {
+		return nil, ep.errorf("set 后面需要变量名")
+	}
+	result := setNode{name: name.value}
+	if ep.skipOperator(".") {
+		attr := ep.next()
+		if attr.typ != tokenName {
+			return nil, ep.errorf("set 的属性名无效")
+		}
+		result.attr = attr.value
+	}
+
+	if !ep.skipOperator("=") {
+		if err := ep.expectEnd(); err != nil {
+			return nil, err
+		}
+		body, _, err := p.parseBody("endset")
+		result.body = body
+		return result, err
+	}
+	value, err := ep.parseExpression()
+	if err == nil {
+		err = ep.expectEnd()
+	}
+	result.value = value
+	return result, err
+}
+
+func (p *parser) parseMacro(stmt *statement) (node, error) {
+	ep := stmt.expr
+	name := ep.next()
+	if name.typ != tokenName || !ep.skipOperator("(") {
+		return nil, ep.errorf("macro 定义无效")
+	}
+	result := macroNode{name: name.value}
+	for !ep.skipOperator(")") {
+		param := ep.next()
+		if param.typ != tokenName {
+			return nil, ep.errorf("macro 参数无效")
+		}
+		var value expr
+		if ep.skipOperator("=") {
+			var err error
+			if value, err = ep.parseExpression(); err != nil {
+				return nil, err
+			}
+		}
+		result.params = append(result.params, param.value)
+		result.defaults = append(result.defaults, value)
+		if !ep.skipOperator(",") && !ep.isOperator(")") {
+			return nil, ep.errorf("macro 参数之间需要逗号")
+		}
+	}
+	if err := ep.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	body, _, err := p.parseBody("endmacro")
+	result.body = body
+	return result, err
+}
+
+// exprParser 表达式解析，运算符优先级与 Jinja 一致
+type exprParser struct {
+	tokens []token
+	pos    int
+	line   int
+}
+
+func newExprParser(source string, line int) (*exprParser, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, fmt.Errorf("第%d行: %v", line, err)
+	}
+	return &exprParser{tokens: tokens, line: line}, nil
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("第%d行: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) peek() token {
+	return p.peekAt(0)
+}
+
+func (p *exprParser) peekAt(offset int) token {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+	return token{typ: tokenEOF}
+}
+
+func (p *exprParser) next() token {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isOperator(op string) bool {
+	tok := p.peek()
+	return tok.typ == tokenOperator && tok.value == op
+}
+
+func (p *exprParser) isName(name string) bool {
+	tok := p.peek()
+	return tok.typ == tokenName && tok.value == name
+}
+
+func (p *exprParser) skipOperator(op string) bool {
+	if p.isOperator(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) skipName(name string) bool {
+	if p.isName(name) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expectOperator(op string) error {
+	if !p.skipOperator(op) {
+		return p.errorf("需要 %s，实际为 %q", op, p.peek().value)
+	}
+	return nil
+}
+
+func (p *exprParser) expectEnd() error {
+	if tok := p.peek(); tok.typ != tokenEOF {
+		return p.errorf("多余的内容 %q", tok.value)
+	}
+	return nil
+}
+
+// parseExpression 解析完整的表达式，包括 a if cond else b
+func (p *exprParser) parseExpression() (expr, error) {
+	value, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipName("if") {
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		result := condExpr{cond: cond, then: value}
+		if p.skipName("else") {
+			if result.otherwise, err = p.parseExpression(); err != nil {
+				return nil, err
+			}
+		}
+		value = result
+	}
+	return value, nil
+}
+
+func (p *exprParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.skipName("or") {
+		var right expr
+		right, err = p.parseAnd()
+		left = binaryExpr{op: "or", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	for err == nil && p.skipName("and") {
+		var right expr
+		right, err = p.parseNot()
+		left = binaryExpr{op: "and", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseNot() (expr, error) {
+	if p.skipName("not") {
+		operand, err := p.parseNot()
+		return unaryExpr{op: "not", operand: operand}, err
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (expr, error) {
+	left, err := p.parseMath1()
+	for err == nil {
+		op := ""
+		tok := p.peek()
+		switch {
+		case tok.typ == tokenOperator && slices.Contains([]string{"==", "!=", "<", ">", "<=", ">="}, tok.value):
+			op = tok.value
+			p.pos++
+		case p.isName("in"):
+			op = "in"
+			p.pos++
+		case p.isName("not") && p.peekAt(1).typ == tokenName && p.peekAt(1).value == "in":
+			op = "not in"
+			p.pos += 2
+		default:
+			return left, nil
+		}
+		var right expr
+		right, err = p.parseMath1()
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseMath1() (expr, error) {
+	left, err := p.parseConcat()
+	for err == nil && (p.isOperator("+") || p.isOperator("-")) {
+		op := p.next().value
+		var right expr
+		right, err = p.parseConcat()
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseConcat() (expr, error) {
+	left, err := p.parseMath2()
+	for err == nil && p.skipOperator("~") {
+		var right expr
+		right, err = p.parseMath2()
+		left = binaryExpr{op: "~", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseMath2() (expr, error) {
+	left, err := p.parsePow()
+	for err == nil && (p.isOperator("*") || p.isOperator("/") || p.isOperator("//") || p.isOperator("%")) {
+		op := p.next().value
+		var right expr
+		right, err = p.parsePow()
+		left = binaryExpr{op: op, left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parsePow() (expr, error) {
+	left, err := p.parseUnary(true)
+	for err == nil && p.skipOperator("**") {
+		var right expr
+		right, err = p.parseUnary(true)
+		left = binaryExpr{op: "**", left: left, right: right}
+	}
+	return left, err
+}
+
+// parseUnary 负号作用于过滤器之前的值，-x|abs 等价于 (-x)|abs
+func (p *exprParser) parseUnary(withFilter bool) (expr, error) {
+	var value expr
+	var err error
+	if p.isOperator("-") || p.isOperator("+") {
+		op := p.next().value
+		var operand expr
+		operand, err = p.parseUnary(false)
+		value = unaryExpr{op: op, operand: operand}
+	} else {
+		value, err = p.parsePrimary()
+		if err == nil {
+			value, err = p.parsePostfix(value)
+		}
+	}
+	if err == nil && withFilter {
+		value, err = p.parseFilters(value)
+	}
+	return value, err
+}
+
+func (p *exprParser) parsePrimary() (expr, error) {
+	tok := p.next()
+	switch tok.typ {
+	case tokenName:
+		switch tok.value {
+		case "true", "True":
+			return literal{true}, nil
+		case "false", "False":
+			return literal{false}, nil
+		case "none", "None":
+			return literal{nil}, nil
+		}
+		return nameExpr{name: tok.value}, nil
+	case tokenString:
+		// 相邻的字符串自动拼接
+		value := tok.value
+		for p.peek().typ == tokenString {
+			value += p.next().value
+		}
+		return literal{value}, nil
+	case tokenInt:
+		value, err := strconv.Atoi(tok.value)
+		if err != nil {
+			return nil, p.errorf("无效的整数 %s", tok.value)
+		}
+		return literal{value}, nil
+	case tokenFloat:
+		value, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf("无效的数字 %s", tok.value)
+		}
+		return literal{value}, nil
+	case tokenOperator:
+		switch tok.value {
+		case "(":
+			// 括号内有逗号时是元组，按列表处理
+			items, tuple, err := p.parseSequence(")")
+			if err != nil {
+				return nil, err
+			}
+			if !tuple && len(items) == 1 {
+				return items[0], nil
+			}
+			return listExpr{items: items}, nil
+		case "[":
+			items, _, err := p.parseSequence("]")
+			return listExpr{items: items}, err
+		case "{":
+			return p.parseDict()
+		}
+	case tokenEOF:
+		return nil, p.errorf("表达式不完整")
+	}
+	return nil, p.errorf("无法识别的 %q", tok.value)
+}
+
+// parseSequence 解析逗号分隔的表达式直到 closer，返回是否出现过逗号
+func (p *exprParser) parseSequence(closer string) ([]expr, bool, error) {
+	var items []expr
+	comma := false
+	for !p.skipOperator(closer) {
+		item, err := p.parseExpression()
+		if err != nil {
+			return nil, false, err
+		}
+		items = append(items, item)
+		if p.skipOperator(",") {
+			comma = true
+		} else if !p.isOperator(closer) {
+			return nil, false, p.errorf("需要 , 或 %s", closer)
+		}
+	}
+	return items, comma, nil
+}
+
+func (p *exprParser) parseDict() (expr, error) {
+	result := dictExpr{}
+	for !p.skipOperator("}") {
+		key, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOperator(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		result.keys = append(result.keys, key)
+		result.values = append(result.values, value)
+		if !p.skipOperator(",") && !p.isOperator("}") {
+			return nil, p.errorf("需要 , 或 }")
+		}
+	}
+	return result, nil
+}
+
+// parsePostfix 属性访问、下标、切片和函数调用
+func (p *exprParser) parsePostfix(value expr) (expr, error) {
+	for {
+		switch {
+		case p.skipOperator("."):
+			name := p.next()
+			if name.typ != tokenName && name.typ != tokenInt {
+				return nil, p.errorf(". 后面需要属性名")
+			}
+			if name.typ == tokenInt {
+				index, _ := strconv.Atoi(name.value)
+				value = indexExpr{target: value, index: literal{index}}
+			} else {
+				value = attrExpr{target: value, name: name.value}
+			}
+		case p.skipOperator("["):
+			subscript, err := p.parseSubscript(value)
+			if err != nil {
+				return nil, err
+			}
+			value = subscript
+		case p.skipOperator("("):
+			args, kwargs, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			value = callExpr{fn: value, args: args, kwargs: kwargs}
+		default:
+			return value, nil
+		}
+	}
+}
+
+// parseSubscript 解析 [index] 或 [start:stop:step]
+func (p *exprParser) parseSubscript(target expr) (expr, error) {
+	var parts [3]expr
+	colons := 0
+	for !p.skipOperator("]") {
+		if p.skipOperator(":") {
+			colons++
+			if colons > 2 {
+				return nil, p.errorf("切片格式无效")
+			}
+			continue
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		parts[colons] = value
+	}
+	if colons == 0 {
+		if parts[0] == nil {
+			return nil, p.errorf("下标不能为空")
+		}
+		return indexExpr{target: target, index: parts[0]}, nil
+	}
+	return sliceExpr{target: target, start: parts[0], stop: parts[1], step: parts[2]}, nil
+}
+
+// parseArgs 解析调用参数，name=value 为关键字参数
+func (p *exprParser) parseArgs() ([]expr, []kwarg, error) {
+	var args []expr
+	var kwargs []kwarg
+	for !p.skipOperator(")") {
+		if p.peek().typ == tokenName && p.peekAt(1).typ == tokenOperator && p.peekAt(1).value == "=" {
+			name := p.next().value
+			p.pos++
+			value, err := p.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			kwargs = append(kwargs, kwarg{name: name, value: value})
+		} else {
+			value, err := p.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, value)
+		}
+		if !p.skipOperator(",") && !p.isOperator(")") {
+			return nil, nil, p.errorf("参数之间需要逗号")
+		}
+	}
+	return args, kwargs, nil
+}
+
+// parseFilters 解析 |filter 和 is test
+func (p *exprParser) parseFilters(value expr) (expr, error) {
+	for {
+		switch {
+		case p.skipOperator("|"):
+			name := p.next()
+			if name.typ != tokenName {
+				return nil, p.errorf("| 后面需要过滤器名称")
+			}
+			filter := filterExpr{target: value, name: name.value}
+			if p.skipOperator("(") {
+				var err error
+				if filter.args, filter.kwargs, err = p.parseArgs(); err != nil {
+					return nil, err
+				}
+			}
+			value = filter
+		case p.skipName("is"):
+			test := testExpr{target: value, negate: p.skipName("not")}
+			name := p.next()
+			if name.typ != tokenName {
+				return nil, p.errorf("is 后面需要测试名称")
+			}
+			test.name = name.value
+			if name.value == "none" || name.value == "None" {
+				test.name = "none"
+			}
+			if p.skipOperator("(") {
+				args, _, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				test.args = args
+			} else if p.startsTestArg() {
+				// is divisibleby 3 这种不带括号的单个参数
+				arg, err := p.parseUnary(false)
+				if err != nil {
+					return nil, err
+				}
+				test.args = []expr{arg}
+			}
+			value = test
+		default:
+			return value, nil
+		}
+	}
+}
+
+// startsTestArg 后面是否紧跟着不带括号的测试参数
+func (p *exprParser) startsTestArg() bool {
+	tok := p.peek()
+	switch tok.typ {
+	case tokenString, tokenInt, tokenFloat:
+		return true
+	case tokenName:
+		return !slices.Contains([]string{"and", "or", "else", "if", "in", "is", "not"}, tok.value)
+	case tokenOperator:
+		return tok.value == "[" || tok.value == "{"
+	}
+	return false
+}
@@ -0,0 +1,431 @@
+package chattemplate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 模板中的值使用以下Go类型表示：
+// nil(none)、undefined、bool、int、float64、string、[]any(list)、*dict、function
+
+// undefined 未定义的变量或不存在的属性，输出为空字符串
+type undefined struct {
+	name string
+}
+
+// function 可以在模板中调用的函数和方法
+type function func(args []any, kwargs map[string]any) (any, error)
+
+// dict 保持插入顺序的字典，对应 Python 的 dict，也用于 namespace 和 loop
+type dict struct {
+	keys   []string
+	values map[string]any
+}
+
+func newDict() *dict {
+	return &dict{values: make(map[string]any)}
+}
+
+func (d *dict) get(key string) (any, bool) {
+	value, ok := d.values[key]
+	return value, ok
+}
+
+func (d *dict) set(key string, value any) {
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = value
+}
+
+// truthy Python 的真值判断
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case *dict:
+		return len(v.keys) > 0
+	}
+	return true
+}
+
+// toString 输出到模板时的字符串形式
+func toString(value any) string {
+	switch v := value.(type) {
+	case undefined:
+		return ""
+	case string:
+		return v
+	}
+	return repr(value)
+}
+
+// repr 对应 Python 的 repr，列表和字典中的元素使用这种形式
+func repr(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "None"
+	case undefined:
+		return ""
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return formatFloat(v)
+	case string:
+		return quoteString(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = repr(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *dict:
+		items := make([]string, len(v.keys))
+		for i, key := range v.keys {
+			items[i] = quoteString(key) + ": " + repr(v.values[key])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case function:
+		return "<function>"
+	}
+	return fmt.Sprint(value)
+}
+
+// formatFloat 与 Python 的浮点数输出一致，整数值保留 .0
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	case math.IsNaN(value):
+		return "nan"
+	}
+	if value == math.Trunc(value) && math.Abs(value) < 1e16 {
+		return strconv.FormatFloat(value, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// quoteString Python 风格的字符串字面量，包含单引号且不含双引号时使用双引号
+func quoteString(value string) string {
+	quote := "'"
+	if strings.Contains(value, "'") && !strings.Contains(value, "\"") {
+		quote = "\""
+	}
+	replacer := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r", "\t", "\\t", quote, "\\"+quote)
+	return quote + replacer.Replace(value) + quote
+}
+
+// toJSON 与 transformers 中的 tojson 过滤器一致，即 json.dumps(ensure_ascii=False)，
+// 没有缩进时分隔符为 ", " 和 ": "
+func toJSON(value any, indent int, sortKeys bool) (string, error) {
+	var builder strings.Builder
+	if err := writeJSON(&builder, value, indent, sortKeys, 0); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+func writeJSON(builder *strings.Builder, value any, indent int, sortKeys bool, depth int) error {
+	newline := func(depth int) {
+		if indent > 0 {
+			builder.WriteByte('\n')
+			builder.WriteString(strings.Repeat(" ", indent*depth))
+		}
+	}
+	separator := ", "
+	if indent > 0 {
+		separator = ","
+	}
+
+	switch v := value.(type) {
+	case nil, undefined:
+		builder.WriteString("null")
+	case bool:
+		builder.WriteString(strconv.FormatBool(v))
+	case int:
+		builder.WriteString(strconv.Itoa(v))
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			builder.WriteString(map[bool]string{true: "Infinity", false: "NaN"}[math.IsInf(v, 0)])
+		} else {
+			builder.WriteString(formatFloat(v))
+		}
+	case string:
+		writeJSONString(builder, v)
+	case []any:
+		if len(v) == 0 {
+			builder.WriteString("[]")
+			return nil
+		}
+		builder.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				builder.WriteString(separator)
+			}
+			newline(depth + 1)
+			if err := writeJSON(builder, item, indent, sortKeys, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		builder.WriteByte(']')
+	case *dict:
+		if len(v.keys) == 0 {
+			builder.WriteString("{}")
+			return nil
+		}
+		keys := v.keys
+		if sortKeys {
+			keys = append([]string(nil), keys...)
+			sort.Strings(keys)
+		}
+		builder.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				builder.WriteString(separator)
+			}
+			newline(depth + 1)
+			writeJSONString(builder, key)
+			builder.WriteString(": ")
+			if err := writeJSON(builder, v.values[key], indent, sortKeys, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		builder.WriteByte('}')
+	default:
+		return fmt.Errorf("无法转换为JSON: %s", repr(value))
+	}
+	return nil
+}
+
+func writeJSONString(builder *strings.Builder, value string) {
+	builder.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			builder.WriteString(`\"`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		case '\b':
+			builder.WriteString(`\b`)
+		case '\f':
+			builder.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(builder, `\u%04x`, r)
+			} else {
+				builder.WriteRune(r)
+			}
+		}
+	}
+	builder.WriteByte('"')
+}
+
+// fromGo 把Go的值转换为模板中的值，用于传入 tools 等由调用方提供的数据
+func fromGo(value any) any {
+	switch v := value.(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case float32:
+		return float64(v)
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = fromGo(item)
+		}
+		return items
+	case map[string]any:
+		// Go 的 map 没有顺序，按键排序保证输出稳定
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		result := newDict()
+		for _, key := range keys {
+			result.set(key, fromGo(v[key]))
+		}
+		return result
+	}
+	return value
+}
+
+// toNumber 数字转换为 float64，布尔值不算数字
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// equal Python 的 == 比较
+func equal(a, b any) bool {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case undefined:
+		_, ok := b.(undefined)
+		return ok
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case *dict:
+		y, ok := b.(*dict)
+		if !ok || len(x.keys) != len(y.keys) {
+			return false
+		}
+		for _, key := range x.keys {
+			value, ok := y.get(key)
+			if !ok || !equal(x.values[key], value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compare 比较大小，支持数字和字符串
+func compare(a, b any) (int, error) {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	}
+	return 0, fmt.Errorf("无法比较 %s 和 %s", repr(a), repr(b))
+}
+
+// length 字符串按字符计算长度
+func length(value any) (int, error) {
+	switch v := value.(type) {
+	case string:
+		return utf8.RuneCountInString(v), nil
+	case []any:
+		return len(v), nil
+	case *dict:
+		return len(v.keys), nil
+	case undefined:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%s 没有长度", repr(value))
+}
+
+// iterate 遍历列表、字典的键或字符串的字符，none 和未定义按空列表处理
+func iterate(value any) ([]any, error) {
+	switch v := value.(type) {
+	case nil, undefined:
+		return nil, nil
+	case []any:
+		return v, nil
+	case *dict:
+		items := make([]any, len(v.keys))
+		for i, key := range v.keys {
+			items[i] = key
+		}
+		return items, nil
+	case string:
+		items := make([]any, 0, len(v))
+		for _, r := range v {
+			items = append(items, string(r))
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%s 不能遍历", repr(value))
+}
+
+// contains Python 的 in 运算
+func contains(container, item any) (bool, error) {
+	switch v := container.(type) {
+	case string:
+		text, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("字符串中只能查找字符串")
+		}
+		return strings.Contains(v, text), nil
+	case []any:
+		for _, element := range v {
+			if equal(element, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *dict:
+		key, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := v.get(key)
+		return found, nil
+	case nil, undefined:
+		return false, nil
+	}
+	return false, fmt.Errorf("%s 不支持 in 运算", repr(container))
+}
@@ -0,0 +1,416 @@
+// Package gguf 读取GGUF模型文件的头部信息，只解析元数据和张量描述，不加载张量数据
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// magic 文件开头的 "GGUF"
+const magic = 0x46554747
+
+const (
+	// maxStringLength 单个字符串的最大长度，防止损坏的文件导致分配过多内存
+	maxStringLength = 64 << 20
+	// maxArrayLength 数组的最大长度
+	maxArrayLength = 1 << 26
+	// maxDimensions 张量的最大维数
+	maxDimensions = 8
+	// maxArrayDepth 数组嵌套的最大层数，防止损坏的文件导致递归过深
+	maxArrayDepth = 8
+)
+
+// 元数据值的类型
+const (
+	typeUint8 uint32 = iota
+	typeInt8
+	typeUint16
+	typeInt16
+	typeUint32
+	typeInt32
+	typeFloat32
+	typeBool
+	typeString
+	typeArray
+	typeUint64
+	typeInt64
+	typeFloat64
+)
+
+// Tokenizer 分词器信息
+type Tokenizer struct {
+	Model     string `json:"model"` // 如 llama、gpt2
+	Pre       string `json:"pre"`   // 预分词规则，如 qwen2、llama-bpe
+	VocabSize int    `json:"vocab_size"`
+	BOSToken  string `json:"bos_token"`
+	EOSToken  string `json:"eos_token"`
+}
+
+// Metadata GGUF文件的主要信息
+type Metadata struct {
+	Version         uint32    `json:"version"`
+	Name            string    `json:"name"`
+	Architecture    string    `json:"architecture"`
+	ParameterCount  uint64    `json:"parameter_count"`
+	Quantization    string    `json:"quantization"`
+	ContextLength   uint64    `json:"context_length"`
+	EmbeddingLength uint64    `json:"embedding_length"`
+	BlockCount      uint64    `json:"block_count"`
+	TensorCount     uint64    `json:"tensor_count"`
+	Tokenizer       Tokenizer `json:"tokenizer"`
+	ChatTemplate    string    `json:"chat_template"`
+}
+
+// ReadFile 读取GGUF文件的元数据
+func ReadFile(path string) (*Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Read 从文件开头读取元数据，读取到张量描述结束为止
+func Read(r io.Reader) (*Metadata, error) {
+	d := &decoder{r: bufio.NewReaderSize(r, 1<<16)}
+
+	if value := d.uint32(); d.err == nil && value != magic {
+		return nil, errors.New("不是有效的GGUF文件")
+	}
+	meta := &Metadata{Version: d.uint32()}
+	if d.err != nil {
+		return nil, d.fail()
+	}
+	if meta.Version < 1 || meta.Version > 3 {
+		return nil, fmt.Errorf("不支持的GGUF版本: %d", meta.Version)
+	}
+	d.version = meta.Version
+
+	meta.TensorCount = d.count()
+	kvCount := d.count()
+
+	var fileType = -1
+	var tokens []string
+	var bosID, eosID = -1, -1
+	values := make(map[string]any)
+	for i := uint64(0); i < kvCount && d.err == nil; i++ {
+		key := d.string()
+		valueType := d.uint32()
+		if d.err != nil {
+			break
+		}
+
+		if key == "tokenizer.ggml.tokens" {
+			// 只保留词表用于解析特殊token，其他数组直接跳过
+			tokens = d.stringArray(valueType)
+			meta.Tokenizer.VocabSize = len(tokens)
+			continue
+		}
+		value := d.value(valueType)
+		if d.err != nil {
+			break
+		}
+		values[key] = value
+
+		switch key {
+		case "general.architecture":
+			meta.Architecture, _ = value.(string)
+		case "general.name":
+			meta.Name, _ = value.(string)
+		case "general.file_type":
+			if number, ok := toUint(value); ok {
+				fileType = int(number)
+			}
+		case "tokenizer.ggml.model":
+			meta.Tokenizer.Model, _ = value.(string)
+		case "tokenizer.ggml.pre":
+			meta.Tokenizer.Pre, _ = value.(string)
+		case "tokenizer.ggml.bos_token_id":
+			if number, ok := toUint(value); ok {
+				bosID = int(number)
+			}
+		case "tokenizer.ggml.eos_token_id":
+			if number, ok := toUint(value); ok {
+				eosID = int(number)
+			}
+		case "tokenizer.chat_template":
+			meta.ChatTemplate, _ = value.(string)
+		}
+	}
+	if d.err != nil {
+		return nil, d.fail()
+	}
+
+	// 架构相关的键以架构名为前缀，如 llama.context_length
+	prefix := meta.Architecture + "."
+	meta.ContextLength, _ = toUint(values[prefix+"context_length"])
+	meta.EmbeddingLength, _ = toUint(values[prefix+"embedding_length"])
+	meta.BlockCount, _ = toUint(values[prefix+"block_count"])
+	if bosID >= 0 && bosID < len(tokens) {
+		meta.Tokenizer.BOSToken = tokens[bosID]
+	}
+	if eosID >= 0 && eosID < len(tokens) {
+		meta.Tokenizer.EOSToken = tokens[eosID]
+	}
+
+	// 参数量为所有张量元素数之和，同时统计出现最多的张量类型作为量化类型的后备
+	typeCounts := make(map[uint32]uint64)
+	for i := uint64(0); i < meta.TensorCount && d.err == nil; i++ {
+		d.skipString()
+		dims := d.uint32()
+		if dims > maxDimensions {
+			return nil, fmt.Errorf("张量维数异常: %d", dims)
+		}
+		elements := uint64(1)
+		for j := uint32(0); j < dims; j++ {
+			elements *= d.count()
+		}
+		tensorType := d.uint32()
+		d.uint64() // 数据偏移
+		meta.ParameterCount += elements
+		typeCounts[tensorType] += elements
+	}
+	if d.err != nil {
+		return nil, d.fail()
+	}
+
+	if name, ok := fileTypes[fileType]; ok {
+		meta.Quantization = name
+	} else {
+		meta.Quantization = dominantTensorType(typeCounts)
+	}
+	return meta, nil
+}
+
+// ParameterLabel 把参数量转换为 7B、500M 这样的简写
+func ParameterLabel(count uint64) string {
+	switch {
+	case count >= 1e9:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(count)/1e9), ".0") + "B"
+	case count >= 1e6:
+		return fmt.Sprintf("%.0fM", float64(count)/1e6)
+	case count > 0:
+		return fmt.Sprintf("%.0fK", float64(count)/1e3)
+	}
+	return ""
+}
+
+// dominantTensorType 元素数最多的量化张量类型，文件没有写入 general.file_type 时使用
+func dominantTensorType(counts map[uint32]uint64) string {
+	var best uint32
+	var bestCount uint64
+	for tensorType, count := range counts {
+		// 归一化层等通常保持F32，不代表模型的量化类型
+		if tensorType == 0 && len(counts) > 1 {
+			continue
+		}
+		if count > bestCount || (count == bestCount && tensorType < best) {
+			best, bestCount = tensorType, count
+		}
+	}
+	if name, ok := tensorTypes[best]; ok {
+		return name
+	}
+	return fmt.Sprintf("type %d", best)
+}
+
+// toUint 把整数类型的元数据转换为 uint64
+func toUint(value any) (uint64, bool) {
+	switch v := value.(type) {
+	case uint8:
+		return uint64(v), true
+	case int8:
+		return uint64(max(v, 0)), true
+	case uint16:
+		return uint64(v), true
+	case int16:
+		return uint64(max(v, 0)), true
+	case uint32:
+		return uint64(v), true
+	case int32:
+		return uint64(max(v, 0)), true
+	case uint64:
+		return v, true
+	case int64:
+		return uint64(max(v, 0)), true
+	}
+	return 0, false
+}
+
+// decoder 按小端序读取，出错后后续读取都返回零值，由调用方统一检查 err
+type decoder struct {
+	r       *bufio.Reader
+	version uint32
+	err     error
+	buf     [8]byte
+	depth   int // 正在读取的数组层数
+}
+
+func (d *decoder) fail() error {
+	if errors.Is(d.err, io.EOF) || errors.Is(d.err, io.ErrUnexpectedEOF) {
+		return errors.New("GGUF文件不完整")
+	}
+	return d.err
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return d.buf[:n]
+	}
+	_, d.err = io.ReadFull(d.r, d.buf[:n])
+	return d.buf[:n]
+}
+
+func (d *decoder) uint8() uint8   { return d.read(1)[0] }
+func (d *decoder) uint16() uint16 { return binary.LittleEndian.Uint16(d.read(2)) }
+func (d *decoder) uint32() uint32 { return binary.LittleEndian.Uint32(d.read(4)) }
+func (d *decoder) uint64() uint64 { return binary.LittleEndian.Uint64(d.read(8)) }
+
+// count 读取长度和数量，版本1使用32位，之后的版本使用64位
+func (d *decoder) count() uint64 {
+	if d.version == 1 {
+		return uint64(d.uint32())
+	}
+	return d.uint64()
+}
+
+func (d *decoder) stringLength() int {
+	length := d.count()
+	if d.err == nil && length > maxStringLength {
+		d.err = fmt.Errorf("字符串长度异常: %d", length)
+	}
+	return int(length)
+}
+
+func (d *decoder) string() string {
+	length := d.stringLength()
+	if d.err != nil {
+		return ""
+	}
+	// 按实际读到的内容分配内存，文件不完整时不会按声明的长度分配
+	data, err := io.ReadAll(io.LimitReader(d.r, int64(length)))
+	if err == nil && len(data) < length {
+		err = io.ErrUnexpectedEOF
+	}
+	d.err = err
+	return string(data)
+}
+
+func (d *decoder) skipString() {
+	length := d.stringLength()
+	if d.err != nil {
+		return
+	}
+	_, d.err = d.r.Discard(length)
+}
+
+func (d *decoder) arrayHeader() (uint32, uint64) {
+	elementType := d.uint32()
+	length := d.count()
+	if d.err == nil && length > maxArrayLength {
+		d.err = fmt.Errorf("数组长度异常: %d", length)
+	}
+	return elementType, length
+}
+
+// stringArray 读取字符串数组，类型不符时跳过并返回空
+func (d *decoder) stringArray(valueType uint32) []string {
+	if valueType != typeArray {
+		d.skip(valueType)
+		return nil
+	}
+	elementType, length := d.arrayHeader()
+	if d.err != nil {
+		return nil
+	}
+	if elementType != typeString {
+		for i := uint64(0); i < length && d.err == nil; i++ {
+			d.skip(elementType)
+		}
+		return nil
+	}
+	items := make([]string, 0, min(length, 1<<16))
+	for i := uint64(0); i < length && d.err == nil; i++ {
+		items = append(items, d.string())
+	}
+	return items
+}
+
+// value 读取一个元数据值，数组只读取长度不保留内容
+func (d *decoder) value(valueType uint32) any {
+	switch valueType {
+	case typeUint8:
+		return d.uint8()
+	case typeInt8:
+		return int8(d.uint8())
+	case typeUint16:
+		return d.uint16()
+	case typeInt16:
+		return int16(d.uint16())
+	case typeUint32:
+		return d.uint32()
+	case typeInt32:
+		return int32(d.uint32())
+	case typeFloat32:
+		return math.Float32frombits(d.uint32())
+	case typeBool:
+		return d.uint8() != 0
+	case typeString:
+		return d.string()
+	case typeUint64:
+		return d.uint64()
+	case typeInt64:
+		return int64(d.uint64())
+	case typeFloat64:
+		return math.Float64frombits(d.uint64())
+	case typeArray:
+		if d.depth >= maxArrayDepth {
+			if d.err == nil {
+				d.err = fmt.Errorf("数组嵌套层数异常: 超过%d层", maxArrayDepth)
+			}
+			return nil
+		}
+		d.depth++
+		defer func() { d.depth-- }()
+		elementType, length := d.arrayHeader()
+		for i := uint64(0); i < length && d.err == nil; i++ {
+			d.skip(elementType)
+		}
+		return length
+	}
+	if d.err == nil {
+		d.err = fmt.Errorf("未知的元数据类型: %d", valueType)
+	}
+	return nil
+}
+
+// skip 跳过一个值
+func (d *decoder) skip(valueType uint32) {
+	size := 0
+	switch valueType {
+	case typeUint8, typeInt8, typeBool:
+		size = 1
+	case typeUint16, typeInt16:
+		size = 2
+	case typeUint32, typeInt32, typeFloat32:
+		size = 4
+	case typeUint64, typeInt64, typeFloat64:
+		size = 8
+	case typeString:
+		d.skipString()
+		return
+	default:
+		d.value(valueType)
+		return
+	}
+	if d.err == nil {
+		_, d.err = d.r.Discard(size)
+	}
+}
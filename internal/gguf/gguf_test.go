@@ -0,0 +1,348 @@
+package gguf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// builder 按指定版本拼出GGUF文件头，版本1的长度和数量为32位，之后为64位
+type builder struct {
+	version     uint32
+	kv          bytes.Buffer
+	kvCount     uint64
+	tensors     bytes.Buffer
+	tensorCount uint64
+}
+
+func (b *builder) put(w *bytes.Buffer, value any) {
+	binary.Write(w, binary.LittleEndian, value)
+}
+
+func (b *builder) count(w *bytes.Buffer, n uint64) {
+	if b.version == 1 {
+		b.put(w, uint32(n))
+	} else {
+		b.put(w, n)
+	}
+}
+
+func (b *builder) str(w *bytes.Buffer, s string) {
+	b.count(w, uint64(len(s)))
+	w.WriteString(s)
+}
+
+// add 添加一个元数据，write 写入值本身
+func (b *builder) add(key string, valueType uint32, write func(w *bytes.Buffer)) *builder {
+	b.str(&b.kv, key)
+	b.put(&b.kv, valueType)
+	write(&b.kv)
+	b.kvCount++
+	return b
+}
+
+func (b *builder) addString(key, value string) *builder {
+	return b.add(key, typeString, func(w *bytes.Buffer) { b.str(w, value) })
+}
+
+func (b *builder) addUint32(key string, value uint32) *builder {
+	return b.add(key, typeUint32, func(w *bytes.Buffer) { b.put(w, value) })
+}
+
+func (b *builder) addStrings(key string, values ...string) *builder {
+	return b.add(key, typeArray, func(w *bytes.Buffer) {
+		b.put(w, typeString)
+		b.count(w, uint64(len(values)))
+		for _, value := range values {
+			b.str(w, value)
+		}
+	})
+}
+
+func (b *builder) tensor(name string, tensorType uint32, dims ...uint64) *builder {
+	b.str(&b.tensors, name)
+	b.put(&b.tensors, uint32(len(dims)))
+	for _, dim := range dims {
+		b.count(&b.tensors, dim)
+	}
+	b.put(&b.tensors, tensorType)
+	b.put(&b.tensors, uint64(0))
+	b.tensorCount++
+	return b
+}
+
+func (b *builder) bytes() []byte {
+	var w bytes.Buffer
+	b.put(&w, uint32(magic))
+	b.put(&w, b.version)
+	b.count(&w, b.tensorCount)
+	b.count(&w, b.kvCount)
+	w.Write(b.kv.Bytes())
+	w.Write(b.tensors.Bytes())
+	return w.Bytes()
+}
+
+// llamaFile 一个完整的小模型文件头
+func llamaFile(version uint32) []byte {
+	b := &builder{version: version}
+	b.addString("general.architecture", "llama").
+		addString("general.name", "Tiny Llama").
+		addUint32("general.file_type", 15).
+		addUint32("llama.context_length", 4096).
+		add("llama.embedding_length", typeUint64, func(w *bytes.Buffer) { b.put(w, uint64(2048)) }).
+		add("llama.block_count", typeInt32, func(w *bytes.Buffer) { b.put(w, int32(22)) }).
+		addString("tokenizer.ggml.model", "llama").
+		addStrings("tokenizer.ggml.tokens", "<unk>", "<s>", "</s>", "hi").
+		addUint32("tokenizer.ggml.bos_token_id", 1).
+		addUint32("tokenizer.ggml.eos_token_id", 2).
+		addString("tokenizer.chat_template", "{{ messages }}").
+		tensor("token_embd.weight", 12, 2048, 4).
+		tensor("output_norm.weight", 0, 2048)
+	return b.bytes()
+}
+
+func TestRead(t *testing.T) {
+	want := Metadata{
+		Name:            "Tiny Llama",
+		Architecture:    "llama",
+		ParameterCount:  2048*4 + 2048,
+		Quantization:    "Q4_K_M",
+		ContextLength:   4096,
+		EmbeddingLength: 2048,
+		BlockCount:      22,
+		TensorCount:     2,
+		Tokenizer:       Tokenizer{Model: "llama", VocabSize: 4, BOSToken: "<s>", EOSToken: "</s>"},
+		ChatTemplate:    "{{ messages }}",
+	}
+	for _, version := range []uint32{1, 2, 3} {
+		meta, err := Read(bytes.NewReader(llamaFile(version)))
+		if err != nil {
+			t.Errorf("版本%d: 返回错误: %v", version, err)
+			continue
+		}
+		want.Version = version
+		if *meta != want {
+			t.Errorf("版本%d: Read = %+v，期望 %+v", version, *meta, want)
+		}
+	}
+}
+
+func TestReadHeaderErrors(t *testing.T) {
+	valid := llamaFile(3)
+	withVersion := func(version uint32) []byte {
+		data := bytes.Clone(valid)
+		binary.LittleEndian.PutUint32(data[4:], version)
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"空文件", nil, "不完整"},
+		{"不是GGUF文件", []byte("GGML\x03\x00\x00\x00"), "不是有效的GGUF文件"},
+		{"版本0", withVersion(0), "不支持的GGUF版本: 0"},
+		{"版本4", withVersion(4), "不支持的GGUF版本: 4"},
+		{"只有文件标识", valid[:4], "不完整"},
+	}
+	for _, tt := range tests {
+		_, err := Read(bytes.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: 错误 = %v，期望包含 %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestReadSkipsArrays(t *testing.T) {
+	tests := []struct {
+		name  string
+		value func(b *builder, w *bytes.Buffer)
+	}{
+		{"数值数组", func(b *builder, w *bytes.Buffer) {
+			b.put(w, typeFloat32)
+			b.count(w, 3)
+			b.put(w, []float32{1, 2, 3})
+		}},
+		{"嵌套数组", func(b *builder, w *bytes.Buffer) {
+			b.put(w, typeArray)
+			b.count(w, 2)
+			for _, items := range [][]string{{"a", "b"}, {"c"}} {
+				b.put(w, typeString)
+				b.count(w, uint64(len(items)))
+				for _, item := range items {
+					b.str(w, item)
+				}
+			}
+		}},
+		{"多层嵌套的空数组", func(b *builder, w *bytes.Buffer) {
+			for i := 0; i < maxArrayDepth-1; i++ {
+				b.put(w, typeArray)
+				b.count(w, 1)
+			}
+			b.put(w, typeUint64)
+			b.count(w, 0)
+		}},
+	}
+	for _, version := range []uint32{2, 3} {
+		for _, tt := range tests {
+			b := &builder{version: version}
+			b.add("tokenizer.ggml.merges", typeArray, func(w *bytes.Buffer) { tt.value(b, w) }).
+				addString("general.architecture", "qwen2").
+				addUint32("qwen2.context_length", 32768)
+			meta, err := Read(bytes.NewReader(b.bytes()))
+			if err != nil {
+				t.Errorf("版本%d %s: 返回错误: %v", version, tt.name, err)
+				continue
+			}
+			// 数组之后的元数据能正常读取，说明数组被完整跳过
+			if meta.Architecture != "qwen2" || meta.ContextLength != 32768 {
+				t.Errorf("版本%d %s: Read = %+v", version, tt.name, *meta)
+			}
+		}
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	for _, version := range []uint32{1, 2, 3} {
+		data := llamaFile(version)
+		// 在任意位置截断都应返回错误而不是panic
+		for n := 0; n < len(data); n++ {
+			if _, err := Read(bytes.NewReader(data[:n])); err == nil {
+				t.Errorf("版本%d: 截断到%d字节时没有返回错误", version, n)
+			}
+		}
+	}
+}
+
+func TestReadOversizedLengths(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *builder)
+		want  string
+	}{
+		{"字符串长度超过上限", func(b *builder) {
+			b.add("general.name", typeString, func(w *bytes.Buffer) { b.count(w, 1<<40) })
+		}, "字符串长度异常"},
+		{"键的长度超过上限", func(b *builder) {
+			b.count(&b.kv, maxStringLength+1)
+			b.kvCount++
+		}, "字符串长度异常"},
+		{"数组长度超过上限", func(b *builder) {
+			b.add("tokenizer.ggml.scores", typeArray, func(w *bytes.Buffer) {
+				b.put(w, typeFloat32)
+				b.count(w, 1<<40)
+			})
+		}, "数组长度异常"},
+		{"词表长度超过上限", func(b *builder) {
+			b.add("tokenizer.ggml.tokens", typeArray, func(w *bytes.Buffer) {
+				b.put(w, typeString)
+				b.count(w, maxArrayLength+1)
+			})
+		}, "数组长度异常"},
+		{"声明的字符串很长但文件不完整", func(b *builder) {
+			b.add("tokenizer.chat_template", typeString, func(w *bytes.Buffer) {
+				b.count(w, maxStringLength)
+				w.WriteString("{{")
+			})
+		}, "不完整"},
+		{"声明的数组很长但文件不完整", func(b *builder) {
+			b.add("tokenizer.ggml.tokens", typeArray, func(w *bytes.Buffer) {
+				b.put(w, typeString)
+				b.count(w, maxArrayLength)
+				b.str(w, "<unk>")
+			})
+		}, "不完整"},
+		{"数组嵌套过深", func(b *builder) {
+			b.add("general.tags", typeArray, func(w *bytes.Buffer) {
+				for i := 0; i < 100; i++ {
+					b.put(w, typeArray)
+					b.count(w, 1)
+				}
+			})
+		}, "数组嵌套层数异常"},
+		{"未知的元数据类型", func(b *builder) {
+			b.add("general.name", 99, func(w *bytes.Buffer) {})
+		}, "未知的元数据类型"},
+		{"张量维数异常", func(b *builder) {
+			b.tensor("blk.0.weight", 0, make([]uint64, maxDimensions+1)...)
+		}, "张量维数异常"},
+	}
+	for _, tt := range tests {
+		b := &builder{version: 3}
+		tt.build(b)
+		data := b.bytes()
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := Read(bytes.NewReader(data))
+		runtime.ReadMemStats(&after)
+
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: 错误 = %v，期望包含 %q", tt.name, err, tt.want)
+		}
+		// 分配的内存与文件实际大小相关，而不是声明的长度
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 8<<20 {
+			t.Errorf("%s: 分配了 %d 字节内存", tt.name, allocated)
+		}
+	}
+}
+
+func TestReadQuantization(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileType func(b *builder) // 为nil时不写入 general.file_type
+		tensors  []uint32
+		want     string
+	}{
+		{"Q4_0", func(b *builder) { b.addUint32("general.file_type", 2) }, nil, "Q4_0"},
+		{"Q8_0", func(b *builder) { b.addUint32("general.file_type", 7) }, nil, "Q8_0"},
+		{"Q4_K_M", func(b *builder) { b.addUint32("general.file_type", 15) }, nil, "Q4_K_M"},
+		{"IQ4_XS", func(b *builder) { b.addUint32("general.file_type", 30) }, nil, "IQ4_XS"},
+		{"BF16", func(b *builder) { b.addUint32("general.file_type", 32) }, nil, "BF16"},
+		{"以 int32 写入", func(b *builder) {
+			b.add("general.file_type", typeInt32, func(w *bytes.Buffer) { b.put(w, int32(17)) })
+		}, nil, "Q5_K_M"},
+		{"没有写入时按张量类型推断", nil, []uint32{0, 14, 14}, "Q6_K"},
+		{"未知的类型按张量类型推断", func(b *builder) { b.addUint32("general.file_type", 99) }, []uint32{0, 8}, "Q8_0"},
+		{"只有F32张量", nil, []uint32{0}, "F32"},
+		{"未知的张量类型", nil, []uint32{200}, "type 200"},
+	}
+	for _, tt := range tests {
+		b := &builder{version: 3}
+		b.addString("general.architecture", "llama")
+		if tt.fileType != nil {
+			tt.fileType(b)
+		}
+		for i, tensorType := range tt.tensors {
+			b.tensor("blk."+string(rune('a'+i)), tensorType, 16)
+		}
+		meta, err := Read(bytes.NewReader(b.bytes()))
+		if err != nil {
+			t.Errorf("%s: 返回错误: %v", tt.name, err)
+			continue
+		}
+		if meta.Quantization != tt.want {
+			t.Errorf("%s: 量化类型 = %q，期望 %q", tt.name, meta.Quantization, tt.want)
+		}
+	}
+}
+
+func TestParameterLabel(t *testing.T) {
+	tests := []struct {
+		count uint64
+		want  string
+	}{
+		{0, ""},
+		{135_000, "135K"},
+		{494_000_000, "494M"},
+		{7_000_000_000, "7B"},
+		{7_615_616_512, "7.6B"},
+	}
+	for _, tt := range tests {
+		if got := ParameterLabel(tt.count); got != tt.want {
+			t.Errorf("ParameterLabel(%d) = %q，期望 %q", tt.count, got, tt.want)
+		}
+	}
+}
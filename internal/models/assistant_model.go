@@ -0,0 +1,14 @@
+package models
+
+// Assistant 助手（角色），包含系统提示词和默认的模型参数
+type Assistant struct {
+	BaseModel
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	SystemPrompt    string   `json:"system_prompt"`
+	CloudLLMModelID uint     `json:"cloud_llm_model_id"`
+	ModelName       string   `json:"model_name"`
+	Temperature     *float64 `json:"temperature"` // 为空表示使用服务端默认值
+	MaxTokens       uint32   `json:"max_tokens"`
+	HistoryLength   uint32   `json:"history_length"` // 最多发送的历史对话轮数，0表示只受上下文窗口限制
+}
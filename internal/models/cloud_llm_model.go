@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // CloudLLMModel 云端模型设置
 type CloudLLMModel struct {
 	BaseModel
@@ -8,4 +10,24 @@ type CloudLLMModel struct {
 	EndPoint string `json:"endpoint"`
 	ApiKey   string `json:"api_key"`
 	Enabled  bool   `json:"enabled"`
+	// LocalModelID 由本地推理服务自动创建的配置对应的本地模型，用户添加的配置为0
+	LocalModelID uint `json:"local_model_id"`
+
+	// 网络设置，为空时使用系统默认
+	Proxy          string            `json:"proxy"`                          // 代理地址，支持 http://、https://、socks5://
+	Headers        map[string]string `gorm:"serializer:json" json:"headers"` // 每个请求附加的请求头
+	RequestTimeout int               `json:"request_timeout"`                // 等待响应头的超时时间（秒），0表示不限制
+	IdleTimeout    int               `json:"idle_timeout"`                   // 流式输出时两次收到数据的最长间隔（秒），0表示不限制
+	CACertPath     string            `json:"ca_cert_path"`                   // 自定义CA证书（PEM）路径，会追加到系统证书之后
+
+	// 客户端限流，0表示不限制
+	RequestsPerMinute int `json:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute"` // 按估算的输入加预留输出计算，请求结束后以实际用量修正
+	MaxConcurrency    int `json:"max_concurrency"`   // 同时进行的最大请求数
+
+	// 最近一次连接测试的结果
+	HealthStatus    string     `json:"health_status"` // ok 或失败原因分类，未测试时为空
+	HealthMessage   string     `json:"health_message"`
+	HealthLatencyMs int64      `json:"health_latency_ms"`
+	HealthCheckedAt *time.Time `json:"health_checked_at"`
 }
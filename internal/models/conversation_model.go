@@ -3,4 +3,10 @@ package models
 type Conversation struct {
     BaseModel
     Title string `json:"title"`
+    // 标题是否被用户手动修改过，修改后不再自动生成
+    TitleEdited bool `json:"title_edited"`
+    // 绑定的助手ID，0表示未绑定
+    AssistantID uint `json:"assistant_id"`
+    // 当前分支最末端的消息ID
+    ActiveMessageID uint `json:"active_message_id"`
 }
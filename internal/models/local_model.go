@@ -0,0 +1,33 @@
+package models
+
+// 本地模型状态
+const (
+	LocalModelStatusPending     = "pending"
+	LocalModelStatusDownloading = "downloading"
+	LocalModelStatusPaused      = "paused"
+	LocalModelStatusVerifying   = "verifying"
+	LocalModelStatusReady       = "ready"
+	LocalModelStatusFailed      = "failed"
+)
+
+// LocalModel 下载到本地的GGUF模型文件
+type LocalModel struct {
+	BaseModel
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	FileName   string `gorm:"uniqueIndex" json:"file_name"` // 模型目录下的文件名
+	SHA256     string `json:"sha256"`                       // 期望的文件校验值（小写十六进制），为空时不校验
+	Size       int64  `json:"size"`                         // 文件总大小，服务端未返回时为0
+	Downloaded int64  `json:"downloaded"`                   // 已下载的字节数
+	Status     string `json:"status"`
+	Error      string `json:"error"`
+
+	// 从GGUF文件头读取的信息，下载完成后填充
+	Architecture   string `json:"architecture"`
+	ParameterCount uint64 `json:"parameter_count"`
+	Quantization   string `json:"quantization"`
+	ContextLength  uint64 `json:"context_length"`
+	TokenizerModel string `json:"tokenizer_model"`
+	VocabSize      int    `json:"vocab_size"`
+	ChatTemplate   string `json:"chat_template"`
+}
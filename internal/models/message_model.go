@@ -1,8 +1,47 @@
 package models
 
+// 消息状态
+const (
+	MessageStatusPending     = "pending"
+	MessageStatusStreaming   = "streaming"
+	MessageStatusComplete    = "complete"
+	MessageStatusInterrupted = "interrupted"
+	MessageStatusError       = "error"
+)
+
+// MessageRoleSummary 会话摘要，覆盖其父消息及之前的对话，不属于消息树
+const MessageRoleSummary = "summary"
+
 type Message struct {
 	BaseModel
-	ConversationID uint   `json:"conversation_id"`
-	Role           string `json:"role"`
-	Content        string `json:"content"`
+	ConversationID uint `json:"conversation_id"`
+	// 父消息ID，0表示会话中的第一条消息
+	ParentID uint   `gorm:"index" json:"parent_id"`
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	// 推理模型的思考过程，不会作为历史上下文发送
+	ReasoningContent string `json:"reasoning_content"`
+	Status           string `gorm:"default:complete" json:"status"`
+	Error            string `json:"error"`
+
+	// 生成信息，仅AI消息有值，模型为实际生成回答的模型
+	CloudLLMModelID  uint     `json:"cloud_llm_model_id"`
+	ModelName        string   `json:"model_name"`
+	Temperature      *float64 `json:"temperature"` // 为空表示使用服务端默认值
+	MaxTokens        uint32   `json:"max_tokens"`
+	PromptTokens     int64    `json:"prompt_tokens"`
+	CompletionTokens int64    `json:"completion_tokens"`
+	FirstTokenMs     int64    `json:"first_token_ms"` // 首个token耗时（毫秒）
+	LatencyMs        int64    `json:"latency_ms"`     // 总耗时（毫秒）
+	FinishReason     string   `json:"finish_reason"`
+	Attempts         int      `json:"attempts"`      // 请求次数，包含重试和备用模型
+	FallbackFrom     string   `json:"fallback_from"` // 使用了备用模型时，原本请求的模型
+
+	// 同一父消息下的所有消息ID（含自身），用于分支切换，不入库
+	SiblingIDs []uint `gorm:"-" json:"sibling_ids"`
+}
+
+// Replayable 是否可以作为历史上下文发送给模型
+func (m Message) Replayable() bool {
+	return m.Status == MessageStatusComplete || (m.Status == MessageStatusInterrupted && m.Content != "")
 }
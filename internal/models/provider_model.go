@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ProviderModel 从提供商接口获取到的模型，每个云端模型配置一份
+type ProviderModel struct {
+	BaseModel
+	CloudLLMModelID uint   `gorm:"uniqueIndex:idx_provider_model" json:"cloud_llm_model_id"`
+	ModelID         string `gorm:"uniqueIndex:idx_provider_model" json:"model_id"`
+	OwnedBy         string `json:"owned_by"`
+
+	// 根据模型名推断的能力，仅作为提示
+	ContextWindow int  `json:"context_window"`
+	Vision        bool `json:"vision"`
+	Reasoning     bool `json:"reasoning"`
+	Tools         bool `json:"tools"`
+	JSONMode      bool `json:"json_mode"`
+	Embedding     bool `json:"embedding"`
+
+	ReleasedAt  int64     `json:"released_at"`  // 服务端返回的发布时间（Unix秒），没有时为0
+	RefreshedAt time.Time `json:"refreshed_at"` // 最近一次刷新时间
+}
@@ -0,0 +1,266 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"grove-studio/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Anthropic Anthropic Messages API
+	Anthropic = "anthropic"
+
+	anthropicEndpoint = "https://api.anthropic.com/v1"
+	anthropicVersion  = "2023-06-01"
+	// anthropicMaxTokens Messages API 要求必须指定 max_tokens
+	anthropicMaxTokens = 4096
+	// anthropicThinkingBudget 扩展思考的token预算，接口要求至少1024且小于 max_tokens
+	anthropicThinkingBudget = 2048
+)
+
+func init() {
+	Register(Anthropic, newAnthropicProvider)
+}
+
+// anthropicProvider Anthropic Messages API
+type anthropicProvider struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func newAnthropicProvider(cloudLLM models.CloudLLMModel) (ChatProvider, error) {
+	endpoint := strings.TrimRight(cloudLLM.EndPoint, "/")
+	if endpoint == "" {
+		endpoint = anthropicEndpoint
+	}
+	client, err := newHTTPClient(cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+	return &anthropicProvider{
+		client:   client,
+		endpoint: endpoint,
+		apiKey:   cloudLLM.ApiKey,
+	}, nil
+}
+
+func (p *anthropicProvider) header() http.Header {
+	header := http.Header{}
+	header.Set("x-api-key", p.apiKey)
+	header.Set("anthropic-version", anthropicVersion)
+	return header
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int64  `json:"budget_tokens"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int64              `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	Thinking    *anthropicThinking `json:"thinking,omitempty"`
+	Stream      bool               `json:"stream"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+// anthropicEvent 流式事件，不同类型只会填充其中部分字段
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		Thinking   string `json:"thinking"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicMessages 拆出系统提示词，并合并相邻的同角色消息，
+// Messages API 要求消息以用户开头且用户与助手交替出现
+func anthropicMessages(messages []ChatMessage) (string, []anthropicMessage) {
+	var system []string
+	var items []anthropicMessage
+	for _, msg := range messages {
+		if msg.Role == RoleSystem {
+			system = append(system, msg.Content)
+			continue
+		}
+		if len(items) == 0 && msg.Role != RoleUser {
+			continue
+		}
+		if len(items) > 0 && items[len(items)-1].Role == msg.Role {
+			items[len(items)-1].Content += "\n\n" + msg.Content
+			continue
+		}
+		items = append(items, anthropicMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return strings.Join(system, "\n\n"), items
+}
+
+// anthropicErrorStatus 流式事件中的错误类型对应的状态码，用于判断是否可以重试
+var anthropicErrorStatus = map[string]int{
+	"invalid_request_error": http.StatusBadRequest,
+	"authentication_error":  http.StatusUnauthorized,
+	"permission_error":      http.StatusForbidden,
+	"not_found_error":       http.StatusNotFound,
+	"request_too_large":     http.StatusRequestEntityTooLarge,
+	"rate_limit_error":      http.StatusTooManyRequests,
+	"api_error":             http.StatusInternalServerError,
+	"overloaded_error":      529,
+}
+
+// anthropicStopReason 转换为与OpenAI一致的结束原因
+func anthropicStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	}
+	return reason
+}
+
+// StreamChat 流式对话
+func (p *anthropicProvider) StreamChat(ctx context.Context, req ChatRequest, handler StreamHandler) (*ChatResult, error) {
+	system, messages := anthropicMessages(req.Messages)
+	body := anthropicRequest{
+		Model:       req.Model,
+		System:      system,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stream:      true,
+	}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = anthropicMaxTokens
+	}
+	if req.ThinkingBudget > 0 {
+		// 开启扩展思考时不能设置温度（ShapeRequest 已去掉并记录），且思考预算必须小于 max_tokens
+		body.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: req.ThinkingBudget}
+		body.Temperature = nil
+		if body.MaxTokens <= req.ThinkingBudget {
+			body.MaxTokens = req.ThinkingBudget + anthropicMaxTokens
+		}
+	}
+
+	acc := newAccumulator(handler)
+	resp, err := sendJSON(ctx, p.client, http.MethodPost, p.endpoint+"/messages", p.header(), body)
+	if err != nil {
+		return acc.Result(), err
+	}
+	defer resp.Body.Close()
+
+	var usage Usage
+	err = readSSE(resp.Body, func(event sseEvent) error {
+		var data anthropicEvent
+		if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+			return err
+		}
+
+		switch data.Type {
+		case "message_start":
+			input := data.Message.Usage
+			usage.PromptTokens = input.InputTokens + input.CacheCreationInputTokens + input.CacheReadInputTokens
+			usage.CompletionTokens = input.OutputTokens
+		case "content_block_delta":
+			switch data.Delta.Type {
+			case "text_delta":
+				acc.add(StreamDelta{Content: data.Delta.Text})
+			case "thinking_delta":
+				acc.add(StreamDelta{Reasoning: data.Delta.Thinking})
+			}
+		case "message_delta":
+			acc.finish(anthropicStopReason(data.Delta.StopReason))
+			usage.CompletionTokens = data.Usage.OutputTokens
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			current := usage
+			acc.add(StreamDelta{Usage: &current})
+		case "error":
+			status, ok := anthropicErrorStatus[data.Error.Type]
+			if !ok {
+				status = http.StatusInternalServerError
+			}
+			return &APIError{StatusCode: status, Message: data.Error.Message}
+		}
+		return nil
+	})
+	return acc.Result(), err
+}
+
+// ListModels 获取 /models 返回的模型列表
+func (p *anthropicProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var items []ModelInfo
+	afterID := ""
+	for {
+		query := url.Values{"limit": {"1000"}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+
+		var page struct {
+			Data []struct {
+				ID        string    `json:"id"`
+				CreatedAt time.Time `json:"created_at"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		if err := getJSON(ctx, p.client, p.endpoint+"/models?"+query.Encode(), p.header(), &page); err != nil {
+			return nil, err
+		}
+
+		for _, model := range page.Data {
+			items = append(items, ModelInfo{
+				ID:      model.ID,
+				OwnedBy: Anthropic,
+				Created: model.CreatedAt.Unix(),
+			})
+		}
+		if !page.HasMore || page.LastID == "" {
+			return items, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+// Embed Anthropic 没有提供向量接口
+func (p *anthropicProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	return nil, errors.New("Anthropic 不支持计算文本向量")
+}
+
+// TestConnection 通过获取模型列表检查密钥和地址是否可用
+func (p *anthropicProvider) TestConnection(ctx context.Context) error {
+	query := url.Values{"limit": {"1"}}
+	var page struct{}
+	return getJSON(ctx, p.client, p.endpoint+"/models?"+query.Encode(), p.header(), &page)
+}
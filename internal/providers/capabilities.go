@@ -0,0 +1,291 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// defaultContextWindow 无法识别模型时使用的上下文窗口
+	defaultContextWindow = 8192
+	// defaultMaxTemperature 大多数接口允许的最高温度
+	defaultMaxTemperature = 2
+)
+
+// Capabilities 模型能力和支持的请求参数，根据提供商和模型名推断
+type Capabilities struct {
+	ContextWindow   int     `json:"context_window"`
+	MaxOutputTokens int64   `json:"max_output_tokens"` // 单次回答的最大token，0表示未知
+	Vision          bool    `json:"vision"`
+	Tools           bool    `json:"tools"`
+	Reasoning       bool    `json:"reasoning"`
+	JSONMode        bool    `json:"json_mode"`
+	Temperature     bool    `json:"temperature"`     // 是否支持设置温度
+	MaxTemperature  float64 `json:"max_temperature"` // 温度上限
+	// LegacyMaxTokens 使用 max_tokens 而不是 max_completion_tokens 限制输出长度
+	LegacyMaxTokens bool `json:"legacy_max_tokens"`
+}
+
+// contextWindows 模型名称与上下文窗口的对应关系，按顺序匹配，越具体的越靠前
+var contextWindows = []struct {
+	match  func(name string) bool
+	tokens int
+}{
+	// 名称中带有窗口大小的模型，如 moonshot-v1-8k
+	{nameToken("1m"), 1000000},
+	{nameToken("256k"), 256000},
+	{nameToken("200k"), 200000},
+	{nameToken("128k"), 128000},
+	{nameToken("64k"), 65536},
+	{nameToken("32k"), 32768},
+	{nameToken("16k"), 16384},
+	{nameToken("8k"), 8192},
+
+	{nameContains("gpt-4.1"), 1047576},
+	{nameContains("gpt-4o"), 128000},
+	{nameContains("gpt-5"), 400000},
+	{nameToken("o1", "o3", "o4"), 200000},
+	{nameContains("gpt-3.5"), 16385},
+	{nameContains("claude"), 200000},
+	{nameContains("gemini-1.5-pro"), 2097152},
+	{nameContains("gemini"), 1048576},
+	{nameContains("deepseek"), 65536},
+	{nameContains("qwen-long"), 1000000},
+	{nameContains("qwen-turbo"), 1000000},
+	{nameContains("qwen-plus"), 131072},
+	{nameContains("qwen-max"), 32768},
+	{nameContains("qwq"), 32768},
+	{nameContains("qwen"), 32768},
+	{nameContains("glm-4"), 32768},
+	{nameContains("glm-z1"), 32768},
+	{nameContains("moonshot"), 8192},
+	{nameContains("kimi"), 131072},
+	{nameContains("hunyuan-lite"), 256000},
+	{nameContains("hunyuan"), 32768},
+	{nameContains("doubao"), 32768},
+	{nameContains("baichuan"), 32768},
+	{nameContains("command-r"), 128000},
+	{nameContains("command"), 4096},
+	{nameContains("llama"), 8192},
+}
+
+// ContextWindowFor 根据模型名称获取上下文窗口大小
+func ContextWindowFor(modelName string) int {
+	name := strings.ToLower(modelName)
+	for _, item := range contextWindows {
+		if item.match(name) {
+			return item.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+// capabilityRule 一条能力规则，模型名匹配时修改能力
+type capabilityRule struct {
+	match func(name string) bool
+	apply func(caps *Capabilities)
+}
+
+// nameContains 模型名包含任意一个片段
+func nameContains(patterns ...string) func(string) bool {
+	return func(name string) bool {
+		for _, pattern := range patterns {
+			if strings.Contains(name, pattern) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// nameTokens 按 - / : _ 拆分模型名，如 deepseek-r1:7b 拆为 deepseek、r1、7b
+func nameTokens(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '/' || r == ':' || r == '_'
+	})
+}
+
+// nameToken 模型名拆分后包含任意一个片段，用于 r1、1m 这类直接查找子串容易误匹配的短名称
+func nameToken(tokens ...string) func(string) bool {
+	return func(name string) bool {
+		for _, part := range nameTokens(name) {
+			for _, token := range tokens {
+				if part == token {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// nameFamily 去掉 "openai/" 之类的前缀后，模型名属于某个系列，如 o3 匹配 o3、o3-mini，但不匹配 o30
+func nameFamily(families ...string) func(string) bool {
+	return func(name string) bool {
+		name = name[strings.LastIndex(name, "/")+1:]
+		for _, family := range families {
+			if name == family || strings.HasPrefix(name, family+"-") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// 常用的能力组合
+func withChat(caps *Capabilities) {
+	caps.Tools = true
+	caps.JSONMode = true
+}
+
+func withVision(caps *Capabilities) {
+	caps.Vision = true
+}
+
+func withReasoning(caps *Capabilities) {
+	caps.Reasoning = true
+}
+
+// withFixedSampling 推理模型不接受温度参数，只能用 max_completion_tokens
+func withFixedSampling(caps *Capabilities) {
+	caps.Reasoning = true
+	caps.Temperature = false
+	caps.LegacyMaxTokens = false
+}
+
+// capabilityRules 按顺序应用所有匹配的规则，通用的在前、具体的在后
+var capabilityRules = []capabilityRule{
+	{nameContains("gpt-3.5", "gpt-4"), withChat},
+	{nameContains("gpt-3.5"), func(caps *Capabilities) { caps.MaxOutputTokens = 4096 }},
+	{nameContains("gpt-4o", "gpt-4.1", "gpt-5"), withVision},
+	{nameContains("gpt-4o"), func(caps *Capabilities) { caps.MaxOutputTokens = 16384 }},
+	{nameContains("gpt-4.1"), func(caps *Capabilities) { caps.MaxOutputTokens = 32768 }},
+	{nameContains("gpt-5"), withFixedSampling},
+	{nameContains("gpt-5-chat"), func(caps *Capabilities) {
+		caps.Temperature = true
+		caps.Reasoning = false
+	}},
+	{nameFamily("o1", "o3", "o4"), func(caps *Capabilities) {
+		withChat(caps)
+		withVision(caps)
+		withFixedSampling(caps)
+		caps.MaxOutputTokens = 100000
+	}},
+
+	{nameContains("claude"), func(caps *Capabilities) {
+		caps.Tools = true
+		caps.Vision = true
+		caps.MaxTemperature = 1
+		caps.MaxOutputTokens = 8192
+	}},
+	{nameContains("claude-3-opus", "claude-3-haiku", "claude-3-sonnet"), func(caps *Capabilities) { caps.MaxOutputTokens = 4096 }},
+	{nameContains("claude-3-7", "claude-sonnet-4", "claude-opus-4"), func(caps *Capabilities) {
+		withReasoning(caps)
+		caps.MaxOutputTokens = 64000
+	}},
+	{nameContains("claude-opus-4"), func(caps *Capabilities) { caps.MaxOutputTokens = 32000 }},
+
+	{nameContains("gemini"), func(caps *Capabilities) {
+		withChat(caps)
+		withVision(caps)
+		caps.MaxOutputTokens = 8192
+	}},
+	{nameContains("gemini-2.5"), func(caps *Capabilities) {
+		withReasoning(caps)
+		caps.MaxOutputTokens = 65536
+	}},
+
+	{nameContains("deepseek"), withChat},
+	{nameContains("deepseek-chat"), func(caps *Capabilities) { caps.MaxOutputTokens = 8192 }},
+	{nameContains("deepseek-reasoner", "deepseek-r1"), func(caps *Capabilities) {
+		// 思考模式会忽略温度，也不支持工具调用
+		caps.Reasoning = true
+		caps.Temperature = false
+		caps.Tools = false
+	}},
+
+	{nameContains("qwen", "qwq"), withChat},
+	{nameToken("vl", "qvq"), withVision},
+	{nameContains("qwq", "qvq", "qwen3"), withReasoning},
+
+	{nameContains("glm-4"), withChat},
+	{nameContains("glm-4v"), withVision},
+	{nameContains("glm-z1"), withReasoning},
+
+	{nameContains("moonshot", "kimi"), func(caps *Capabilities) {
+		withChat(caps)
+		caps.MaxTemperature = 1
+	}},
+
+	{nameContains("doubao", "hunyuan", "baichuan", "command-r"), withChat},
+	{nameContains("vision", "llava", "pixtral"), withVision},
+	{nameToken("thinking", "reasoner", "r1", "x1"), withReasoning},
+}
+
+// providerMaxTemperature 温度范围小于0~2的提供商
+var providerMaxTemperature = map[string]float64{
+	Anthropic: 1,
+	"kimi":    1,
+	"spark":   1,
+	"tongyi":  1.99, // 取值范围为 [0, 2)
+}
+
+// CapabilitiesFor 推断模型的能力，provider 对应 CloudLLMModel.Provider
+func CapabilitiesFor(provider, modelName string) Capabilities {
+	caps := Capabilities{
+		ContextWindow:  ContextWindowFor(modelName),
+		Temperature:    true,
+		MaxTemperature: defaultMaxTemperature,
+		// 几乎所有兼容接口都支持 max_tokens，OpenAI的推理模型由 withFixedSampling 改为 max_completion_tokens，
+		// 按模型判断而不是按提供商，通过网关调用这些模型时同样适用
+		LegacyMaxTokens: true,
+	}
+
+	name := strings.ToLower(modelName)
+	for _, rule := range capabilityRules {
+		if rule.match(name) {
+			rule.apply(&caps)
+		}
+	}
+	if value, ok := providerMaxTemperature[provider]; ok {
+		caps.MaxTemperature = min(caps.MaxTemperature, value)
+	}
+	return caps
+}
+
+// ShapeRequest 按模型能力调整请求参数：去掉不支持的温度、把温度限制在允许范围内、
+// 限制输出长度，选择限制输出长度所用的字段，并为支持的模型开启扩展思考（开启后不能设置温度）。
+// 负数的温度和输出长度无法调整，返回错误；其他调整通过 notes 返回，由调用方记录日志
+func ShapeRequest(provider string, req ChatRequest) (shaped ChatRequest, notes []string, err error) {
+	if req.Temperature != nil && *req.Temperature < 0 {
+		return req, nil, fmt.Errorf("温度不能小于0: %g", *req.Temperature)
+	}
+	if req.MaxTokens < 0 {
+		return req, nil, fmt.Errorf("最大输出token不能小于0: %d", req.MaxTokens)
+	}
+	caps := CapabilitiesFor(provider, req.Model)
+
+	if req.Temperature != nil {
+		if !caps.Temperature {
+			notes = append(notes, fmt.Sprintf("模型%s不支持设置温度，已忽略温度%g", req.Model, *req.Temperature))
+			req.Temperature = nil
+		} else if *req.Temperature > caps.MaxTemperature {
+			notes = append(notes, fmt.Sprintf("模型%s的温度上限为%g，温度%g已调整为上限", req.Model, caps.MaxTemperature, *req.Temperature))
+			req.Temperature = Float(caps.MaxTemperature)
+		}
+	}
+	if caps.MaxOutputTokens > 0 && req.MaxTokens > caps.MaxOutputTokens {
+		notes = append(notes, fmt.Sprintf("模型%s最多输出%d个token，最大输出token已从%d调整为%d", req.Model, caps.MaxOutputTokens, req.MaxTokens, caps.MaxOutputTokens))
+		req.MaxTokens = caps.MaxOutputTokens
+	}
+	req.LegacyMaxTokens = caps.LegacyMaxTokens
+	// Anthropic 的推理模型需要在请求中显式开启扩展思考
+	if provider == Anthropic && caps.Reasoning && !req.DisableThinking {
+		req.ThinkingBudget = anthropicThinkingBudget
+		if req.Temperature != nil {
+			notes = append(notes, fmt.Sprintf("模型%s开启扩展思考时不支持设置温度，已忽略温度%g", req.Model, *req.Temperature))
+			req.Temperature = nil
+		}
+	}
+	return req, notes, nil
+}
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShapeRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		req      ChatRequest
+		want     ChatRequest
+		notes    int
+		wantErr  bool
+	}{
+		{
+			name:     "温度为负数",
+			provider: OpenAICompatible,
+			req:      ChatRequest{Model: "gpt-4o", Temperature: Float(-1)},
+			wantErr:  true,
+		},
+		{
+			name:     "不调整",
+			provider: OpenAICompatible,
+			req:      ChatRequest{Model: "gpt-4o", Temperature: Float(0.7), MaxTokens: 1000},
+			want:     ChatRequest{Model: "gpt-4o", Temperature: Float(0.7), MaxTokens: 1000, LegacyMaxTokens: true},
+		},
+		{
+			name:     "推理模型忽略温度",
+			provider: OpenAICompatible,
+			req:      ChatRequest{Model: "o3-mini", Temperature: Float(0.7)},
+			want:     ChatRequest{Model: "o3-mini"},
+			notes:    1,
+		},
+		{
+			name:     "温度超过上限",
+			provider: Anthropic,
+			req:      ChatRequest{Model: "claude-3-5-sonnet-latest", Temperature: Float(1.5), MaxTokens: 100000},
+			want:     ChatRequest{Model: "claude-3-5-sonnet-latest", Temperature: Float(1), MaxTokens: 8192, LegacyMaxTokens: true},
+			notes:    2,
+		},
+		{
+			name:     "开启扩展思考时忽略温度",
+			provider: Anthropic,
+			req:      ChatRequest{Model: "claude-sonnet-4-20250514", Temperature: Float(0.7)},
+			want:     ChatRequest{Model: "claude-sonnet-4-20250514", LegacyMaxTokens: true, ThinkingBudget: anthropicThinkingBudget},
+			notes:    1,
+		},
+		{
+			name:     "没有设置温度时直接开启扩展思考",
+			provider: Anthropic,
+			req:      ChatRequest{Model: "claude-3-7-sonnet-latest"},
+			want:     ChatRequest{Model: "claude-3-7-sonnet-latest", LegacyMaxTokens: true, ThinkingBudget: anthropicThinkingBudget},
+		},
+		{
+			name:     "不开启扩展思考时保留温度",
+			provider: Anthropic,
+			req:      ChatRequest{Model: "claude-opus-4-1", Temperature: Float(0.2), DisableThinking: true},
+			want:     ChatRequest{Model: "claude-opus-4-1", Temperature: Float(0.2), LegacyMaxTokens: true, DisableThinking: true},
+		},
+		{
+			name:     "其他提供商不设置思考预算",
+			provider: OpenAICompatible,
+			req:      ChatRequest{Model: "claude-sonnet-4", Temperature: Float(0.5)},
+			want:     ChatRequest{Model: "claude-sonnet-4", Temperature: Float(0.5), LegacyMaxTokens: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notes, err := ShapeRequest(tt.provider, tt.req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("没有返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("返回错误: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ShapeRequest = %+v，期望 %+v", got, tt.want)
+			}
+			if len(notes) != tt.notes {
+				t.Errorf("notes = %q，期望 %d 条", notes, tt.notes)
+			}
+		})
+	}
+}
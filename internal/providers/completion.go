@@ -0,0 +1,239 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"grove-studio/internal/chattemplate"
+	"grove-studio/internal/models"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// LocalCompletion 只提供 /completion 接口的本地服务，如旧版 llama.cpp server，
+	// 对话由客户端按模型的对话模板渲染为提示词
+	LocalCompletion = "local-completion"
+
+	completionEndpoint = "http://localhost:8080"
+)
+
+func init() {
+	registerKeyless(LocalCompletion, newCompletionProvider)
+}
+
+// completionProvider 通过 /completion 接口对话
+type completionProvider struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func newCompletionProvider(cloudLLM models.CloudLLMModel) (ChatProvider, error) {
+	// 兼容填写了OpenAI兼容地址的情况
+	endpoint := strings.TrimSuffix(strings.TrimRight(cloudLLM.EndPoint, "/"), "/v1")
+	if endpoint == "" {
+		endpoint = completionEndpoint
+	}
+	client, err := newHTTPClient(cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+	return &completionProvider{
+		client:   client,
+		endpoint: endpoint,
+		apiKey:   cloudLLM.ApiKey,
+	}, nil
+}
+
+// header 通过反向代理访问时可能需要密钥
+func (p *completionProvider) header() http.Header {
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return header
+}
+
+// completionProps /props 接口返回的模型信息，旧版本服务端可能缺少部分字段
+type completionProps struct {
+	ChatTemplate string `json:"chat_template"`
+	BOSToken     string `json:"bos_token"`
+	EOSToken     string `json:"eos_token"`
+	ModelPath    string `json:"model_path"`
+}
+
+func (p *completionProvider) props(ctx context.Context) (*completionProps, error) {
+	var props completionProps
+	if err := getJSON(ctx, p.client, p.endpoint+"/props", p.header(), &props); err != nil {
+		return nil, err
+	}
+	return &props, nil
+}
+
+// prompt 渲染提示词，优先使用模型文件自带的对话模板，其次是服务端提供的模板，都没有时按模型名称选用内置模板
+func (p *completionProvider) prompt(ctx context.Context, req ChatRequest) (*chattemplate.Prompt, error) {
+	props, err := p.props(ctx)
+	if err != nil {
+		// 不支持 /props 的服务端只能按模型名称判断
+		props = &completionProps{}
+	}
+
+	family := chattemplate.FamilyFor(req.Model + " " + filepath.Base(props.ModelPath))
+	source := req.ChatTemplate
+	if source == "" {
+		source = props.ChatTemplate
+	}
+	if source == "" {
+		source = family.Template
+	}
+	opts := chattemplate.Options{
+		BOSToken:            props.BOSToken,
+		EOSToken:            props.EOSToken,
+		AddGenerationPrompt: true,
+	}
+	if opts.BOSToken == "" {
+		opts.BOSToken = family.BOSToken
+	}
+	if opts.EOSToken == "" {
+		opts.EOSToken = family.EOSToken
+	}
+
+	tmpl, err := chattemplate.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	messages := templateMessages(req.Messages)
+	prompt, err := tmpl.Render(messages, opts)
+	var templateErr *chattemplate.TemplateError
+	if errors.As(err, &templateErr) && len(messages) > 1 && messages[0].Role == RoleSystem {
+		// Gemma 等模板不支持系统消息，合并到第一条用户消息中重试
+		prompt, err = tmpl.Render(foldSystemMessage(messages), opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 服务端分词时会自动加上BOS，避免重复
+	prompt.Text = strings.TrimPrefix(prompt.Text, opts.BOSToken)
+	return prompt, nil
+}
+
+// templateMessages 转换为模板使用的消息，连续的系统消息（系统提示词和会话摘要）合并为一条，
+// 多数模板只识别第一条系统消息
+func templateMessages(messages []ChatMessage) []chattemplate.Message {
+	items := make([]chattemplate.Message, 0, len(messages))
+	for _, msg := range messages {
+		if n := len(items); n > 0 && msg.Role == RoleSystem && items[n-1].Role == RoleSystem {
+			items[n-1].Content += "\n\n" + msg.Content
+			continue
+		}
+		items = append(items, chattemplate.Message{Role: msg.Role, Content: msg.Content})
+	}
+	return items
+}
+
+// foldSystemMessage 把开头的系统消息放到第一条用户消息前面
+func foldSystemMessage(messages []chattemplate.Message) []chattemplate.Message {
+	if messages[1].Role != RoleUser {
+		return messages
+	}
+	items := append([]chattemplate.Message(nil), messages[1:]...)
+	items[0].Content = messages[0].Content + "\n\n" + items[0].Content
+	return items
+}
+
+type completionRequest struct {
+	Prompt      string   `json:"prompt"`
+	Stream      bool     `json:"stream"`
+	NPredict    int64    `json:"n_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	CachePrompt bool     `json:"cache_prompt"`
+}
+
+type completionChunk struct {
+	Content         string          `json:"content"`
+	Stop            bool            `json:"stop"`
+	StoppedLimit    bool            `json:"stopped_limit"`
+	TokensEvaluated int64           `json:"tokens_evaluated"`
+	TokensPredicted int64           `json:"tokens_predicted"`
+	Error           json.RawMessage `json:"error"`
+}
+
+// StreamChat 渲染提示词后流式补全
+func (p *completionProvider) StreamChat(ctx context.Context, req ChatRequest, handler StreamHandler) (*ChatResult, error) {
+	acc := newAccumulator(handler)
+	prompt, err := p.prompt(ctx, req)
+	if err != nil {
+		return acc.Result(), err
+	}
+
+	body := completionRequest{
+		Prompt:      prompt.Text,
+		Stream:      true,
+		NPredict:    req.MaxTokens,
+		Temperature: req.Temperature,
+		Stop:        prompt.Stop,
+		// 多轮对话的前缀相同，复用服务端的KV缓存
+		CachePrompt: true,
+	}
+	resp, err := sendJSON(ctx, p.client, http.MethodPost, p.endpoint+"/completion", p.header(), body)
+	if err != nil {
+		return acc.Result(), err
+	}
+	defer resp.Body.Close()
+
+	err = readSSE(resp.Body, func(event sseEvent) error {
+		var chunk completionChunk
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			return err
+		}
+		if len(chunk.Error) > 0 {
+			return errors.New(errorMessage([]byte(event.Data)))
+		}
+
+		acc.add(StreamDelta{Content: chunk.Content})
+		if chunk.Stop {
+			if chunk.StoppedLimit {
+				acc.finish("length")
+			} else {
+				acc.finish("stop")
+			}
+			acc.add(StreamDelta{Usage: &Usage{
+				PromptTokens:     chunk.TokensEvaluated,
+				CompletionTokens: chunk.TokensPredicted,
+				TotalTokens:      chunk.TokensEvaluated + chunk.TokensPredicted,
+			}})
+		}
+		return nil
+	})
+	return acc.Result(), err
+}
+
+// ListModels 服务端只加载了一个模型，以模型文件名作为模型名称
+func (p *completionProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	props, err := p.props(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := "default"
+	if props.ModelPath != "" {
+		id = strings.TrimSuffix(filepath.Base(props.ModelPath), filepath.Ext(props.ModelPath))
+	}
+	return []ModelInfo{{ID: id, OwnedBy: LocalCompletion}}, nil
+}
+
+// Embed /completion 接口不提供文本向量
+func (p *completionProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	return nil, errors.New("本地补全接口不支持计算文本向量")
+}
+
+// TestConnection 检查服务是否在运行，旧版本没有 /props 时改用 /health
+func (p *completionProvider) TestConnection(ctx context.Context) error {
+	if _, err := p.props(ctx); err == nil {
+		return nil
+	}
+	var result map[string]any
+	return getJSON(ctx, p.client, p.endpoint+"/health", p.header(), &result)
+}
@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"grove-studio/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompletionPromptTemplate(t *testing.T) {
+	const (
+		storedTemplate = `{% for message in messages %}{{ '### ' + message.role + ': ' + message.content + '\n' }}{% endfor %}`
+		serverTemplate = `{% for message in messages %}{{ '<|im_start|>' + message.role + '\n' + message.content + '<|im_end|>\n' }}{% endfor %}`
+	)
+	tests := []struct {
+		name         string
+		chatTemplate string
+		props        *completionProps // 为nil时服务端不支持 /props
+		want         string
+	}{
+		{
+			name:         "优先使用模型文件自带的模板",
+			chatTemplate: storedTemplate,
+			props:        &completionProps{ChatTemplate: serverTemplate},
+			want:         "### user: hi\n",
+		},
+		{
+			name:  "使用服务端提供的模板",
+			props: &completionProps{ChatTemplate: serverTemplate, ModelPath: "/models/Meta-Llama-3-8B.gguf"},
+			want:  "<|im_start|>user\nhi<|im_end|>\n",
+		},
+		{
+			name:  "按模型文件名选用内置模板",
+			props: &completionProps{ModelPath: "/models/Meta-Llama-3-8B.gguf"},
+			want:  "<|start_header_id|>user<|end_header_id|>\n\nhi<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n",
+		},
+		{
+			name: "不支持 /props 时按模型名称选用内置模板",
+			want: "<|user|>\nhi<|end|>\n<|assistant|>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body completionRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/props":
+					if tt.props == nil {
+						http.NotFound(w, r)
+						return
+					}
+					json.NewEncoder(w).Encode(tt.props)
+				case "/completion":
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Errorf("解析请求失败: %v", err)
+					}
+					w.Header().Set("Content-Type", "text/event-stream")
+					fmt.Fprint(w, "data: {\"content\":\"ok\",\"stop\":true,\"tokens_evaluated\":3,\"tokens_predicted\":1}\n\n")
+				}
+			}))
+			defer server.Close()
+
+			provider, err := New(models.CloudLLMModel{Provider: LocalCompletion, EndPoint: server.URL})
+			if err != nil {
+				t.Fatalf("创建提供商失败: %v", err)
+			}
+			model := "default"
+			if tt.props == nil {
+				model = "phi-3-mini"
+			}
+			result, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:        model,
+				Messages:     []ChatMessage{{Role: RoleUser, Content: "hi"}},
+				ChatTemplate: tt.chatTemplate,
+			}, nil)
+			if err != nil {
+				t.Fatalf("StreamChat 返回错误: %v", err)
+			}
+			if body.Prompt != tt.want {
+				t.Errorf("提示词 = %q，期望 %q", body.Prompt, tt.want)
+			}
+			if result.Content != "ok" || result.FinishReason != "stop" {
+				t.Errorf("结果 = %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// 请求失败的原因分类
+const (
+	ErrorKindAuth          = "auth"            // 密钥无效或没有权限
+	ErrorKindNetwork       = "network"         // 无法连接、域名解析失败或超时
+	ErrorKindTLS           = "tls"             // 证书或TLS握手失败
+	ErrorKindRateLimit     = "rate_limit"      // 请求过于频繁或额度不足
+	ErrorKindModelNotFound = "model_not_found" // 模型不存在或无权使用
+	ErrorKindBadEndpoint   = "bad_endpoint"    // 接口地址或路径错误
+	ErrorKindUnknown       = "unknown"
+)
+
+// errorKindHints 各类错误的提示
+var errorKindHints = map[string]string{
+	ErrorKindAuth:          "API密钥无效或没有权限",
+	ErrorKindNetwork:       "无法连接到服务，请检查网络和地址",
+	ErrorKindTLS:           "证书校验失败，请检查地址或代理设置",
+	ErrorKindRateLimit:     "请求过于频繁或账户额度不足",
+	ErrorKindModelNotFound: "模型不存在或没有使用权限",
+	ErrorKindBadEndpoint:   "接口地址错误，请检查是否填写了正确的路径",
+	ErrorKindUnknown:       "请求失败",
+}
+
+// ErrorHint 错误分类对应的提示
+func ErrorHint(kind string) string {
+	if hint, ok := errorKindHints[kind]; ok {
+		return hint
+	}
+	return errorKindHints[ErrorKindUnknown]
+}
+
+// ClassifyError 判断请求失败的原因
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return classifyStatus(apiErr.StatusCode, apiErr.Message)
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return classifyStatus(openaiErr.StatusCode, openaiErr.Message+" "+openaiErr.Code)
+	}
+
+	// 证书相关的错误需要在网络错误之前判断，它们同样会被包装为 net.OpError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCert) || errors.As(err, &recordErr) {
+		return ErrorKindTLS
+	}
+
+	// 地址缺少 http:// 或 https:// 前缀
+	if strings.Contains(err.Error(), "unsupported protocol scheme") {
+		return ErrorKindBadEndpoint
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	if errors.As(err, &dnsErr) || errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindNetwork
+	}
+
+	// 返回的不是JSON，通常是地址指向了网页而不是接口
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return ErrorKindBadEndpoint
+	}
+	return ErrorKindUnknown
+}
+
+// classifyStatus 根据状态码和错误信息判断原因
+func classifyStatus(status int, message string) string {
+	message = strings.ToLower(message)
+	mentionsModel := strings.Contains(message, "model")
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		if mentionsModel && status == http.StatusForbidden {
+			return ErrorKindModelNotFound
+		}
+		return ErrorKindAuth
+	case status == http.StatusTooManyRequests || status == http.StatusPaymentRequired:
+		return ErrorKindRateLimit
+	case status == http.StatusNotFound:
+		if mentionsModel {
+			return ErrorKindModelNotFound
+		}
+		return ErrorKindBadEndpoint
+	case status == http.StatusMethodNotAllowed:
+		return ErrorKindBadEndpoint
+	case status == http.StatusBadRequest && mentionsModel &&
+		(strings.Contains(message, "not exist") || strings.Contains(message, "not found") ||
+			strings.Contains(message, "invalid") || strings.Contains(message, "not support")):
+		return ErrorKindModelNotFound
+	case strings.Contains(message, "api key") || strings.Contains(message, "api_key"):
+		return ErrorKindAuth
+	case strings.Contains(message, "quota") || strings.Contains(message, "insufficient") || strings.Contains(message, "rate limit"):
+		return ErrorKindRateLimit
+	}
+	return ErrorKindUnknown
+}
+
+// IsRetryable 是否为可以重试的临时错误：限流、服务端过载或网关错误、连接中断和超时
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	status := 0
+	var apiErr *APIError
+	var openaiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		status = apiErr.StatusCode
+	} else if errors.As(err, &openaiErr) {
+		status = openaiErr.StatusCode
+	}
+	if status != 0 {
+		// 409 通常是请求与服务端状态冲突，重试也不会成功
+		switch status {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests,
+			http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout, 529: // 529 为 Anthropic 的服务过载
+			return true
+		}
+		return false
+	}
+
+	// 域名解析失败和证书错误重试也不会成功
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && !dnsErr.IsTemporary {
+		return false
+	}
+	kind := ClassifyError(err)
+	return kind == ErrorKindNetwork || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// RetryAfter 服务端通过 Retry-After 要求的等待时间，没有时返回0
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) && openaiErr.Response != nil {
+		return parseRetryAfter(openaiErr.Response.Header)
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"无错误", nil, false},
+		{"已取消", context.Canceled, false},
+		{"限流", &APIError{StatusCode: 429}, true},
+		{"请求超时", &APIError{StatusCode: 408}, true},
+		{"服务端错误", &APIError{StatusCode: 500}, true},
+		{"网关错误", &APIError{StatusCode: 502}, true},
+		{"Anthropic 过载", &APIError{StatusCode: 529}, true},
+		{"状态冲突", &APIError{StatusCode: 409}, false},
+		{"参数错误", &APIError{StatusCode: 400}, false},
+		{"密钥无效", &APIError{StatusCode: 401}, false},
+		{"模型不存在", fmt.Errorf("请求失败: %w", &APIError{StatusCode: 404}), false},
+		{"连接中断", io.ErrUnexpectedEOF, true},
+		{"域名不存在", &net.DNSError{Err: "no such host", Name: "api.example.invalid", IsNotFound: true}, false},
+		{"其他错误", errors.New("模型返回了空的内容"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable(%v) = %v，期望 %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"grove-studio/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Gemini Google Gemini generateContent API
+	Gemini = "gemini"
+
+	geminiEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+)
+
+func init() {
+	Register(Gemini, newGeminiProvider)
+}
+
+// geminiProvider Google Gemini 原生接口
+type geminiProvider struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func newGeminiProvider(cloudLLM models.CloudLLMModel) (ChatProvider, error) {
+	endpoint := strings.TrimRight(cloudLLM.EndPoint, "/")
+	if endpoint == "" {
+		endpoint = geminiEndpoint
+	}
+	// 以前配置的是Gemini的OpenAI兼容地址，继续按兼容协议处理
+	if strings.HasSuffix(endpoint, "/openai") {
+		return newOpenAIProvider(cloudLLM)
+	}
+
+	client, err := newHTTPClient(cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+	return &geminiProvider{
+		client:   client,
+		endpoint: endpoint,
+		apiKey:   cloudLLM.ApiKey,
+	}, nil
+}
+
+func (p *geminiProvider) header() http.Header {
+	header := http.Header{}
+	header.Set("x-goog-api-key", p.apiKey)
+	return header
+}
+
+// modelURL 模型方法的地址，模型名可以带或不带 models/ 前缀
+func (p *geminiProvider) modelURL(model, method string) string {
+	return p.endpoint + "/models/" + strings.TrimPrefix(model, "models/") + ":" + method
+}
+
+type geminiPart struct {
+	Text    string `json:"text"`
+	Thought bool   `json:"thought,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int64    `json:"maxOutputTokens,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int64 `json:"thoughtsTokenCount"`
+		TotalTokenCount      int64 `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiContents 拆出系统提示词，助手角色在Gemini中叫 model
+func geminiContents(messages []ChatMessage) (*geminiContent, []geminiContent) {
+	var system *geminiContent
+	var contents []geminiContent
+	for _, msg := range messages {
+		part := geminiPart{Text: msg.Content}
+		switch msg.Role {
+		case RoleSystem:
+			if system == nil {
+				system = &geminiContent{}
+			}
+			system.Parts = append(system.Parts, part)
+		case RoleAssistant:
+			contents = append(contents, geminiContent{Role: "model", Parts: []geminiPart{part}})
+		default:
+			contents = append(contents, geminiContent{Role: "user", Parts: []geminiPart{part}})
+		}
+	}
+	return system, contents
+}
+
+// geminiFinishReason 转换为与OpenAI一致的结束原因，被安全策略拦截时返回错误
+func geminiFinishReason(reason string) (string, error) {
+	switch reason {
+	case "", "FINISH_REASON_UNSPECIFIED":
+		return "", nil
+	case "STOP":
+		return "stop", nil
+	case "MAX_TOKENS":
+		return "length", nil
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter", fmt.Errorf("回答被安全策略拦截: %s", reason)
+	}
+	return strings.ToLower(reason), nil
+}
+
+// StreamChat 流式对话
+func (p *geminiProvider) StreamChat(ctx context.Context, req ChatRequest, handler StreamHandler) (*ChatResult, error) {
+	system, contents := geminiContents(req.Messages)
+	body := geminiRequest{
+		Contents:          contents,
+		SystemInstruction: system,
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+		},
+	}
+
+	acc := newAccumulator(handler)
+	resp, err := sendJSON(ctx, p.client, http.MethodPost, p.modelURL(req.Model, "streamGenerateContent?alt=sse"), p.header(), body)
+	if err != nil {
+		return acc.Result(), err
+	}
+	defer resp.Body.Close()
+
+	err = readSSE(resp.Body, func(event sseEvent) error {
+		var data geminiResponse
+		if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+			return err
+		}
+		if reason := data.PromptFeedback.BlockReason; reason != "" {
+			acc.finish("content_filter")
+			return fmt.Errorf("问题被安全策略拦截: %s", reason)
+		}
+
+		if len(data.Candidates) > 0 {
+			candidate := data.Candidates[0]
+			for _, part := range candidate.Content.Parts {
+				if part.Thought {
+					acc.add(StreamDelta{Reasoning: part.Text})
+				} else {
+					acc.add(StreamDelta{Content: part.Text})
+				}
+			}
+
+			reason, err := geminiFinishReason(candidate.FinishReason)
+			acc.finish(reason)
+			if err != nil {
+				return err
+			}
+		}
+
+		if meta := data.UsageMetadata; meta != nil && meta.TotalTokenCount > 0 {
+			acc.add(StreamDelta{Usage: &Usage{
+				PromptTokens:     meta.PromptTokenCount,
+				CompletionTokens: meta.CandidatesTokenCount + meta.ThoughtsTokenCount,
+				TotalTokens:      meta.TotalTokenCount,
+			}})
+		}
+		return nil
+	})
+	return acc.Result(), err
+}
+
+// ListModels 获取 /models 返回的模型列表
+func (p *geminiProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var items []ModelInfo
+	pageToken := ""
+	for {
+		query := url.Values{"pageSize": {"1000"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			Models []struct {
+				Name            string `json:"name"`
+				InputTokenLimit int    `json:"inputTokenLimit"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := getJSON(ctx, p.client, p.endpoint+"/models?"+query.Encode(), p.header(), &page); err != nil {
+			return nil, err
+		}
+
+		for _, model := range page.Models {
+			items = append(items, ModelInfo{
+				ID:            strings.TrimPrefix(model.Name, "models/"),
+				OwnedBy:       "google",
+				ContextWindow: model.InputTokenLimit,
+			})
+		}
+		if page.NextPageToken == "" {
+			return items, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// Embed 计算文本向量
+func (p *geminiProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	type embedRequest struct {
+		Model   string        `json:"model"`
+		Content geminiContent `json:"content"`
+	}
+	body := struct {
+		Requests []embedRequest `json:"requests"`
+	}{}
+	for _, input := range inputs {
+		body.Requests = append(body.Requests, embedRequest{
+			Model:   "models/" + strings.TrimPrefix(model, "models/"),
+			Content: geminiContent{Parts: []geminiPart{{Text: input}}},
+		})
+	}
+
+	resp, err := sendJSON(ctx, p.client, http.MethodPost, p.modelURL(model, "batchEmbedContents"), p.header(), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Embeddings []struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(inputs) {
+		return nil, errors.New("返回的向量数量与输入不一致")
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for i, item := range result.Embeddings {
+		vectors[i] = item.Values
+	}
+	return vectors, nil
+}
+
+// TestConnection 通过获取模型列表检查密钥和地址是否可用
+func (p *geminiProvider) TestConnection(ctx context.Context) error {
+	var page struct{}
+	return getJSON(ctx, p.client, p.endpoint+"/models?pageSize=1", p.header(), &page)
+}
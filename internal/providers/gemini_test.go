@@ -0,0 +1,165 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"grove-studio/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// geminiStub 模拟 streamGenerateContent 接口，记录收到的请求并按顺序返回SSE事件
+type geminiStub struct {
+	path   string
+	query  string
+	apiKey string
+	body   geminiRequest
+}
+
+func newGeminiStub(t *testing.T, events ...string) (*geminiStub, ChatProvider) {
+	t.Helper()
+	stub := &geminiStub{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.path = r.URL.Path
+		stub.query = r.URL.RawQuery
+		stub.apiKey = r.Header.Get("x-goog-api-key")
+		if err := json.NewDecoder(r.Body).Decode(&stub.body); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(models.CloudLLMModel{Provider: Gemini, EndPoint: server.URL, ApiKey: "test-key"})
+	if err != nil {
+		t.Fatalf("创建提供商失败: %v", err)
+	}
+	return stub, provider
+}
+
+func TestGeminiStreamChat(t *testing.T) {
+	stub, provider := newGeminiStub(t,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"先想一想","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"你好"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"！"}]},"finishReason":"STOP"}],`+
+			`"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":3,"thoughtsTokenCount":5,"totalTokenCount":20}}`,
+	)
+
+	var deltas []StreamDelta
+	result, err := provider.StreamChat(context.Background(), ChatRequest{
+		Model: "models/gemini-2.5-flash",
+		Messages: []ChatMessage{
+			{Role: RoleSystem, Content: "你是助手"},
+			{Role: RoleSystem, Content: "会话摘要"},
+			{Role: RoleUser, Content: "在吗"},
+			{Role: RoleAssistant, Content: "在"},
+			{Role: RoleUser, Content: "打个招呼"},
+		},
+		Temperature: Float(0.5),
+		MaxTokens:   256,
+	}, func(delta StreamDelta) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("StreamChat 返回错误: %v", err)
+	}
+
+	if stub.path != "/models/gemini-2.5-flash:streamGenerateContent" || stub.query != "alt=sse" {
+		t.Errorf("请求地址 = %s?%s", stub.path, stub.query)
+	}
+	if stub.apiKey != "test-key" {
+		t.Errorf("x-goog-api-key = %q", stub.apiKey)
+	}
+
+	system := stub.body.SystemInstruction
+	if system == nil || len(system.Parts) != 2 || system.Parts[0].Text != "你是助手" || system.Parts[1].Text != "会话摘要" {
+		t.Errorf("systemInstruction = %+v", system)
+	}
+	var roles []string
+	for _, content := range stub.body.Contents {
+		roles = append(roles, content.Role)
+	}
+	if got := strings.Join(roles, ","); got != "user,model,user" {
+		t.Errorf("contents 角色 = %s，期望 user,model,user", got)
+	}
+	config := stub.body.GenerationConfig
+	if config.Temperature == nil || *config.Temperature != 0.5 || config.MaxOutputTokens != 256 {
+		t.Errorf("generationConfig = %+v", config)
+	}
+
+	if result.Reasoning != "先想一想" {
+		t.Errorf("Reasoning = %q", result.Reasoning)
+	}
+	if result.Content != "你好！" {
+		t.Errorf("Content = %q", result.Content)
+	}
+	if result.FinishReason != "stop" {
+		t.Errorf("FinishReason = %q", result.FinishReason)
+	}
+	want := Usage{PromptTokens: 12, CompletionTokens: 8, TotalTokens: 20}
+	if result.Usage != want {
+		t.Errorf("Usage = %+v，期望 %+v", result.Usage, want)
+	}
+	if len(deltas) != 4 || deltas[3].Usage == nil {
+		t.Errorf("增量 = %+v，期望3个文本增量和1个用量增量", deltas)
+	}
+}
+
+func TestGeminiStreamChatBlocked(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []string
+		wantContent string
+		wantError   string
+	}{
+		{
+			name: "回答触发安全策略",
+			events: []string{
+				`{"candidates":[{"content":{"parts":[{"text":"部分"}]}}]}`,
+				`{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY"}]}`,
+			},
+			wantContent: "部分",
+			wantError:   "SAFETY",
+		},
+		{
+			name: "回答与受版权保护的内容重复",
+			events: []string{
+				`{"candidates":[{"content":{"parts":[{"text":"歌词"}]},"finishReason":"RECITATION"}]}`,
+			},
+			wantContent: "歌词",
+			wantError:   "RECITATION",
+		},
+		{
+			name: "问题被拦截",
+			events: []string{
+				`{"promptFeedback":{"blockReason":"PROHIBITED_CONTENT"}}`,
+			},
+			wantError: "PROHIBITED_CONTENT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, provider := newGeminiStub(t, tt.events...)
+			result, err := provider.StreamChat(context.Background(), ChatRequest{
+				Model:    "gemini-2.0-flash",
+				Messages: []ChatMessage{{Role: RoleUser, Content: "hi"}},
+			}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Fatalf("错误 = %v，期望包含 %s", err, tt.wantError)
+			}
+			if result.FinishReason != "content_filter" {
+				t.Errorf("FinishReason = %q，期望 content_filter", result.FinishReason)
+			}
+			if result.Content != tt.wantContent {
+				t.Errorf("Content = %q，期望保留已生成的 %q", result.Content, tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError 服务端返回的错误
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // 服务端要求的重试等待时间，没有时为0
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("请求失败，状态码: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("请求失败，状态码: %d，%s", e.StatusCode, e.Message)
+}
+
+// sendJSON 发送JSON请求，状态码异常时读取错误信息并返回 APIError
+func sendJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    errorMessage(data),
+			RetryAfter: parseRetryAfter(resp.Header),
+		}
+	}
+	return resp, nil
+}
+
+// getJSON 发送GET请求并解析返回的JSON
+func getJSON(ctx context.Context, client *http.Client, url string, header http.Header, out any) error {
+	resp, err := sendJSON(ctx, client, http.MethodGet, url, header, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和HTTP日期两种格式
+func parseRetryAfter(header http.Header) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// errorMessage 从常见的几种错误格式中取出错误描述
+func errorMessage(data []byte) string {
+	var body struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return strings.TrimSpace(string(data))
+	}
+
+	var detail struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body.Error, &detail) == nil && detail.Message != "" {
+		return detail.Message
+	}
+	var text string
+	if json.Unmarshal(body.Error, &text) == nil && text != "" {
+		return text
+	}
+	if body.Message != "" {
+		return body.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// sseEvent 一条 Server-Sent Events 事件
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// readSSE 逐条读取SSE事件，handle返回错误时停止读取
+func readSSE(r io.Reader, handle func(event sseEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var event sseEvent
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = sseEvent{}
+			return nil
+		}
+		event.Data = strings.Join(data, "\n")
+		err := handle(event)
+		event, data = sseEvent{}, nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// 注释行，用于保活
+		case strings.HasPrefix(line, "event:"):
+			event.Event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
+
+// readJSONLines 逐行读取以换行分隔的JSON，handle返回错误时停止读取
+func readJSONLines(r io.Reader, handle func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := handle(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,211 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"grove-studio/internal/models"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Ollama 本地 Ollama 服务，使用其原生接口
+	Ollama = "ollama"
+	// LocalOpenAI 本地的OpenAI兼容服务，如 LM Studio、llama.cpp server、vLLM
+	LocalOpenAI = "local"
+
+	ollamaEndpoint = "http://localhost:11434"
+	localEndpoint  = "http://localhost:8080/v1"
+)
+
+func init() {
+	registerKeyless(Ollama, newOllamaProvider)
+	registerKeyless(LocalOpenAI, newLocalProvider)
+}
+
+// newLocalProvider 本地OpenAI兼容服务，未填写地址时使用默认端口
+func newLocalProvider(cloudLLM models.CloudLLMModel) (ChatProvider, error) {
+	if cloudLLM.EndPoint == "" {
+		cloudLLM.EndPoint = localEndpoint
+	}
+	return newOpenAIProvider(cloudLLM)
+}
+
+// ollamaProvider Ollama 原生接口
+type ollamaProvider struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func newOllamaProvider(cloudLLM models.CloudLLMModel) (ChatProvider, error) {
+	// 兼容填写了OpenAI兼容地址的情况
+	endpoint := strings.TrimSuffix(strings.TrimRight(cloudLLM.EndPoint, "/"), "/v1")
+	if endpoint == "" {
+		endpoint = ollamaEndpoint
+	}
+	client, err := newHTTPClient(cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+	return &ollamaProvider{
+		client:   client,
+		endpoint: endpoint,
+		apiKey:   cloudLLM.ApiKey,
+	}, nil
+}
+
+// header 通过反向代理访问时可能需要密钥
+func (p *ollamaProvider) header() http.Header {
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return header
+}
+
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  struct {
+		Temperature *float64 `json:"temperature,omitempty"`
+		NumPredict  int64    `json:"num_predict,omitempty"`
+	} `json:"options"`
+}
+
+type ollamaChatChunk struct {
+	Message struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int64  `json:"prompt_eval_count"`
+	EvalCount       int64  `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// StreamChat 流式对话
+func (p *ollamaProvider) StreamChat(ctx context.Context, req ChatRequest, handler StreamHandler) (*ChatResult, error) {
+	body := ollamaChatRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Stream:   true,
+	}
+	body.Options.Temperature = req.Temperature
+	body.Options.NumPredict = req.MaxTokens
+
+	acc := newAccumulator(handler)
+	resp, err := sendJSON(ctx, p.client, http.MethodPost, p.endpoint+"/api/chat", p.header(), body)
+	if err != nil {
+		return acc.Result(), err
+	}
+	defer resp.Body.Close()
+
+	err = readJSONLines(resp.Body, func(line []byte) error {
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return err
+		}
+		if chunk.Error != "" {
+			return errors.New(chunk.Error)
+		}
+
+		acc.add(StreamDelta{
+			Content:   chunk.Message.Content,
+			Reasoning: chunk.Message.Thinking,
+		})
+		if chunk.Done {
+			acc.finish(chunk.DoneReason)
+			acc.add(StreamDelta{Usage: &Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}})
+		}
+		return nil
+	})
+	return acc.Result(), err
+}
+
+// ListModels 获取本地已安装的模型
+func (p *ollamaProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := getJSON(ctx, p.client, p.endpoint+"/api/tags", p.header(), &result); err != nil {
+		return nil, err
+	}
+
+	items := make([]ModelInfo, 0, len(result.Models))
+	for _, model := range result.Models {
+		items = append(items, ModelInfo{ID: model.Name, OwnedBy: Ollama})
+	}
+	return items, nil
+}
+
+// Embed 计算文本向量
+func (p *ollamaProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	body := map[string]any{"model": model, "input": inputs}
+	resp, err := sendJSON(ctx, p.client, http.MethodPost, p.endpoint+"/api/embed", p.header(), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Embeddings, nil
+}
+
+// TestConnection 检查服务是否在运行
+func (p *ollamaProvider) TestConnection(ctx context.Context) error {
+	_, err := p.Version(ctx)
+	return err
+}
+
+// Version 获取 Ollama 的版本
+func (p *ollamaProvider) Version(ctx context.Context) (string, error) {
+	var result struct {
+		Version string `json:"version"`
+	}
+	if err := getJSON(ctx, p.client, p.endpoint+"/api/version", p.header(), &result); err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// PullModel 从 Ollama 模型库下载模型，progress 会收到每一条进度
+func (p *ollamaProvider) PullModel(ctx context.Context, model string, progress func(PullProgress)) error {
+	body := map[string]any{"model": model, "stream": true}
+	resp, err := sendJSON(ctx, p.client, http.MethodPost, p.endpoint+"/api/pull", p.header(), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readJSONLines(resp.Body, func(line []byte) error {
+		var item struct {
+			PullProgress
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return err
+		}
+		if item.Error != "" {
+			return errors.New(item.Error)
+		}
+		if progress != nil {
+			progress(item.PullProgress)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,153 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"grove-studio/internal/models"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/packages/param"
+)
+
+func init() {
+	Register(OpenAICompatible, newOpenAIProvider)
+}
+
+// openAIProvider OpenAI兼容接口，deepseek、硅基流动、通义千问等都使用这一协议
+type openAIProvider struct {
+	client openai.Client
+}
+
+func newOpenAIProvider(cloudLLM models.CloudLLMModel) (ChatProvider, error) {
+	httpClient, err := newHTTPClient(cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+	return &openAIProvider{
+		// 重试由调用方统一控制
+		client: openai.NewClient(
+			option.WithAPIKey(cloudLLM.ApiKey),
+			option.WithBaseURL(cloudLLM.EndPoint),
+			option.WithHTTPClient(httpClient),
+			option.WithMaxRetries(0),
+		),
+	}, nil
+}
+
+// StreamChat 流式对话
+func (p *openAIProvider) StreamChat(ctx context.Context, req ChatRequest, handler StreamHandler) (*ChatResult, error) {
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case RoleSystem:
+			messages = append(messages, openai.SystemMessage(msg.Content))
+		case RoleUser:
+			messages = append(messages, openai.UserMessage(msg.Content))
+		case RoleAssistant:
+			messages = append(messages, openai.AssistantMessage(msg.Content))
+		}
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Messages: messages,
+		Model:    req.Model,
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: param.NewOpt(true),
+		},
+	}
+	if req.Temperature != nil {
+		params.Temperature = param.NewOpt(*req.Temperature)
+	}
+	if req.MaxTokens > 0 {
+		if req.LegacyMaxTokens {
+			params.MaxTokens = param.NewOpt(req.MaxTokens)
+		} else {
+			params.MaxCompletionTokens = param.NewOpt(req.MaxTokens)
+		}
+	}
+
+	acc := newAccumulator(handler)
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	for stream.Next() {
+		chunk := stream.Current()
+
+		if len(chunk.Choices) > 0 {
+			choice := chunk.Choices[0]
+			acc.add(StreamDelta{
+				Content:   choice.Delta.Content,
+				Reasoning: reasoningDelta(choice.Delta),
+			})
+			acc.finish(choice.FinishReason)
+		}
+
+		if chunk.JSON.Usage.IsPresent() && chunk.Usage.TotalTokens > 0 {
+			acc.add(StreamDelta{Usage: &Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}})
+		}
+	}
+
+	return acc.Result(), stream.Err()
+}
+
+// reasoningDelta 读取推理模型额外返回的思考内容，
+// deepseek/QwQ/GLM-Z1 使用 reasoning_content，部分聚合平台使用 reasoning
+func reasoningDelta(delta openai.ChatCompletionChunkChoiceDelta) string {
+	for _, key := range []string{"reasoning_content", "reasoning"} {
+		field, ok := delta.JSON.ExtraFields[key]
+		if !ok || !field.IsPresent() || field.IsExplicitNull() {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal([]byte(field.Raw()), &text); err == nil && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// ListModels 获取 /models 返回的模型列表
+func (p *openAIProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var items []ModelInfo
+	pager := p.client.Models.ListAutoPaging(ctx)
+	for pager.Next() {
+		model := pager.Current()
+		items = append(items, ModelInfo{
+			ID:      model.ID,
+			OwnedBy: model.OwnedBy,
+			Created: model.Created,
+		})
+	}
+	if err := pager.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Embed 计算文本向量
+func (p *openAIProvider) Embed(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: model,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: inputs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for _, item := range resp.Data {
+		if int(item.Index) < len(vectors) {
+			vectors[item.Index] = item.Embedding
+		}
+	}
+	return vectors, nil
+}
+
+// TestConnection 通过获取模型列表检查密钥和地址是否可用
+func (p *openAIProvider) TestConnection(ctx context.Context) error {
+	_, err := p.client.Models.List(ctx)
+	return err
+}
@@ -0,0 +1,231 @@
+package providers
+
+// Preset 内置的提供商预设，前端据此展示可选的提供商和默认模型
+type Preset struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Icon     string   `json:"icon"` // 图标文件名，位于前端 assets/images/providers 目录
+	Endpoint string   `json:"endpoint"`
+	Models   []string `json:"models"` // 默认模型，刷新模型列表后以服务端返回的为准
+	Local    bool     `json:"local"`  // 本地运行的服务，不需要API密钥，地址可修改
+}
+
+// presets 内置的提供商列表
+var presets = []Preset{
+	{
+		ID:       "openai",
+		Name:     "OpenAI",
+		Icon:     "openai.png",
+		Endpoint: "https://api.openai.com/v1",
+		Models: []string{
+			"gpt-4o",
+			"gpt-4o-mini",
+			"gpt-4.1",
+		},
+	},
+	{
+		ID:       "anthropic",
+		Name:     "Anthropic",
+		Icon:     "anthropic.png",
+		Endpoint: "https://api.anthropic.com/v1",
+		Models: []string{
+			"claude-sonnet-4-0",
+			"claude-opus-4-0",
+			"claude-3-7-sonnet-latest",
+			"claude-3-5-haiku-latest",
+		},
+	},
+	{
+		ID:       "deepseek",
+		Name:     "DeepSeek",
+		Icon:     "deepseek.png",
+		Endpoint: "https://api.deepseek.com/v1",
+		Models: []string{
+			"deepseek-chat",
+			"deepseek-reasoner",
+		},
+	},
+	{
+		ID:       "siliconflow",
+		Name:     "硅基流动(siliconflow)",
+		Icon:     "deepseek.png",
+		Endpoint: "https://api.siliconflow.cn/v1",
+		Models: []string{
+			"THUDM/GLM-Z1-32B-0414",
+			"THUDM/GLM-4-32B-0414",
+			"THUDM/GLM-Z1-Rumination-32B-0414",
+			"THUDM/GLM-4-9B-0414",
+			"Qwen/QwQ-32B",
+			"Pro/deepseek-ai/DeepSeek-R1",
+			"Pro/deepseek-ai/DeepSeek-V3",
+			"deepseek-ai/DeepSeek-R1",
+			"deepseek-ai/DeepSeek-V3",
+			"deepseek-ai/DeepSeek-R1-Distill-Qwen-32B",
+			"deepseek-ai/DeepSeek-R1-Distill-Qwen-14B",
+			"deepseek-ai/DeepSeek-R1-Distill-Qwen-7B",
+			"deepseek-ai/DeepSeek-R1-Distill-Qwen-1.5B",
+			"Pro/deepseek-ai/DeepSeek-R1-Distill-Qwen-7B",
+			"Pro/deepseek-ai/DeepSeek-R1-Distill-Qwen-1.5B",
+		},
+	},
+	{
+		ID:       "baidu",
+		Name:     "百度",
+		Icon:     "baidu.png",
+		Endpoint: "https://qianfan.baidubce.com/v2",
+		Models: []string{
+			"ernie-x1-32k",
+			"ernie-4.0-8k-latest",
+			"ernie-4.0-8k",
+			"ernie-4.0-turbo-8k-latest",
+			"ernie-4.0-turbo-128k",
+			"ernie-3.5-128k",
+			"deepseek-v3",
+			"deepseek-r1",
+		},
+	},
+	{
+		ID:       "tongyi",
+		Name:     "通义千问",
+		Icon:     "tongyi.png",
+		Endpoint: "https://dashscope.aliyuncs.com/compatible-mode/v1",
+		Models: []string{
+			"qwen-max",
+			"qwen-plus",
+			"qwen-turbo",
+			"qwen-long",
+		},
+	},
+	{
+		ID:       "hunyuan",
+		Name:     "腾讯混元",
+		Icon:     "hunyuan.png",
+		Endpoint: "https://api.hunyuan.cloud.tencent.com/v1",
+		Models: []string{
+			"hunyuan-lite",
+			"hunyuan-standard",
+			"hunyuan-standard-256k",
+			"hunyuan-turbo-latest",
+			"hunyuan-large",
+			"hunyuan-large-longcontext",
+		},
+	},
+	{
+		ID:       "huoshan",
+		Name:     "火山引擎",
+		Icon:     "huoshan.png",
+		Endpoint: "https://ark.cn-beijing.volces.com/api/v3/",
+		Models: []string{
+			"doubao-1-5-pro-32k-250115",
+			"doubao-1-5-pro-256k-250115",
+			"doubao-1.5-lite-32k-250115",
+			"deepseek-v3-250324",
+			"deepseek-v3-241226",
+		},
+	},
+	{
+		ID:       "gemini",
+		Name:     "Google Gemini",
+		Icon:     "gemini.png",
+		Endpoint: "https://generativelanguage.googleapis.com/v1beta",
+		Models: []string{
+			"gemini-2.0-flash",
+			"gemini-2.0-flash-lite",
+			"gemini-1.5-flash",
+			"gemini-1.5-pro",
+		},
+	},
+	{
+		ID:       "spark",
+		Name:     "讯飞星火",
+		Icon:     "gemini.png",
+		Endpoint: "https://spark-api-open.xf-yun.com/v1",
+		Models: []string{
+			"x1",
+			"4.0Ultra",
+			"generalv3.5",
+			"max-32k",
+			"generalv3",
+			"pro-128k",
+			"lite",
+		},
+	},
+	{
+		ID:       "cohere",
+		Name:     "cohere",
+		Icon:     "cohere.png",
+		Endpoint: "https://api.cohere.ai/compatibility/v1",
+		Models: []string{
+			"command-r-plus",
+			"command-r",
+			"command",
+			"command-light",
+		},
+	},
+	{
+		ID:       "kimi",
+		Name:     "Kimi",
+		Icon:     "kimi.png",
+		Endpoint: "https://api.moonshot.cn/v1",
+		Models: []string{
+			"kimi-latest-8k",
+			"kimi-latest-32k",
+			"kimi-latest-128k",
+			"moonshot-v1-8k",
+			"moonshot-v1-32k",
+			"moonshot-v1-128k",
+		},
+	},
+	{
+		ID:       "baichuan",
+		Name:     "百川智能",
+		Icon:     "baichuan.png",
+		Endpoint: "https://api.baichuan-ai.com/v1",
+		Models: []string{
+			"Baichuan4-Turbo",
+			"Baichuan4-Air",
+			"Baichuan4",
+			"Baichuan3-Turbo",
+			"Baichuan3-Turbo-128k",
+			"Baichuan2-Turbo",
+		},
+	},
+	{
+		ID:       "ollama",
+		Name:     "Ollama",
+		Icon:     "openai.png",
+		Endpoint: "http://localhost:11434",
+		Local:    true,
+	},
+	{
+		ID:       "local",
+		Name:     "本地OpenAI兼容服务",
+		Icon:     "openai.png",
+		Endpoint: "http://localhost:8080/v1",
+		Local:    true,
+	},
+	{
+		ID:       "local-completion",
+		Name:     "本地补全接口(/completion)",
+		Icon:     "openai.png",
+		Endpoint: "http://localhost:8080",
+		Local:    true,
+	},
+}
+
+// Presets 获取全部内置的提供商预设
+func Presets() []Preset {
+	items := make([]Preset, len(presets))
+	copy(items, presets)
+	return items
+}
+
+// FindPreset 根据提供商ID获取预设
+func FindPreset(id string) (Preset, bool) {
+	for _, preset := range presets {
+		if preset.ID == id {
+			return preset, true
+		}
+	}
+	return Preset{}, false
+}
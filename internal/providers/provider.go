@@ -0,0 +1,145 @@
+package providers
+
+import (
+	"context"
+	"strings"
+)
+
+// 对话角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatMessage 与厂商无关的对话消息
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest 对话请求
+type ChatRequest struct {
+	Model       string
+	Messages    []ChatMessage
+	Temperature *float64 // nil表示使用服务端默认值
+	MaxTokens   int64    // 0表示不限制
+	// LegacyMaxTokens 用 max_tokens 代替 max_completion_tokens，由 ShapeRequest 根据模型能力设置
+	LegacyMaxTokens bool
+	// ThinkingBudget 扩展思考可用的token数，0表示不开启，由 ShapeRequest 根据模型能力设置
+	ThinkingBudget int64
+	// DisableThinking 不开启扩展思考，只需要回答正文的请求（如生成标题、连接测试）使用
+	DisableThinking bool
+	// ChatTemplate 模型文件自带的对话模板，只有 /completion 接口渲染提示词时使用
+	ChatTemplate string
+}
+
+// Float 返回浮点数的指针，用于设置可选的请求参数
+func Float(value float64) *float64 {
+	return &value
+}
+
+// Usage token用量
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// StreamDelta 流式输出的一个增量
+type StreamDelta struct {
+	Content   string
+	Reasoning string // 推理模型的思考过程
+	Usage     *Usage // 仅在返回用量的分片中有值
+}
+
+// StreamHandler 处理流式增量
+type StreamHandler func(delta StreamDelta)
+
+// ChatResult 累积后的完整回答
+type ChatResult struct {
+	Content      string
+	Reasoning    string
+	FinishReason string
+	Usage        Usage
+}
+
+// ModelInfo 服务端返回的模型信息
+type ModelInfo struct {
+	ID            string `json:"id"`
+	OwnedBy       string `json:"owned_by"`
+	Created       int64  `json:"created"`
+	ContextWindow int    `json:"context_window"` // 服务端返回了上下文长度时才有值
+}
+
+// ChatProvider 大模型服务的统一接口，每种厂商协议一个实现
+type ChatProvider interface {
+	// StreamChat 流式对话，handler可以为nil；出错时同样返回已累积的内容
+	StreamChat(ctx context.Context, req ChatRequest, handler StreamHandler) (*ChatResult, error)
+	// ListModels 获取服务端可用的模型
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+	// Embed 计算文本向量
+	Embed(ctx context.Context, model string, inputs []string) ([][]float64, error)
+	// TestConnection 检查服务是否可用
+	TestConnection(ctx context.Context) error
+}
+
+// PullProgress 下载模型的进度
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// ModelPuller 支持从服务端拉取模型的本地提供商
+type ModelPuller interface {
+	PullModel(ctx context.Context, model string, progress func(PullProgress)) error
+}
+
+// VersionReporter 能返回服务端版本的提供商
+type VersionReporter interface {
+	Version(ctx context.Context) (string, error)
+}
+
+// accumulator 累积流式增量，供各实现复用
+type accumulator struct {
+	content   strings.Builder
+	reasoning strings.Builder
+	result    ChatResult
+	handler   StreamHandler
+}
+
+func newAccumulator(handler StreamHandler) *accumulator {
+	return &accumulator{handler: handler}
+}
+
+// add 累积一个增量并转发给handler
+func (a *accumulator) add(delta StreamDelta) {
+	if delta.Content == "" && delta.Reasoning == "" && delta.Usage == nil {
+		return
+	}
+	a.content.WriteString(delta.Content)
+	a.reasoning.WriteString(delta.Reasoning)
+	if delta.Usage != nil {
+		a.result.Usage = *delta.Usage
+	}
+	if a.handler != nil {
+		a.handler(delta)
+	}
+}
+
+// finish 记录结束原因
+func (a *accumulator) finish(reason string) {
+	if reason != "" {
+		a.result.FinishReason = reason
+	}
+}
+
+// Result 当前累积的结果
+func (a *accumulator) Result() *ChatResult {
+	result := a.result
+	result.Content = a.content.String()
+	result.Reasoning = a.reasoning.String()
+	return &result
+}
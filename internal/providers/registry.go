@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"grove-studio/internal/models"
+	"sync"
+)
+
+// OpenAICompatible OpenAI兼容接口，未注册的提供商都按此协议处理
+const OpenAICompatible = "openai"
+
+// Factory 根据模型配置创建对应的实现
+type Factory func(cloudLLM models.CloudLLMModel) (ChatProvider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+	// keyless 本地运行、不需要API密钥的提供商
+	keyless = make(map[string]bool)
+)
+
+// Register 注册一种提供商协议，key 对应 CloudLLMModel.Provider
+func Register(provider string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[provider] = factory
+}
+
+// registerKeyless 注册一种不需要API密钥的本地提供商
+func registerKeyless(provider string, factory Factory) {
+	Register(provider, factory)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	keyless[provider] = true
+}
+
+// RequiresAPIKey 提供商是否需要配置API密钥
+func RequiresAPIKey(provider string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return !keyless[provider]
+}
+
+// New 根据 CloudLLMModel.Provider 创建对应的实现
+func New(cloudLLM models.CloudLLMModel) (ChatProvider, error) {
+	registryMu.RLock()
+	factory, ok := registry[cloudLLM.Provider]
+	if !ok {
+		factory = registry[OpenAICompatible]
+	}
+	registryMu.RUnlock()
+
+	return factory(cloudLLM)
+}
@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"grove-studio/internal/models"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// transports 按网络设置缓存的连接池，相同设置的模型共用连接
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[string]*http.Transport)
+)
+
+// ValidateNetwork 检查模型的网络设置
+func ValidateNetwork(cloudLLM models.CloudLLMModel) error {
+	if cloudLLM.Proxy != "" {
+		if _, err := parseProxy(cloudLLM.Proxy); err != nil {
+			return err
+		}
+	}
+	if cloudLLM.CACertPath != "" {
+		if _, err := loadCertPool(cloudLLM.CACertPath); err != nil {
+			return err
+		}
+	}
+	if cloudLLM.RequestTimeout < 0 || cloudLLM.IdleTimeout < 0 {
+		return errors.New("超时时间不能为负数")
+	}
+	return nil
+}
+
+// newHTTPClient 根据模型的网络设置创建HTTP客户端
+func newHTTPClient(cloudLLM models.CloudLLMModel) (*http.Client, error) {
+	transport, err := sharedTransport(cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if len(cloudLLM.Headers) > 0 {
+		roundTripper = &headerTransport{base: transport, headers: cloudLLM.Headers}
+	}
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+// ResetTransports 丢弃缓存的连接池，网络设置保存后调用，之后的请求按新的设置建立连接
+func ResetTransports() {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	for key, transport := range transports {
+		// 进行中的请求不受影响，结束后连接随连接池一起释放
+		transport.CloseIdleConnections()
+		delete(transports, key)
+	}
+}
+
+// sharedTransport 获取或创建与网络设置对应的连接池
+func sharedTransport(cloudLLM models.CloudLLMModel) (*http.Transport, error) {
+	// CA证书文件被替换后需要重新加载，缓存键中加入文件的修改时间和大小
+	caCert := cloudLLM.CACertPath
+	if caCert != "" {
+		if info, err := os.Stat(caCert); err == nil {
+			caCert = fmt.Sprintf("%s@%d/%d", caCert, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	key := fmt.Sprintf("%s|%s|%d|%d", cloudLLM.Proxy, caCert, cloudLLM.RequestTimeout, cloudLLM.IdleTimeout)
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if transport, ok := transports[key]; ok {
+		return transport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cloudLLM.Proxy != "" {
+		proxyURL, err := parseProxy(cloudLLM.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if cloudLLM.CACertPath != "" {
+		pool, err := loadCertPool(cloudLLM.CACertPath)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	if cloudLLM.RequestTimeout > 0 {
+		transport.ResponseHeaderTimeout = time.Duration(cloudLLM.RequestTimeout) * time.Second
+	}
+	if cloudLLM.IdleTimeout > 0 {
+		idle := time.Duration(cloudLLM.IdleTimeout) * time.Second
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &idleTimeoutConn{Conn: conn, timeout: idle}, nil
+		}
+	}
+
+	transports[key] = transport
+	return transport, nil
+}
+
+// parseProxy 解析代理地址
+func parseProxy(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址格式错误: %v", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New("代理地址需要以 http://、https:// 或 socks5:// 开头")
+	}
+	if proxyURL.Host == "" {
+		return nil, errors.New("代理地址缺少主机名")
+	}
+	return proxyURL, nil
+}
+
+// loadCertPool 在系统证书的基础上加入自定义CA证书
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取CA证书失败: %v", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("CA证书中没有有效的PEM证书")
+	}
+	return pool, nil
+}
+
+// headerTransport 为每个请求附加自定义请求头
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// idleTimeoutConn 每次读取前刷新读超时，连接在指定时间内没有收到数据时读取失败
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"grove-studio/internal/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// selfSignedCert 生成一个与测试服务器无关的自签名证书
+func selfSignedCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// writeCert 把证书写入CA文件，并设置修改时间
+func writeCert(t *testing.T, path string, cert *x509.Certificate, modTime time.Time) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharedTransportReloadsCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cloudLLM := models.CloudLLMModel{CACertPath: filepath.Join(t.TempDir(), "ca.pem")}
+	modTime := time.Now().Add(-time.Hour)
+	writeCert(t, cloudLLM.CACertPath, selfSignedCert(t), modTime)
+
+	get := func() error {
+		client, err := newHTTPClient(cloudLLM)
+		if err != nil {
+			t.Fatalf("创建客户端失败: %v", err)
+		}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(); err == nil {
+		t.Fatal("使用不匹配的CA证书时请求成功")
+	}
+
+	first, _ := sharedTransport(cloudLLM)
+	if again, _ := sharedTransport(cloudLLM); again != first {
+		t.Error("相同的网络设置没有复用连接池")
+	}
+
+	// 替换证书文件后不需要重启即可生效
+	writeCert(t, cloudLLM.CACertPath, server.Certificate(), modTime.Add(time.Minute))
+	if err := get(); err != nil {
+		t.Fatalf("替换CA证书后请求失败: %v", err)
+	}
+	if again, _ := sharedTransport(cloudLLM); again == first {
+		t.Error("CA证书文件修改后仍然使用旧的连接池")
+	}
+}
+
+func TestResetTransports(t *testing.T) {
+	cloudLLM := models.CloudLLMModel{Proxy: "http://127.0.0.1:1", RequestTimeout: 7}
+	first, err := sharedTransport(cloudLLM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ResetTransports()
+	if again, _ := sharedTransport(cloudLLM); again == first {
+		t.Error("ResetTransports 后仍然使用旧的连接池")
+	}
+}
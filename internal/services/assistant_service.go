@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/utils"
+)
+
+// AssistantService 助手服务
+type AssistantService struct {
+	ctx    context.Context
+	logger *utils.Logger
+}
+
+// AssistantPageResult 分页查询结果
+type AssistantPageResult struct {
+	Total int64              `json:"total"`
+	Items []models.Assistant `json:"items"`
+}
+
+// NewAssistantService 创建助手服务
+func NewAssistantService(ctx context.Context) *AssistantService {
+	return &AssistantService{
+		ctx:    ctx,
+		logger: utils.NewLogger(ctx),
+	}
+}
+
+// GetList 获取助手列表，支持分页
+func (a *AssistantService) GetList(page, size int) (*AssistantPageResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 10
+	}
+
+	var total int64
+	var items []models.Assistant
+
+	// 查询总数
+	if err := database.DB.Model(&models.Assistant{}).Count(&total).Error; err != nil {
+		a.logger.Error("获取助手总数失败: %v", err)
+		return nil, err
+	}
+
+	// 分页查询
+	if err := database.DB.Offset((page - 1) * size).Limit(size).Find(&items).Error; err != nil {
+		a.logger.Error("分页查询助手失败: %v", err)
+		return nil, err
+	}
+
+	return &AssistantPageResult{
+		Total: total,
+		Items: items,
+	}, nil
+}
+
+// GetByID 根据ID获取助手
+func (a *AssistantService) GetByID(id uint) (*models.Assistant, error) {
+	var assistant models.Assistant
+	if err := database.DB.First(&assistant, id).Error; err != nil {
+		a.logger.Error("获取助手详情失败: %v", err)
+		return nil, err
+	}
+	return &assistant, nil
+}
+
+// Create 创建助手
+func (a *AssistantService) Create(assistant *models.Assistant) error {
+	if assistant.Name == "" {
+		return errors.New("助手名称不能为空")
+	}
+
+	if err := database.DB.Create(assistant).Error; err != nil {
+		a.logger.Error("创建助手失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Update 更新助手
+func (a *AssistantService) Update(assistant *models.Assistant) error {
+	if assistant.ID == 0 {
+		return errors.New("助手ID不能为空")
+	}
+	if assistant.Name == "" {
+		return errors.New("助手名称不能为空")
+	}
+
+	// 先检查是否存在
+	var count int64
+	if err := database.DB.Model(&models.Assistant{}).Where("id = ?", assistant.ID).Count(&count).Error; err != nil {
+		a.logger.Error("查询助手失败: %v", err)
+		return err
+	}
+	if count == 0 {
+		return errors.New("助手不存在")
+	}
+
+	if err := database.DB.Save(assistant).Error; err != nil {
+		a.logger.Error("更新助手失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete 删除助手，已绑定的会话会解除绑定
+func (a *AssistantService) Delete(id uint) error {
+	if id == 0 {
+		return errors.New("助手ID不能为空")
+	}
+
+	if err := database.DB.Model(&models.Conversation{}).Where("assistant_id = ?", id).Update("assistant_id", 0).Error; err != nil {
+		a.logger.Error("解除会话绑定失败: %v", err)
+		return err
+	}
+
+	if err := database.DB.Delete(&models.Assistant{}, id).Error; err != nil {
+		a.logger.Error("删除助手失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyAssistantDefaults 用助手的默认参数补齐请求中未设置的参数
+func applyAssistantDefaults(assistant *models.Assistant, params *MessageRequestParams) {
+	if assistant == nil {
+		return
+	}
+	if params.CloudLLMId <= 0 && assistant.CloudLLMModelID > 0 {
+		params.CloudLLMId = int(assistant.CloudLLMModelID)
+		params.ModelName = assistant.ModelName
+	}
+	if params.ModelName == "" {
+		params.ModelName = assistant.ModelName
+	}
+	if params.Temperature == nil {
+		params.Temperature = assistant.Temperature
+	}
+	if params.MaxCompletionTokens == 0 {
+		params.MaxCompletionTokens = assistant.MaxTokens
+	}
+	if params.HistoryLength == 0 {
+		params.HistoryLength = assistant.HistoryLength
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"grove-studio/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// 辅助任务类型，对应设置项 <kind>_cloud_llm_id 和 <kind>_model_name
+const (
+	AuxiliaryTaskSummary = "summary"
+	AuxiliaryTaskTitle   = "title"
+)
+
+// auxiliaryModel 读取辅助任务使用的模型配置，未配置时使用fallback
+func auxiliaryModel(kind string, fallbackCloudLLMId int, fallbackModelName string) (int, string) {
+	values := settingValues(kind+"_cloud_llm_id", kind+"_model_name")
+	cloudLLMId, _ := strconv.Atoi(values[kind+"_cloud_llm_id"])
+	modelName := values[kind+"_model_name"]
+
+	if cloudLLMId <= 0 || modelName == "" {
+		return fallbackCloudLLMId, fallbackModelName
+	}
+	return cloudLLMId, modelName
+}
+
+// completeText 非流式地请求一次模型，返回回答正文
+func (n *MessageService) completeText(ctx context.Context, cloudLLM models.CloudLLMModel, req providers.ChatRequest) (string, error) {
+	cloudLLM, err := n.localRuntime.ensure(ctx, cloudLLM)
+	if err != nil {
+		return "", err
+	}
+	defer n.localRuntime.release(cloudLLM)
+	provider, err := providers.New(cloudLLM)
+	if err != nil {
+		return "", err
+	}
+	// 辅助任务只使用回答正文，不开启扩展思考
+	req.DisableThinking = true
+	req, err = shapeRequest(n.logger, cloudLLM.Provider, req)
+	if err != nil {
+		return "", err
+	}
+	req.ChatTemplate = localChatTemplate(cloudLLM, req.Model)
+
+	release, err := acquireModel(ctx, cloudLLM, req, nil)
+	if err != nil {
+		return "", err
+	}
+	result, err := provider.StreamChat(ctx, req, nil)
+	if err != nil || result == nil {
+		// 失败时提供商可能没有返回结果，不计入token用量
+		release(0)
+		if err != nil {
+			return "", err
+		}
+		return "", errors.New("模型返回了空的内容")
+	}
+	release(int(result.Usage.TotalTokens))
+
+	content := strings.TrimSpace(result.Content)
+	if content == "" {
+		return "", errors.New("模型返回了空的内容")
+	}
+	return content, nil
+}
+
+// shapeRequest 按模型能力调整请求参数，并记录被调整的参数
+func shapeRequest(logger *utils.Logger, provider string, req providers.ChatRequest) (providers.ChatRequest, error) {
+	shaped, notes, err := providers.ShapeRequest(provider, req)
+	if err != nil {
+		return req, err
+	}
+	for _, note := range notes {
+		logger.Warning("%s", note)
+	}
+	return shaped, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// HealthStatusOK 连接测试通过
+	HealthStatusOK = "ok"
+
+	// healthCheckTimeout 连接测试的超时时间
+	healthCheckTimeout = 20 * time.Second
+)
+
+// CloudLLMHealth 连接测试结果
+type CloudLLMHealth struct {
+	Status    string    `json:"status"` // ok 或失败原因分类
+	Hint      string    `json:"hint"`   // 失败原因的说明
+	Message   string    `json:"message"`
+	Model     string    `json:"model"` // 测试使用的模型，为空表示只检查了连接
+	LatencyMs int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Test 发送一个最小的请求检查配置是否可用，并保存检查结果
+func (c *CloudLLMModelService) Test(id uint) (*CloudLLMHealth, error) {
+	cloudLLM, err := c.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	// 推理服务启动较慢，不计入测试的超时时间
+	*cloudLLM, err = c.localRuntime.ensure(c.ctx, *cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+	defer c.localRuntime.release(*cloudLLM)
+	provider, err := providers.New(*cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, healthCheckTimeout)
+	defer cancel()
+
+	health := &CloudLLMHealth{Model: c.testModelName(ctx, cloudLLM, provider)}
+	startedAt := time.Now()
+	if health.Model == "" {
+		err = provider.TestConnection(ctx)
+	} else {
+		var req providers.ChatRequest
+		req, err = shapeRequest(c.logger, cloudLLM.Provider, providers.ChatRequest{
+			Model:     health.Model,
+			Messages:  []providers.ChatMessage{{Role: providers.RoleUser, Content: "hi"}},
+			MaxTokens: 1,
+			// 只检查能否连通，不开启扩展思考
+			DisableThinking: true,
+		})
+		var result *providers.ChatResult
+		if err == nil {
+			req.ChatTemplate = localChatTemplate(*cloudLLM, req.Model)
+			result, err = provider.StreamChat(ctx, req, nil)
+		}
+		// 地址指向网页等情况下请求成功但没有任何返回内容
+		if err == nil && result.FinishReason == "" && result.Usage.TotalTokens == 0 && result.Content == "" {
+			err = &providers.APIError{StatusCode: http.StatusNotFound, Message: "接口没有返回任何内容"}
+		}
+	}
+	health.LatencyMs = time.Since(startedAt).Milliseconds()
+	health.CheckedAt = time.Now()
+
+	if err != nil {
+		health.Status = providers.ClassifyError(err)
+		health.Hint = providers.ErrorHint(health.Status)
+		health.Message = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			health.Message = "请求超时"
+		}
+	} else {
+		health.Status = HealthStatusOK
+	}
+
+	if err := database.DB.Model(&models.CloudLLMModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"health_status":     health.Status,
+		"health_message":    health.Message,
+		"health_latency_ms": health.LatencyMs,
+		"health_checked_at": health.CheckedAt,
+	}).Error; err != nil {
+		c.logger.Error("保存连接测试结果失败: %v", err)
+		return nil, err
+	}
+	return health, nil
+}
+
+// testModelName 选择测试用的模型：优先使用预设中账户可用的模型，其次是刷新得到的模型，
+// 都没有时从服务端获取，仍然没有则只检查连接
+func (c *CloudLLMModelService) testModelName(ctx context.Context, cloudLLM *models.CloudLLMModel, provider providers.ChatProvider) string {
+	var discovered []string
+	database.DB.Model(&models.ProviderModel{}).
+		Where("cloud_llm_model_id = ? AND embedding = ?", cloudLLM.ID, false).
+		Order("model_id asc").
+		Pluck("model_id", &discovered)
+
+	if preset, ok := providers.FindPreset(cloudLLM.Provider); ok {
+		for _, name := range preset.Models {
+			if len(discovered) == 0 || slices.Contains(discovered, name) {
+				return name
+			}
+		}
+	}
+	if len(discovered) > 0 {
+		return discovered[0]
+	}
+
+	items, err := provider.ListModels(ctx)
+	if err != nil || len(items) == 0 {
+		return ""
+	}
+	return items[0].ID
+}
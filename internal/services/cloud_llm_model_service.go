@@ -5,13 +5,22 @@ import (
 	"errors"
 	"grove-studio/internal/database"
 	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
 	"grove-studio/internal/utils"
+	"sync"
 )
 
 // CloudLLMModelService 云端LLM模型服务
 type CloudLLMModelService struct {
 	ctx    context.Context
 	logger *utils.Logger
+
+	// 正在下载的模型，key为 cloudLLMId/模型名
+	mu    sync.Mutex
+	pulls map[string]context.CancelFunc
+
+	// localRuntime 测试本地模型配置时按需启动推理服务
+	localRuntime *LocalRuntimeService
 }
 
 // CloudLLMModelPageResult 分页查询结果
@@ -21,10 +30,12 @@ type CloudLLMModelPageResult struct {
 }
 
 // NewCloudLLMModelService 创建云端模型服务
-func NewCloudLLMModelService(ctx context.Context) *CloudLLMModelService {
+func NewCloudLLMModelService(ctx context.Context, localRuntime *LocalRuntimeService) *CloudLLMModelService {
 	return &CloudLLMModelService{
-		ctx:    ctx,
-		logger: utils.NewLogger(ctx),
+		ctx:          ctx,
+		logger:       utils.NewLogger(ctx),
+		pulls:        make(map[string]context.CancelFunc),
+		localRuntime: localRuntime,
 	}
 }
 
@@ -70,8 +81,17 @@ func (c *CloudLLMModelService) GetByID(id uint) (*models.CloudLLMModel, error) {
 
 // Create 创建云端模型
 func (c *CloudLLMModelService) Create(model *models.CloudLLMModel) error {
-	if model.Name == "" || model.Provider == "" || model.ApiKey == "" {
-		return errors.New("模型名称、提供商和API密钥不能为空")
+	if model.Name == "" || model.Provider == "" {
+		return errors.New("模型名称和提供商不能为空")
+	}
+	if model.ApiKey == "" && providers.RequiresAPIKey(model.Provider) {
+		return errors.New("API密钥不能为空")
+	}
+	if err := providers.ValidateNetwork(*model); err != nil {
+		return err
+	}
+	if model.RequestsPerMinute < 0 || model.TokensPerMinute < 0 || model.MaxConcurrency < 0 {
+		return errors.New("限流设置不能为负数")
 	}
 
 	if err := database.DB.Create(model).Error; err != nil {
@@ -86,6 +106,12 @@ func (c *CloudLLMModelService) Update(model *models.CloudLLMModel) error {
 	if model.ID == 0 {
 		return errors.New("模型ID不能为空")
 	}
+	if err := providers.ValidateNetwork(*model); err != nil {
+		return err
+	}
+	if model.RequestsPerMinute < 0 || model.TokensPerMinute < 0 || model.MaxConcurrency < 0 {
+		return errors.New("限流设置不能为负数")
+	}
 
 	// 先检查是否存在
 	var count int64
@@ -94,11 +120,19 @@ func (c *CloudLLMModelService) Update(model *models.CloudLLMModel) error {
 		return errors.New("模型不存在")
 	}
 
+	// 配置修改后之前的连接测试结果不再有效
+	model.HealthStatus = ""
+	model.HealthMessage = ""
+	model.HealthLatencyMs = 0
+	model.HealthCheckedAt = nil
+
 	// 更新模型
 	if err := database.DB.Save(model).Error; err != nil {
 		c.logger.Error("更新云端模型失败: %v", err)
 		return err
 	}
+	// 代理、证书等网络设置可能已修改，不再复用之前的连接
+	providers.ResetTransports()
 	return nil
 }
 
@@ -108,6 +142,11 @@ func (c *CloudLLMModelService) Delete(id uint) error {
 		return errors.New("模型ID不能为空")
 	}
 
+	if err := database.DB.Where("cloud_llm_model_id = ?", id).Delete(&models.ProviderModel{}).Error; err != nil {
+		c.logger.Error("删除模型列表失败: %v", err)
+		return err
+	}
+
 	// 执行删除
 	if err := database.DB.Delete(&models.CloudLLMModel{}, id).Error; err != nil {
 		c.logger.Error("删除云端模型失败: %v", err)
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"grove-studio/internal/utils"
+)
+
+const (
+	// defaultCompletionReserve 未设置最大输出token时为回答预留的token数
+	defaultCompletionReserve = 1024
+	// messageTokenOverhead 每条消息的角色和分隔符开销
+	messageTokenOverhead = 4
+	// replyTokenOverhead 模型回复前缀的开销
+	replyTokenOverhead = 3
+)
+
+// contextWindowFor 获取模型的上下文窗口：优先使用刷新模型列表时保存的值，
+// 其中包含服务端返回的上下文长度，没有时按模型能力推断
+func contextWindowFor(cloudLLM models.CloudLLMModel, modelName string) int {
+	var item models.ProviderModel
+	err := database.DB.Where("cloud_llm_model_id = ? AND model_id = ?", cloudLLM.ID, modelName).Limit(1).Find(&item).Error
+	if err == nil && item.ContextWindow > 0 {
+		return item.ContextWindow
+	}
+	return providers.CapabilitiesFor(cloudLLM.Provider, modelName).ContextWindow
+}
+
+// ContextReport 上下文裁剪结果，通过流式事件告知前端
+type ContextReport struct {
+	ContextWindow    int  `json:"context_window"`
+	CompletionTokens int  `json:"completion_tokens"` // 为回答预留的token
+	PromptTokens     int  `json:"prompt_tokens"`     // 估算的输入token
+	IncludedMessages int  `json:"included_messages"` // 发送的历史消息数
+	DroppedMessages  int  `json:"dropped_messages"`  // 因超出预算被丢弃的历史消息数
+	Summarized       bool `json:"summarized"`        // 是否使用了会话摘要
+	Overflow         bool `json:"overflow"`          // 即使不带历史也超出了预算
+}
+
+// promptBudget 计算可用于输入的token预算
+func promptBudget(cloudLLM models.CloudLLMModel, modelName string, maxCompletionTokens uint32) (window, reserve, budget int) {
+	window = contextWindowFor(cloudLLM, modelName)
+	reserve = int(maxCompletionTokens)
+	if reserve <= 0 {
+		reserve = min(defaultCompletionReserve, window/4)
+	}
+	return window, reserve, window - reserve
+}
+
+// messageTokens 估算单条消息占用的token
+func messageTokens(content string) int {
+	return utils.EstimateTokens(content) + messageTokenOverhead
+}
+
+// fitHistory 从最新的消息开始填充历史，直到用完预算。
+// historyMessages 按时间倒序，返回放入上下文的消息和被丢弃的消息，同样按时间倒序。
+func fitHistory(historyMessages []models.Message, budget int, report *ContextReport) (fitted []models.Message, dropped []models.Message) {
+	for i, msg := range historyMessages {
+		if msg.Role == "assistant" && !msg.Replayable() {
+			continue
+		}
+		tokens := messageTokens(msg.Content)
+		if report.PromptTokens+tokens > budget {
+			for _, rest := range historyMessages[i:] {
+				if rest.Role != "assistant" || rest.Replayable() {
+					dropped = append(dropped, rest)
+				}
+			}
+			break
+		}
+		report.PromptTokens += tokens
+		fitted = append(fitted, msg)
+	}
+	report.IncludedMessages = len(fitted)
+	report.DroppedMessages = len(dropped)
+	return fitted, dropped
+}
+
+// capHistory 按设置的对话轮数限制发送的历史消息，在 fitHistory 之后调用。
+// 超出轮数的消息是用户选择不发送的，不会合并到会话摘要；rounds为0时不限制
+func capHistory(fitted []models.Message, rounds uint32, report *ContextReport) []models.Message {
+	limit := int(rounds) * 2
+	if limit <= 0 || len(fitted) <= limit {
+		return fitted
+	}
+	for _, msg := range fitted[limit:] {
+		report.PromptTokens -= messageTokens(msg.Content)
+	}
+	report.IncludedMessages = limit
+	return fitted[:limit]
+}
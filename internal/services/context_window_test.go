@@ -0,0 +1,57 @@
+package services
+
+import (
+	"grove-studio/internal/models"
+	"testing"
+)
+
+// testHistory 按时间倒序的历史消息，每条消息的token数相同
+func testHistory(n int) []models.Message {
+	items := make([]models.Message, n)
+	for i := range items {
+		role := "assistant"
+		if i%2 == 1 {
+			role = "user"
+		}
+		items[i] = models.Message{BaseModel: models.BaseModel{ID: uint(n - i)}, Role: role, Content: "hello world", Status: models.MessageStatusComplete}
+	}
+	return items
+}
+
+func TestFitAndCapHistory(t *testing.T) {
+	perMessage := messageTokens("hello world")
+	tests := []struct {
+		name         string
+		messages     int
+		budget       int // 能放下的消息条数
+		rounds       uint32
+		wantIncluded int
+		wantDropped  int
+	}{
+		{name: "预算和轮数都足够", messages: 6, budget: 10, rounds: 0, wantIncluded: 6},
+		{name: "超出预算的合并到摘要", messages: 10, budget: 4, rounds: 0, wantIncluded: 4, wantDropped: 6},
+		{name: "轮数限制在预算之后生效", messages: 10, budget: 8, rounds: 2, wantIncluded: 4, wantDropped: 2},
+		{name: "轮数大于预算时以预算为准", messages: 10, budget: 4, rounds: 5, wantIncluded: 4, wantDropped: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &ContextReport{}
+			fitted, dropped := fitHistory(testHistory(tt.messages), tt.budget*perMessage, report)
+			fitted = capHistory(fitted, tt.rounds, report)
+
+			if len(fitted) != tt.wantIncluded || report.IncludedMessages != tt.wantIncluded {
+				t.Errorf("发送 %d 条（报告 %d 条），期望 %d 条", len(fitted), report.IncludedMessages, tt.wantIncluded)
+			}
+			// 只有超出预算的消息会合并到摘要，被轮数限制的不会
+			if len(dropped) != tt.wantDropped || report.DroppedMessages != tt.wantDropped {
+				t.Errorf("丢弃 %d 条（报告 %d 条），期望 %d 条", len(dropped), report.DroppedMessages, tt.wantDropped)
+			}
+			if want := tt.wantIncluded * perMessage; report.PromptTokens != want {
+				t.Errorf("PromptTokens = %d，期望 %d", report.PromptTokens, want)
+			}
+			if len(fitted) > 0 && fitted[0].ID != uint(tt.messages) {
+				t.Errorf("应保留最新的消息，得到ID %d", fitted[0].ID)
+			}
+		})
+	}
+}
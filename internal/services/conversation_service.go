@@ -2,9 +2,11 @@ package services
 
 import (
 	"context"
+	"errors"
 	"grove-studio/internal/database"
 	"grove-studio/internal/models"
 	"grove-studio/internal/utils"
+	"strings"
 )
 
 type ConversationService struct {
@@ -62,3 +64,48 @@ func (n *ConversationService) Destroy(id int) error {
 	}
 	return nil
 }
+
+// Rename 修改会话标题，修改后不再自动生成标题
+func (n *ConversationService) Rename(id int, title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return errors.New("会话标题不能为空")
+	}
+
+	result := database.DB.Model(&models.Conversation{}).Where("id = ?", id).Updates(map[string]any{
+		"title":        truncateTitle(title),
+		"title_edited": true,
+	})
+	if result.Error != nil {
+		n.logger.Error("修改会话标题失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("会话不存在")
+	}
+	return nil
+}
+
+// BindAssistant 为会话绑定助手，assistantId为0时解除绑定
+func (n *ConversationService) BindAssistant(id, assistantId int) error {
+	if assistantId > 0 {
+		var count int64
+		if err := database.DB.Model(&models.Assistant{}).Where("id = ?", assistantId).Count(&count).Error; err != nil {
+			n.logger.Error("查询助手失败: %v", err)
+			return err
+		}
+		if count == 0 {
+			return errors.New("助手不存在")
+		}
+	}
+
+	result := database.DB.Model(&models.Conversation{}).Where("id = ?", id).Update("assistant_id", max(assistantId, 0))
+	if result.Error != nil {
+		n.logger.Error("绑定助手失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("会话不存在")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestConversationRenameAndBind(t *testing.T) {
+	newTestDB(t)
+	service := NewConversationService(context.Background())
+	conversation := models.Conversation{Title: "新会话"}
+	assistant := models.Assistant{Name: "翻译"}
+	if err := database.DB.Create(&conversation).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Create(&assistant).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := int(conversation.ID)
+
+	tests := []struct {
+		name      string
+		call      func() error
+		wantError string
+	}{
+		{name: "修改标题", call: func() error { return service.Rename(id, " 周报 ") }},
+		{name: "标题为空", call: func() error { return service.Rename(id, "  ") }, wantError: "不能为空"},
+		{name: "修改不存在的会话", call: func() error { return service.Rename(9999, "周报") }, wantError: "会话不存在"},
+		{name: "绑定助手", call: func() error { return service.BindAssistant(id, int(assistant.ID)) }},
+		{name: "助手不存在", call: func() error { return service.BindAssistant(id, 9999) }, wantError: "助手不存在"},
+		{name: "绑定到不存在的会话", call: func() error { return service.BindAssistant(9999, int(assistant.ID)) }, wantError: "会话不存在"},
+		{name: "解除绑定", call: func() error { return service.BindAssistant(id, 0) }},
+	}
+	for _, tt := range tests {
+		err := tt.call()
+		if tt.wantError == "" && err != nil {
+			t.Errorf("%s: 返回错误 %v", tt.name, err)
+		}
+		if tt.wantError != "" && (err == nil || !strings.Contains(err.Error(), tt.wantError)) {
+			t.Errorf("%s: 错误 = %v，期望包含 %q", tt.name, err, tt.wantError)
+		}
+	}
+
+	var saved models.Conversation
+	database.DB.First(&saved, id)
+	if saved.Title != "周报" || !saved.TitleEdited || saved.AssistantID != 0 {
+		t.Errorf("会话 = %+v", saved)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// ConversationTitleEventName 会话标题更新事件
+	ConversationTitleEventName = "conversation-title-updated"
+
+	// titleTimeout 生成标题的超时时间
+	titleTimeout = 30 * time.Second
+	// titleMaxRunes 标题最大长度
+	titleMaxRunes = 100
+	// titleMaxTokens 生成标题时的最大输出token
+	titleMaxTokens = 64
+	// titleTemperature 生成标题的温度
+	titleTemperature = 0.5
+
+	titleInstruction = "根据下面的对话为它起一个简短的标题，不超过20个字，" +
+		"使用对话所用的语言，不要加引号、标点结尾或任何解释，只输出标题本身。"
+)
+
+// ConversationTitleEvent 会话标题更新事件内容
+type ConversationTitleEvent struct {
+	ConversationId uint   `json:"conversation_id"`
+	Title          string `json:"title"`
+}
+
+// truncateTitle 限制标题长度，按Unicode字符截取
+func truncateTitle(title string) string {
+	if utf8.RuneCountInString(title) > titleMaxRunes {
+		runes := []rune(title)
+		return string(runes[:titleMaxRunes]) + "..."
+	}
+	return title
+}
+
+// cleanTitle 去掉模型输出中常见的引号、前缀和多余的行
+func cleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = title[:i]
+	}
+	title = strings.TrimPrefix(title, "标题：")
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(title, " \"'“”‘’《》「」*#")
+	title = strings.TrimRight(title, "。.!！")
+	return truncateTitle(title)
+}
+
+// generateTitle 根据第一轮问答生成会话标题，用户手动改过标题时不会覆盖
+func (n *MessageService) generateTitle(conversationID uint, question, answer string, cloudLLMId int, modelName string) {
+	cloudLLMId, modelName = auxiliaryModel(AuxiliaryTaskTitle, cloudLLMId, modelName)
+	cloudLLM, err := n.getCloudLLM(cloudLLMId)
+	if err != nil {
+		return
+	}
+
+	// 问答内容只需要开头部分即可概括主题
+	content := "用户: " + truncateRunes(question, 1000) + "\n助手: " + truncateRunes(answer, 1000)
+
+	ctx, cancel := context.WithTimeout(n.ctx, titleTimeout)
+	defer cancel()
+
+	reply, err := n.completeText(ctx, cloudLLM, providers.ChatRequest{
+		Model: modelName,
+		Messages: []providers.ChatMessage{
+			{Role: providers.RoleSystem, Content: titleInstruction},
+			{Role: providers.RoleUser, Content: content},
+		},
+		Temperature: providers.Float(titleTemperature),
+		MaxTokens:   titleMaxTokens,
+	})
+	if err != nil {
+		n.logger.Error("生成会话标题失败: %v", err)
+		return
+	}
+
+	title := cleanTitle(reply)
+	if title == "" {
+		return
+	}
+
+	result := database.DB.Model(&models.Conversation{}).
+		Where("id = ? AND title_edited = ?", conversationID, false).
+		Update("title", title)
+	if result.Error != nil {
+		n.logger.Error("保存会话标题失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	runtime.EventsEmit(n.ctx, ConversationTitleEventName, ConversationTitleEvent{
+		ConversationId: conversationID,
+		Title:          title,
+	})
+}
+
+// truncateRunes 按Unicode字符截取文本
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit])
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/utils"
+	"testing"
+)
+
+// newTestDB 在临时的应用数据目录中创建数据库，测试结束后关闭
+func newTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	appDataPath, err := utils.GetAppDataPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.InitDB(appDataPath, false); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.CloseDB() })
+
+	dst := []interface{}{
+		&models.Setting{},
+		&models.CloudLLMModel{},
+		&models.ProviderModel{},
+		&models.LocalModel{},
+		&models.Assistant{},
+		&models.Conversation{},
+		&models.Message{},
+	}
+	if err := database.DB.AutoMigrate(dst...); err != nil {
+		t.Fatal(err)
+	}
+}
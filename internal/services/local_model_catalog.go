@@ -0,0 +1,285 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CatalogModel 可供下载的本地模型，同一模型的每个量化版本是一个条目
+type CatalogModel struct {
+	ID              string  `json:"id"`
+	Family          string  `json:"family"` // 同一模型的不同量化版本属于同一系列
+	Name            string  `json:"name"`
+	URL             string  `json:"url"`
+	FileName        string  `json:"file_name"`
+	Parameters      float64 `json:"parameters"` // 参数量（十亿）
+	Quantization    string  `json:"quantization"`
+	Size            int64   `json:"size"`               // 文件大小（字节），近似值，仅用于估算
+	ContextLength   int     `json:"context_length"`     // 模型支持的最大上下文
+	KVBytesPerToken int64   `json:"kv_bytes_per_token"` // f16 KV缓存每个token占用的字节数
+}
+
+// ModelRecommendation 目录中的模型在本机上的评估结果
+type ModelRecommendation struct {
+	CatalogModel
+	Fits           bool     `json:"fits"`        // 内存和磁盘是否足够
+	Recommended    bool     `json:"recommended"` // 同一系列中最适合本机的量化版本
+	Score          int      `json:"score"`       // 0-100，越高越推荐
+	Context        int      `json:"context"`     // 建议使用的上下文长度
+	MemoryRequired uint64   `json:"memory_required"`
+	Reasons        []string `json:"reasons"`
+}
+
+// catalogFamily 一个模型系列，展开为每个量化文件一个条目
+type catalogFamily struct {
+	id            string
+	name          string
+	repo          string // Hugging Face 仓库
+	parameters    float64
+	contextLength int
+	// KV缓存每个token的字节数 = 层数 × KV头数 × 头维度 × 2(K和V) × 2字节
+	kvBytesPerToken int64
+	files           []catalogFile
+}
+
+type catalogFile struct {
+	quantization string
+	fileName     string
+	size         int64
+}
+
+var catalogFamilies = []catalogFamily{
+	{
+		id: "qwen2.5-0.5b", name: "Qwen2.5 0.5B Instruct", repo: "Qwen/Qwen2.5-0.5B-Instruct-GGUF",
+		parameters: 0.49, contextLength: 32768, kvBytesPerToken: 24 * 2 * 64 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "qwen2.5-0.5b-instruct-q4_k_m.gguf", 491_000_000},
+			{"Q8_0", "qwen2.5-0.5b-instruct-q8_0.gguf", 676_000_000},
+		},
+	},
+	{
+		id: "qwen2.5-1.5b", name: "Qwen2.5 1.5B Instruct", repo: "Qwen/Qwen2.5-1.5B-Instruct-GGUF",
+		parameters: 1.54, contextLength: 32768, kvBytesPerToken: 28 * 2 * 128 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "qwen2.5-1.5b-instruct-q4_k_m.gguf", 1_120_000_000},
+			{"Q8_0", "qwen2.5-1.5b-instruct-q8_0.gguf", 1_890_000_000},
+		},
+	},
+	{
+		id: "qwen2.5-3b", name: "Qwen2.5 3B Instruct", repo: "Qwen/Qwen2.5-3B-Instruct-GGUF",
+		parameters: 3.09, contextLength: 32768, kvBytesPerToken: 36 * 2 * 128 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "qwen2.5-3b-instruct-q4_k_m.gguf", 2_100_000_000},
+			{"Q8_0", "qwen2.5-3b-instruct-q8_0.gguf", 3_620_000_000},
+		},
+	},
+	{
+		id: "llama3.2-3b", name: "Llama 3.2 3B Instruct", repo: "bartowski/Llama-3.2-3B-Instruct-GGUF",
+		parameters: 3.21, contextLength: 131072, kvBytesPerToken: 28 * 8 * 128 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "Llama-3.2-3B-Instruct-Q4_K_M.gguf", 2_020_000_000},
+			{"Q8_0", "Llama-3.2-3B-Instruct-Q8_0.gguf", 3_420_000_000},
+		},
+	},
+	{
+		id: "qwen2.5-7b", name: "Qwen2.5 7B Instruct", repo: "bartowski/Qwen2.5-7B-Instruct-GGUF",
+		parameters: 7.62, contextLength: 32768, kvBytesPerToken: 28 * 4 * 128 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "Qwen2.5-7B-Instruct-Q4_K_M.gguf", 4_680_000_000},
+			{"Q6_K", "Qwen2.5-7B-Instruct-Q6_K.gguf", 6_250_000_000},
+			{"Q8_0", "Qwen2.5-7B-Instruct-Q8_0.gguf", 8_100_000_000},
+		},
+	},
+	{
+		id: "llama3.1-8b", name: "Llama 3.1 8B Instruct", repo: "bartowski/Meta-Llama-3.1-8B-Instruct-GGUF",
+		parameters: 8.03, contextLength: 131072, kvBytesPerToken: 32 * 8 * 128 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "Meta-Llama-3.1-8B-Instruct-Q4_K_M.gguf", 4_920_000_000},
+			{"Q8_0", "Meta-Llama-3.1-8B-Instruct-Q8_0.gguf", 8_540_000_000},
+		},
+	},
+	{
+		id: "gemma2-9b", name: "Gemma 2 9B Instruct", repo: "bartowski/gemma-2-9b-it-GGUF",
+		parameters: 9.24, contextLength: 8192, kvBytesPerToken: 42 * 8 * 256 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "gemma-2-9b-it-Q4_K_M.gguf", 5_760_000_000},
+		},
+	},
+	{
+		id: "qwen2.5-14b", name: "Qwen2.5 14B Instruct", repo: "bartowski/Qwen2.5-14B-Instruct-GGUF",
+		parameters: 14.7, contextLength: 32768, kvBytesPerToken: 48 * 8 * 128 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "Qwen2.5-14B-Instruct-Q4_K_M.gguf", 8_990_000_000},
+			{"Q6_K", "Qwen2.5-14B-Instruct-Q6_K.gguf", 12_120_000_000},
+		},
+	},
+	{
+		id: "qwen2.5-32b", name: "Qwen2.5 32B Instruct", repo: "bartowski/Qwen2.5-32B-Instruct-GGUF",
+		parameters: 32.8, contextLength: 32768, kvBytesPerToken: 64 * 8 * 128 * 4,
+		files: []catalogFile{
+			{"Q4_K_M", "Qwen2.5-32B-Instruct-Q4_K_M.gguf", 19_850_000_000},
+		},
+	},
+}
+
+// quantizationQuality 量化类型相对原始精度的质量，用于评分
+var quantizationQuality = map[string]float64{
+	"F16":    1,
+	"Q8_0":   0.99,
+	"Q6_K":   0.97,
+	"Q5_K_M": 0.94,
+	"Q4_K_M": 0.9,
+	"Q4_0":   0.82,
+	"Q3_K_M": 0.74,
+	"Q2_K":   0.55,
+}
+
+const (
+	// runtimeOverhead 推理服务本身和计算缓冲区占用的内存
+	runtimeOverhead = 512 << 20
+	// minReservedMemory 至少给系统和其他程序保留的内存
+	minReservedMemory = 2 << 30
+)
+
+// recommendContexts 依次尝试的上下文长度，取内存能容纳的最大值
+var recommendContexts = []int{32768, 16384, 8192, 4096, 2048}
+
+// LocalModelCatalog 可供下载的本地模型目录
+func LocalModelCatalog() []CatalogModel {
+	var items []CatalogModel
+	for _, family := range catalogFamilies {
+		for _, file := range family.files {
+			items = append(items, CatalogModel{
+				ID:              family.id + ":" + strings.ToLower(file.quantization),
+				Family:          family.id,
+				Name:            family.name,
+				URL:             fmt.Sprintf("https://huggingface.co/%s/resolve/main/%s", family.repo, file.fileName),
+				FileName:        file.fileName,
+				Parameters:      family.parameters,
+				Quantization:    file.quantization,
+				Size:            file.size,
+				ContextLength:   family.contextLength,
+				KVBytesPerToken: family.kvBytesPerToken,
+			})
+		}
+	}
+	return items
+}
+
+// RecommendModels 根据本机硬件为目录中的模型评分，能运行的排在前面，按分数从高到低排序
+func RecommendModels(info *SystemInfo, catalog []CatalogModel) []ModelRecommendation {
+	// 总内存未知时不限制
+	usable := uint64(math.MaxUint64)
+	if info.TotalMemory > 0 {
+		reserved := max(info.TotalMemory/4, minReservedMemory)
+		usable = 0
+		if info.TotalMemory > reserved {
+			usable = info.TotalMemory - reserved
+		}
+	}
+
+	items := make([]ModelRecommendation, 0, len(catalog))
+	for _, model := range catalog {
+		items = append(items, evaluateModel(info, model, usable))
+	}
+
+	// 每个系列中分数最高且能运行的版本作为推荐的量化版本
+	best := make(map[string]int)
+	for i, item := range items {
+		if !item.Fits {
+			continue
+		}
+		if j, ok := best[item.Family]; !ok || item.Score > items[j].Score {
+			best[item.Family] = i
+		}
+	}
+	for _, i := range best {
+		items[i].Recommended = true
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Fits != items[j].Fits {
+			return items[i].Fits
+		}
+		return items[i].Score > items[j].Score
+	})
+	return items
+}
+
+// evaluateModel 评估单个模型，分数由模型规模、量化质量、上下文长度和内存余量组成
+func evaluateModel(info *SystemInfo, model CatalogModel, usable uint64) ModelRecommendation {
+	item := ModelRecommendation{CatalogModel: model}
+
+	if info.FreeDisk > 0 && uint64(model.Size) > info.FreeDisk {
+		item.Reasons = append(item.Reasons, fmt.Sprintf("磁盘空间不足，需要 %s，剩余 %s", formatGB(uint64(model.Size)), formatGB(info.FreeDisk)))
+	}
+
+	for _, context := range recommendContexts {
+		if context > model.ContextLength {
+			continue
+		}
+		required := memoryRequired(model, context)
+		item.Context, item.MemoryRequired = context, required
+		if required <= usable {
+			break
+		}
+	}
+	if item.MemoryRequired > usable {
+		item.Reasons = append(item.Reasons, fmt.Sprintf("内存不足，至少需要 %s，本机可用于模型的约 %s", formatGB(item.MemoryRequired), formatGB(usable)))
+	}
+	item.Fits = len(item.Reasons) == 0
+	if !item.Fits {
+		return item
+	}
+
+	// 参数量按对数计算，32B 及以上为满分
+	sizeScore := math.Min(math.Log2(1+model.Parameters)/math.Log2(33), 1)
+	quality, ok := quantizationQuality[model.Quantization]
+	if !ok {
+		quality = 0.8
+	}
+	contextScore := math.Min(float64(item.Context)/16384, 1)
+	// 占用不超过可用内存的一半时余量为满分
+	headroom := 1.0
+	if usable != math.MaxUint64 {
+		headroom = math.Max(0, math.Min((1-float64(item.MemoryRequired)/float64(usable))/0.5, 1))
+	}
+	score := 50*sizeScore + 20*quality + 10*contextScore + 20*headroom
+
+	// 只用CPU推理时速度取决于指令集和核心数
+	if isX86(info.Arch) && !slices.Contains(info.CPUFeatures, "AVX2") && model.Parameters > 3 {
+		score *= 0.6
+		item.Reasons = append(item.Reasons, "CPU不支持AVX2，推理速度会比较慢")
+	}
+	if info.CPUCores > 0 && info.CPUCores < 4 && model.Parameters > 4 {
+		score *= 0.8
+		item.Reasons = append(item.Reasons, fmt.Sprintf("CPU只有 %d 个核心，推理速度会比较慢", info.CPUCores))
+	}
+	if info.AvailableMemory > 0 && item.MemoryRequired > info.AvailableMemory {
+		score *= 0.85
+		item.Reasons = append(item.Reasons, "当前空闲内存不足，运行前可能需要关闭其他程序")
+	}
+	if context := min(model.ContextLength, recommendContexts[0]); item.Context < context {
+		item.Reasons = append(item.Reasons, fmt.Sprintf("受内存限制，建议上下文长度不超过 %d", item.Context))
+	}
+
+	item.Score = int(math.Round(score))
+	return item
+}
+
+// memoryRequired 运行模型需要的内存：模型文件 + KV缓存 + 运行开销
+func memoryRequired(model CatalogModel, context int) uint64 {
+	return uint64(model.Size) + uint64(model.KVBytesPerToken)*uint64(context) + runtimeOverhead
+}
+
+func isX86(arch string) bool {
+	return arch == "amd64" || arch == "386"
+}
+
+// formatGB 以GB为单位显示字节数
+func formatGB(bytes uint64) string {
+	return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+}
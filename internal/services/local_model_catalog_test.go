@@ -0,0 +1,275 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const gib = 1 << 30
+
+// testCatalogModel 一个测试用的模型，KV缓存每个token 32KB，32K上下文正好占用1GB
+func testCatalogModel(parameters float64, size int64, contextLength int) CatalogModel {
+	return CatalogModel{
+		ID:              "test:q4_k_m",
+		Family:          "test",
+		Parameters:      parameters,
+		Quantization:    "Q4_K_M",
+		Size:            size,
+		ContextLength:   contextLength,
+		KVBytesPerToken: 32 << 10,
+	}
+}
+
+func TestEvaluateModel(t *testing.T) {
+	desktop := SystemInfo{Arch: "amd64", CPUCores: 8, CPUFeatures: []string{"AVX2"}, TotalMemory: 16 * gib, FreeDisk: 500 * gib}
+	with := func(change func(info *SystemInfo)) SystemInfo {
+		info := desktop
+		change(&info)
+		return info
+	}
+
+	tests := []struct {
+		name    string
+		info    SystemInfo
+		model   CatalogModel
+		fits    bool
+		context int
+		reasons []string // 每条原因中包含的文字，顺序一致
+	}{
+		{
+			name:    "内存充足",
+			info:    desktop,
+			model:   testCatalogModel(7, 4*gib, 32768),
+			fits:    true,
+			context: 32768,
+		},
+		{
+			// 8GB 保留2GB，可用6GB：32K上下文需要6.5GB，16K上下文正好6GB
+			name:    "缩短上下文后能运行",
+			info:    with(func(info *SystemInfo) { info.TotalMemory = 8 * gib }),
+			model:   testCatalogModel(7, 5*gib, 32768),
+			fits:    true,
+			context: 16384,
+			reasons: []string{"建议上下文长度不超过 16384"},
+		},
+		{
+			name:    "最短的上下文也放不下",
+			info:    with(func(info *SystemInfo) { info.TotalMemory = 8 * gib }),
+			model:   testCatalogModel(7, 6*gib, 32768),
+			fits:    false,
+			context: 2048,
+			reasons: []string{"内存不足"},
+		},
+		{
+			// 32GB 保留四分之一即8GB，可用24GB
+			name:    "大内存按比例保留",
+			info:    with(func(info *SystemInfo) { info.TotalMemory = 32 * gib }),
+			model:   testCatalogModel(32, 23*gib, 32768),
+			fits:    true,
+			context: 16384,
+			reasons: []string{"建议上下文长度不超过 16384"},
+		},
+		{
+			name:    "总内存未知时不限制",
+			info:    with(func(info *SystemInfo) { info.TotalMemory = 0 }),
+			model:   testCatalogModel(70, 40*gib, 32768),
+			fits:    true,
+			context: 32768,
+		},
+		{
+			name:    "模型本身的上下文较短",
+			info:    desktop,
+			model:   testCatalogModel(9, 5*gib, 8192),
+			fits:    true,
+			context: 8192,
+		},
+		{
+			name:    "磁盘空间不足",
+			info:    with(func(info *SystemInfo) { info.FreeDisk = 3 * gib }),
+			model:   testCatalogModel(7, 4*gib, 32768),
+			fits:    false,
+			context: 32768,
+			reasons: []string{"磁盘空间不足"},
+		},
+		{
+			name:    "磁盘和内存都不足",
+			info:    with(func(info *SystemInfo) { info.TotalMemory, info.FreeDisk = 4*gib, 1*gib }),
+			model:   testCatalogModel(7, 4*gib, 32768),
+			fits:    false,
+			context: 2048,
+			reasons: []string{"磁盘空间不足", "内存不足"},
+		},
+		{
+			name:    "x86 不支持 AVX2",
+			info:    with(func(info *SystemInfo) { info.CPUFeatures = []string{"AVX"} }),
+			model:   testCatalogModel(7, 4*gib, 32768),
+			fits:    true,
+			context: 32768,
+			reasons: []string{"AVX2"},
+		},
+		{
+			name:    "小模型不要求 AVX2",
+			info:    with(func(info *SystemInfo) { info.CPUFeatures = nil }),
+			model:   testCatalogModel(1.5, 1*gib, 32768),
+			fits:    true,
+			context: 32768,
+		},
+		{
+			name:    "ARM 不检查 AVX2",
+			info:    with(func(info *SystemInfo) { info.Arch, info.CPUFeatures = "arm64", []string{"NEON"} }),
+			model:   testCatalogModel(7, 4*gib, 32768),
+			fits:    true,
+			context: 32768,
+		},
+		{
+			name:    "核心数少",
+			info:    with(func(info *SystemInfo) { info.CPUCores = 2 }),
+			model:   testCatalogModel(7, 4*gib, 32768),
+			fits:    true,
+			context: 32768,
+			reasons: []string{"只有 2 个核心"},
+		},
+		{
+			name:    "当前空闲内存不足",
+			info:    with(func(info *SystemInfo) { info.AvailableMemory = 4 * gib }),
+			model:   testCatalogModel(7, 4*gib, 32768),
+			fits:    true,
+			context: 32768,
+			reasons: []string{"空闲内存不足"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := RecommendModels(&tt.info, []CatalogModel{tt.model})
+			if len(items) != 1 {
+				t.Fatalf("结果数量 = %d", len(items))
+			}
+			item := items[0]
+			if item.Fits != tt.fits || item.Context != tt.context {
+				t.Errorf("Fits = %v，Context = %d，期望 %v、%d", item.Fits, item.Context, tt.fits, tt.context)
+			}
+			if item.Recommended != tt.fits {
+				t.Errorf("Recommended = %v，期望 %v", item.Recommended, tt.fits)
+			}
+			if want := memoryRequired(tt.model, item.Context); item.MemoryRequired != want {
+				t.Errorf("MemoryRequired = %d，期望 %d", item.MemoryRequired, want)
+			}
+			if (item.Score > 0) != tt.fits || item.Score > 100 {
+				t.Errorf("Score = %d", item.Score)
+			}
+			if len(item.Reasons) != len(tt.reasons) {
+				t.Fatalf("Reasons = %q，期望 %d 条", item.Reasons, len(tt.reasons))
+			}
+			for i, reason := range tt.reasons {
+				if !strings.Contains(item.Reasons[i], reason) {
+					t.Errorf("Reasons[%d] = %q，期望包含 %q", i, item.Reasons[i], reason)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateModelPenalties(t *testing.T) {
+	model := testCatalogModel(7, 4*gib, 32768)
+	base := SystemInfo{Arch: "amd64", CPUCores: 8, CPUFeatures: []string{"AVX2"}, TotalMemory: 16 * gib}
+	score := RecommendModels(&base, []CatalogModel{model})[0].Score
+
+	for name, change := range map[string]func(info *SystemInfo){
+		"不支持 AVX2": func(info *SystemInfo) { info.CPUFeatures = nil },
+		"核心数少":     func(info *SystemInfo) { info.CPUCores = 2 },
+		"空闲内存不足":   func(info *SystemInfo) { info.AvailableMemory = 2 * gib },
+		"可用内存较少":   func(info *SystemInfo) { info.TotalMemory = 10 * gib },
+	} {
+		info := base
+		change(&info)
+		if got := RecommendModels(&info, []CatalogModel{model})[0].Score; got >= score {
+			t.Errorf("%s: 分数 %d 应低于 %d", name, got, score)
+		}
+	}
+}
+
+func TestRecommendModels(t *testing.T) {
+	tests := []struct {
+		name string
+		info SystemInfo
+		// 每个系列推荐的量化版本，不在其中的系列没有能运行的版本
+		want map[string]string
+	}{
+		{
+			name: "16GB 台式机",
+			info: SystemInfo{Arch: "amd64", CPUCores: 8, CPUFeatures: []string{"AVX2"}, TotalMemory: 16 * gib, AvailableMemory: 12 * gib, FreeDisk: 500 * gib},
+			want: map[string]string{
+				"qwen2.5-0.5b": "Q8_0",
+				"qwen2.5-1.5b": "Q8_0",
+				"qwen2.5-3b":   "Q8_0",
+				"llama3.2-3b":  "Q4_K_M",
+				"qwen2.5-7b":   "Q4_K_M",
+				"llama3.1-8b":  "Q4_K_M",
+				"gemma2-9b":    "Q4_K_M",
+				"qwen2.5-14b":  "Q4_K_M",
+			},
+		},
+		{
+			name: "8GB 笔记本",
+			info: SystemInfo{Arch: "amd64", CPUCores: 8, CPUFeatures: []string{"AVX2"}, TotalMemory: 8 * gib, AvailableMemory: 6 * gib, FreeDisk: 500 * gib},
+			want: map[string]string{
+				"qwen2.5-0.5b": "Q8_0",
+				"qwen2.5-1.5b": "Q8_0",
+				"qwen2.5-3b":   "Q4_K_M",
+				"llama3.2-3b":  "Q8_0",
+				"qwen2.5-7b":   "Q4_K_M",
+				"llama3.1-8b":  "Q4_K_M",
+			},
+		},
+		{
+			name: "4GB 单板机",
+			info: SystemInfo{Arch: "arm64", CPUCores: 4, TotalMemory: 4 * gib, FreeDisk: 500 * gib},
+			want: map[string]string{
+				"qwen2.5-0.5b": "Q4_K_M",
+				"qwen2.5-1.5b": "Q4_K_M",
+			},
+		},
+		{
+			name: "磁盘只剩 1GB",
+			info: SystemInfo{Arch: "arm64", CPUCores: 8, TotalMemory: 16 * gib, FreeDisk: 1 * gib},
+			want: map[string]string{
+				"qwen2.5-0.5b": "Q8_0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := RecommendModels(&tt.info, LocalModelCatalog())
+
+			got := make(map[string]string)
+			for i, item := range items {
+				if item.Recommended {
+					if !item.Fits {
+						t.Errorf("%s 无法运行却被推荐", item.ID)
+					}
+					if _, ok := got[item.Family]; ok {
+						t.Errorf("系列 %s 推荐了多个版本", item.Family)
+					}
+					got[item.Family] = item.Quantization
+				}
+				// 能运行的排在前面，各自按分数从高到低
+				if i > 0 {
+					prev := items[i-1]
+					if (!prev.Fits && item.Fits) || (prev.Fits == item.Fits && prev.Score < item.Score) {
+						t.Errorf("排序错误: %s(%v, %d) 在 %s(%v, %d) 之前", prev.ID, prev.Fits, prev.Score, item.ID, item.Fits, item.Score)
+					}
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("推荐 = %v，期望 %v", got, tt.want)
+			}
+			for family, quantization := range tt.want {
+				if got[family] != quantization {
+					t.Errorf("系列 %s 推荐 %q，期望 %q", family, got[family], quantization)
+				}
+			}
+		})
+	}
+}
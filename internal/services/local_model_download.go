@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// downloadBufferSize 每次写入文件的数据大小
+	downloadBufferSize = 256 * 1024
+	// downloadProgressInterval 下载进度回调的最短间隔
+	downloadProgressInterval = 500 * time.Millisecond
+)
+
+// downloadClient 下载模型文件使用的客户端，文件较大不设置整体超时
+var downloadClient = &http.Client{
+	Transport: func() http.RoundTripper {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = 30 * time.Second
+		return transport
+	}(),
+}
+
+// downloadProgress 下载进度回调，total 未知时为0
+type downloadProgress func(downloaded, total int64)
+
+// downloadFile 把 url 下载到 path，path 已存在时通过 Range 请求从已有的大小继续下载；
+// 服务端不支持断点续传时从头下载。返回文件总大小
+func downloadFile(ctx context.Context, client *http.Client, url, path string, progress downloadProgress) (int64, error) {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var total int64
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return 0, err
+		}
+		if start != offset {
+			return 0, fmt.Errorf("服务端返回的数据位置与已下载的不一致: %d != %d", start, offset)
+		}
+		total = size
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// 服务端忽略了 Range，从头开始下载
+		offset = 0
+		total = max(resp.ContentLength, 0)
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 已下载的大小与文件大小一致时说明上次已经下载完成
+		if _, size, err := parseContentRange(resp.Header.Get("Content-Range")); err == nil && size == offset {
+			return size, nil
+		}
+		return 0, errors.New("已下载的数据与服务端文件不一致，请删除后重新下载")
+	default:
+		return 0, fmt.Errorf("下载失败: %s", resp.Status)
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	downloaded := offset
+	lastReport := time.Time{}
+	buf := make([]byte, downloadBufferSize)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := file.Write(buf[:n]); err != nil {
+				return total, err
+			}
+			downloaded += int64(n)
+			if progress != nil && time.Since(lastReport) >= downloadProgressInterval {
+				lastReport = time.Now()
+				progress(downloaded, total)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return total, readErr
+		}
+	}
+	if progress != nil {
+		progress(downloaded, total)
+	}
+
+	if total > 0 && downloaded != total {
+		return total, fmt.Errorf("下载不完整: %d/%d", downloaded, total)
+	}
+	return downloaded, nil
+}
+
+// parseContentRange 解析 "bytes 100-199/1000" 或 "bytes */1000"，返回起始位置和文件总大小
+func parseContentRange(value string) (start, total int64, err error) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("无法解析 Content-Range: %q", value)
+	}
+	rangePart, totalPart, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("无法解析 Content-Range: %q", value)
+	}
+
+	if totalPart != "*" {
+		if total, err = strconv.ParseInt(totalPart, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("无法解析 Content-Range: %q", value)
+		}
+	}
+	if rangePart != "*" {
+		first, _, _ := strings.Cut(rangePart, "-")
+		if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("无法解析 Content-Range: %q", value)
+		}
+	}
+	return start, total, nil
+}
+
+// fileSHA256 计算文件的sha256，返回小写十六进制
+func fileSHA256(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	buf := make([]byte, downloadBufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := file.Read(buf)
+		hash.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
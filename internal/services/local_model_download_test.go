@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGGUF 只有 general.architecture 一个键的GGUF文件，后面补齐数据模拟较大的文件
+func testGGUF(size int) []byte {
+	var buf bytes.Buffer
+	write := func(value any) { binary.Write(&buf, binary.LittleEndian, value) }
+	writeString := func(value string) {
+		write(uint64(len(value)))
+		buf.WriteString(value)
+	}
+	buf.WriteString("GGUF")
+	write(uint32(3))
+	write(uint64(0)) // 张量数量
+	write(uint64(1)) // 键值对数量
+	writeString("general.architecture")
+	write(uint32(8))
+	writeString("llama")
+	if padding := size - buf.Len(); padding > 0 {
+		buf.Write(bytes.Repeat([]byte{0x5a}, padding))
+	}
+	return buf.Bytes()
+}
+
+// serveFile 支持 Range 的文件服务，记录每次请求的 Range 头
+func serveFile(content []byte, ranges *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ranges != nil {
+			*ranges = append(*ranges, r.Header.Get("Range"))
+		}
+		http.ServeContent(w, r, "model.gguf", time.Time{}, bytes.NewReader(content))
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	content := testGGUF(4096)
+	ignoreRange := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "4096")
+		w.Write(content)
+	}
+
+	tests := []struct {
+		name      string
+		existing  []byte // 下载前已有的部分
+		handler   http.HandlerFunc
+		wantRange string
+		wantError string
+	}{
+		{name: "从头下载", handler: serveFile(content, nil)},
+		{name: "断点续传", existing: content[:1000], wantRange: "bytes=1000-"},
+		{
+			name:      "服务端忽略Range时从头下载",
+			existing:  []byte("<html>not found</html>"),
+			handler:   ignoreRange,
+			wantRange: "bytes=22-",
+		},
+		{name: "上次已下载完成", existing: content, wantRange: "bytes=4096-"},
+		{
+			name:      "已下载的部分比文件大",
+			existing:  append(append([]byte(nil), content...), "多余"...),
+			wantRange: "bytes=4102-",
+			wantError: "不一致",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			handler := tt.handler
+			if handler == nil {
+				handler = serveFile(content, &ranges)
+			} else {
+				inner := handler
+				handler = func(w http.ResponseWriter, r *http.Request) {
+					ranges = append(ranges, r.Header.Get("Range"))
+					inner(w, r)
+				}
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "model.gguf.part")
+			if tt.existing != nil {
+				if err := os.WriteFile(path, tt.existing, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var lastProgress int64
+			total, err := downloadFile(context.Background(), server.Client(), server.URL, path, func(downloaded, total int64) {
+				lastProgress = downloaded
+			})
+			if len(ranges) != 1 || ranges[0] != tt.wantRange {
+				t.Errorf("Range = %q，期望 %q", ranges, tt.wantRange)
+			}
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("downloadFile 返回错误: %v", err)
+			}
+			if total != int64(len(content)) {
+				t.Errorf("total = %d，期望 %d", total, len(content))
+			}
+			if tt.existing == nil && lastProgress != total {
+				t.Errorf("最后一次进度 = %d，期望 %d", lastProgress, total)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, content) {
+				t.Errorf("文件内容不一致，大小 %d", len(data))
+			}
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value      string
+		start, end int64
+		wantError  bool
+	}{
+		{value: "bytes 100-199/1000", start: 100, end: 1000},
+		{value: "bytes */1000", start: 0, end: 1000},
+		{value: "bytes 0-99/*", start: 0, end: 0},
+		{value: "items 0-1/2", wantError: true},
+		{value: "bytes 0-99", wantError: true},
+	}
+	for _, tt := range tests {
+		start, total, err := parseContentRange(tt.value)
+		if (err != nil) != tt.wantError || start != tt.start || total != tt.end {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", tt.value, start, total, err)
+		}
+	}
+}
+
+// newTestLocalModelService 使用临时目录中的数据库，返回的通道接收下载进度事件
+func newTestLocalModelService(t *testing.T) (*LocalModelService, <-chan LocalModelEvent) {
+	t.Helper()
+	newTestDB(t)
+
+	events := make(chan LocalModelEvent, 1024)
+	service := &LocalModelService{
+		ctx:    context.Background(),
+		logger: utils.NewLogger(nil),
+		tasks:  make(map[uint]*localModelTask),
+		emitEvent: func(ctx context.Context, eventName string, data ...interface{}) {
+			events <- data[0].(LocalModelEvent)
+		},
+	}
+	t.Cleanup(service.Shutdown)
+	return service, events
+}
+
+// waitEvent 等待满足条件的事件
+func waitEvent(t *testing.T, events <-chan LocalModelEvent, match func(LocalModelEvent) bool) LocalModelEvent {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if match(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("等待下载事件超时")
+		}
+	}
+}
+
+func waitStatus(t *testing.T, events <-chan LocalModelEvent, status string) LocalModelEvent {
+	t.Helper()
+	return waitEvent(t, events, func(event LocalModelEvent) bool { return event.Status == status })
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLocalModelDownload(t *testing.T) {
+	content := testGGUF(64 * 1024)
+	tests := []struct {
+		name       string
+		sha256     string
+		wantStatus string
+		wantError  string
+	}{
+		{name: "下载完成", sha256: sha256Hex(content), wantStatus: models.LocalModelStatusReady},
+		{name: "不校验", wantStatus: models.LocalModelStatusReady},
+		{
+			name:       "校验失败",
+			sha256:     strings.Repeat("0", 64),
+			wantStatus: models.LocalModelStatusFailed,
+			wantError:  "文件校验失败",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, events := newTestLocalModelService(t)
+			server := httptest.NewServer(serveFile(content, nil))
+			defer server.Close()
+
+			model, err := service.Add(LocalModelParams{URL: server.URL + "/files/tiny.gguf", SHA256: tt.sha256})
+			if err != nil {
+				t.Fatalf("Add 返回错误: %v", err)
+			}
+			event := waitEvent(t, events, func(event LocalModelEvent) bool {
+				return event.Status == models.LocalModelStatusReady || event.Status == models.LocalModelStatusFailed
+			})
+			if event.Status != tt.wantStatus || !strings.Contains(event.Error, tt.wantError) {
+				t.Fatalf("事件 = %+v，期望状态 %s", event, tt.wantStatus)
+			}
+
+			saved, err := service.GetByID(model.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.Status != tt.wantStatus {
+				t.Errorf("保存的状态 = %s，期望 %s", saved.Status, tt.wantStatus)
+			}
+			filePath, _ := service.modelPath(saved)
+			_, fileErr := os.Stat(filePath)
+			_, partErr := os.Stat(filePath + partialSuffix)
+			if tt.wantStatus == models.LocalModelStatusReady {
+				if fileErr != nil || saved.Architecture != "llama" || saved.Size != int64(len(content)) {
+					t.Errorf("模型 = %+v，文件: %v", saved, fileErr)
+				}
+			} else if !errors.Is(fileErr, os.ErrNotExist) || !errors.Is(partErr, os.ErrNotExist) || saved.Downloaded != 0 {
+				t.Errorf("校验失败后应删除文件，已下载 %d，文件: %v, %v", saved.Downloaded, fileErr, partErr)
+			}
+		})
+	}
+}
+
+// stallingServer 不带 Range 的请求只返回前一半数据，然后等待客户端断开；带 Range 的请求正常返回
+func stallingServer(t *testing.T, content []byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			serveFile(content, nil)(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content[:len(content)/2])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLocalModelPauseResume(t *testing.T) {
+	content := testGGUF(64 * 1024)
+	// 先启动服务端，清理时先停止下载再关闭服务端
+	server := stallingServer(t, content)
+	service, events := newTestLocalModelService(t)
+
+	model, err := service.Add(LocalModelParams{URL: server.URL + "/tiny.gguf", SHA256: sha256Hex(content)})
+	if err != nil {
+		t.Fatalf("Add 返回错误: %v", err)
+	}
+	waitEvent(t, events, func(event LocalModelEvent) bool { return event.Downloaded > 0 })
+
+	if err := service.Pause(model.ID); err != nil {
+		t.Fatalf("Pause 返回错误: %v", err)
+	}
+	event := waitStatus(t, events, models.LocalModelStatusPaused)
+	if event.Downloaded <= 0 || event.Downloaded > int64(len(content)/2) {
+		t.Errorf("暂停后已下载 = %d，期望保留已下载的部分", event.Downloaded)
+	}
+	if err := service.Pause(model.ID); err == nil {
+		t.Error("没有在下载时 Pause 应返回错误")
+	}
+
+	if err := service.Start(model.ID); err != nil {
+		t.Fatalf("Start 返回错误: %v", err)
+	}
+	event = waitEvent(t, events, func(event LocalModelEvent) bool {
+		return event.Status == models.LocalModelStatusReady || event.Status == models.LocalModelStatusFailed
+	})
+	if event.Status != models.LocalModelStatusReady {
+		t.Fatalf("继续下载后 = %+v", event)
+	}
+}
+
+func TestLocalModelCancel(t *testing.T) {
+	content := testGGUF(64 * 1024)
+	server := stallingServer(t, content)
+	service, events := newTestLocalModelService(t)
+
+	model, err := service.Add(LocalModelParams{URL: server.URL + "/tiny.gguf"})
+	if err != nil {
+		t.Fatalf("Add 返回错误: %v", err)
+	}
+	waitEvent(t, events, func(event LocalModelEvent) bool { return event.Downloaded > 0 })
+	filePath, _ := service.modelPath(model)
+	if _, err := os.Stat(filePath + partialSuffix); err != nil {
+		t.Fatalf("下载中应有未完成的文件: %v", err)
+	}
+
+	if err := service.Cancel(model.ID); err != nil {
+		t.Fatalf("Cancel 返回错误: %v", err)
+	}
+	if _, err := os.Stat(filePath + partialSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("取消后应删除未完成的文件: %v", err)
+	}
+	if _, err := service.GetByID(model.ID); err == nil {
+		t.Error("取消后应删除模型记录")
+	}
+}
+
+func TestLocalModelDelete(t *testing.T) {
+	content := testGGUF(1024)
+	service, events := newTestLocalModelService(t)
+	server := httptest.NewServer(serveFile(content, nil))
+	defer server.Close()
+
+	model, err := service.Add(LocalModelParams{URL: server.URL + "/tiny.gguf"})
+	if err != nil {
+		t.Fatalf("Add 返回错误: %v", err)
+	}
+	waitStatus(t, events, models.LocalModelStatusReady)
+	if err := service.Cancel(model.ID); err == nil {
+		t.Error("已下载完成的模型 Cancel 应返回错误")
+	}
+
+	managed := models.CloudLLMModel{Name: "tiny", LocalModelID: model.ID}
+	if err := database.DB.Create(&managed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Delete(model.ID); err != nil {
+		t.Fatalf("Delete 返回错误: %v", err)
+	}
+
+	filePath, _ := service.modelPath(model)
+	if _, err := os.Stat(filePath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("删除后模型文件仍存在: %v", err)
+	}
+	var count int64
+	database.DB.Model(&models.CloudLLMModel{}).Where("local_model_id = ?", model.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("删除后仍有 %d 个关联的模型配置", count)
+	}
+	if _, err := service.GetByID(model.ID); err == nil {
+		t.Error("删除后应删除模型记录")
+	}
+}
@@ -0,0 +1,504 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grove-studio/internal/database"
+	"grove-studio/internal/gguf"
+	"grove-studio/internal/models"
+	"grove-studio/internal/utils"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// LocalModelEventName 本地模型下载进度事件
+	LocalModelEventName = "local-model-progress"
+
+	// localModelsDirName 模型文件所在的目录，位于应用数据目录下
+	localModelsDirName = "models"
+	// partialSuffix 未下载完成的文件后缀
+	partialSuffix = ".part"
+	// localModelSaveInterval 下载过程中保存进度的间隔
+	localModelSaveInterval = 5 * time.Second
+)
+
+var (
+	errDownloadPaused   = errors.New("下载已暂停")
+	errDownloadCanceled = errors.New("下载已取消")
+)
+
+// LocalModelService 本地模型服务，负责模型文件的下载和管理
+type LocalModelService struct {
+	ctx    context.Context
+	logger *utils.Logger
+
+	// 正在下载的模型，key为模型ID
+	mu    sync.Mutex
+	tasks map[uint]*localModelTask
+
+	// emitEvent 通知前端，测试时替换为记录事件
+	emitEvent func(ctx context.Context, eventName string, data ...interface{})
+
+	// localRuntime 删除模型前停止对应的推理服务
+	localRuntime *LocalRuntimeService
+}
+
+// localModelTask 一个正在进行的下载
+type localModelTask struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+// LocalModelParams 添加本地模型的参数
+type LocalModelParams struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	FileName string `json:"file_name"` // 为空时使用下载地址中的文件名
+	SHA256   string `json:"sha256"`
+}
+
+// LocalModelEvent 本地模型下载进度事件内容
+type LocalModelEvent struct {
+	ID         uint    `json:"id"`
+	Status     string  `json:"status"`
+	Downloaded int64   `json:"downloaded"`
+	Size       int64   `json:"size"`
+	Percent    float64 `json:"percent"`
+	Speed      int64   `json:"speed"` // 下载速度（字节/秒）
+	Error      string  `json:"error,omitempty"`
+}
+
+// LocalModelDiskUsage 模型文件占用的磁盘空间
+type LocalModelDiskUsage struct {
+	Path         string `json:"path"`
+	TotalBytes   int64  `json:"total_bytes"`   // 模型目录下所有文件的大小
+	ReadyBytes   int64  `json:"ready_bytes"`   // 已下载完成的模型
+	PartialBytes int64  `json:"partial_bytes"` // 未下载完成的文件
+}
+
+// NewLocalModelService 创建本地模型服务
+func NewLocalModelService(ctx context.Context, localRuntime *LocalRuntimeService) *LocalModelService {
+	return &LocalModelService{
+		ctx:          ctx,
+		logger:       utils.NewLogger(ctx),
+		tasks:        make(map[uint]*localModelTask),
+		emitEvent:    runtime.EventsEmit,
+		localRuntime: localRuntime,
+	}
+}
+
+// ModelsDir 获取模型目录
+func (l *LocalModelService) ModelsDir() (string, error) {
+	return localModelsDir()
+}
+
+// localModelsDir 获取应用数据目录下的模型目录，不存在时创建
+func localModelsDir() (string, error) {
+	appDataPath, err := utils.GetAppDataPath()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(appDataPath, localModelsDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// modelPath 模型文件的完整路径
+func (l *LocalModelService) modelPath(model *models.LocalModel) (string, error) {
+	dir, err := l.ModelsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, model.FileName), nil
+}
+
+// ResetInterrupted 应用上次退出时未完成的下载标记为暂停，启动时调用
+func (l *LocalModelService) ResetInterrupted() error {
+	return database.DB.Model(&models.LocalModel{}).
+		Where("status IN ?", []string{models.LocalModelStatusPending, models.LocalModelStatusDownloading, models.LocalModelStatusVerifying}).
+		Update("status", models.LocalModelStatusPaused).Error
+}
+
+// GetList 获取本地模型列表
+func (l *LocalModelService) GetList() ([]models.LocalModel, error) {
+	var items []models.LocalModel
+	if err := database.DB.Order("id desc").Find(&items).Error; err != nil {
+		l.logger.Error("获取本地模型列表失败: %v", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetByID 获取本地模型详情
+func (l *LocalModelService) GetByID(id uint) (*models.LocalModel, error) {
+	if id == 0 {
+		return nil, errors.New("模型ID不能为空")
+	}
+	var model models.LocalModel
+	if err := database.DB.First(&model, id).Error; err != nil {
+		l.logger.Error("获取本地模型详情失败: %v", err)
+		return nil, err
+	}
+	return &model, nil
+}
+
+// Add 添加一个模型并开始下载
+func (l *LocalModelService) Add(params LocalModelParams) (*models.LocalModel, error) {
+	downloadURL, err := url.Parse(strings.TrimSpace(params.URL))
+	if err != nil || (downloadURL.Scheme != "http" && downloadURL.Scheme != "https") || downloadURL.Host == "" {
+		return nil, errors.New("下载地址需要以 http:// 或 https:// 开头")
+	}
+
+	fileName := strings.TrimSpace(params.FileName)
+	if fileName == "" {
+		fileName = path.Base(downloadURL.Path)
+	}
+	if fileName != filepath.Base(fileName) || strings.ContainsAny(fileName, `/\`) || fileName == "." {
+		return nil, errors.New("文件名不能包含路径")
+	}
+	if !strings.EqualFold(filepath.Ext(fileName), ".gguf") {
+		return nil, errors.New("只支持 .gguf 格式的模型文件")
+	}
+
+	checksum := strings.ToLower(strings.TrimSpace(params.SHA256))
+	if checksum != "" && len(checksum) != 64 {
+		return nil, errors.New("sha256 校验值应为64位十六进制字符串")
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.LocalModel{}).Where("file_name = ?", fileName).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("模型文件 %s 已存在", fileName)
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		name = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	}
+	model := &models.LocalModel{
+		Name:     name,
+		URL:      downloadURL.String(),
+		FileName: fileName,
+		SHA256:   checksum,
+		Status:   models.LocalModelStatusPending,
+	}
+	if err := database.DB.Create(model).Error; err != nil {
+		l.logger.Error("添加本地模型失败: %v", err)
+		return nil, err
+	}
+
+	if err := l.Start(model.ID); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// Start 开始或继续下载模型，已下载的部分不会重新下载
+func (l *LocalModelService) Start(id uint) error {
+	model, err := l.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if model.Status == models.LocalModelStatusReady {
+		return errors.New("模型已下载完成")
+	}
+	filePath, err := l.modelPath(model)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(l.ctx)
+	task := &localModelTask{cancel: cancel, done: make(chan struct{})}
+	l.mu.Lock()
+	if _, exists := l.tasks[id]; exists {
+		l.mu.Unlock()
+		cancel(nil)
+		return errors.New("该模型正在下载中")
+	}
+	l.tasks[id] = task
+	l.mu.Unlock()
+
+	model.Status = models.LocalModelStatusDownloading
+	model.Error = ""
+	l.save(model)
+
+	go func() {
+		defer func() {
+			l.mu.Lock()
+			delete(l.tasks, id)
+			l.mu.Unlock()
+			cancel(nil)
+			close(task.done)
+		}()
+		l.download(ctx, model, filePath)
+	}()
+	return nil
+}
+
+// download 下载并校验模型文件，结果保存到数据库并通过事件通知前端
+func (l *LocalModelService) download(ctx context.Context, model *models.LocalModel, filePath string) {
+	partPath := filePath + partialSuffix
+	lastSave := time.Now()
+	lastBytes, lastTime := int64(-1), time.Now()
+	var speed int64
+
+	total, err := downloadFile(ctx, downloadClient, model.URL, partPath, func(downloaded, total int64) {
+		now := time.Now()
+		if lastBytes >= 0 && now.After(lastTime) {
+			speed = int64(float64(downloaded-lastBytes) / now.Sub(lastTime).Seconds())
+		}
+		lastBytes, lastTime = downloaded, now
+
+		model.Downloaded = downloaded
+		model.Size = total
+		if now.Sub(lastSave) >= localModelSaveInterval {
+			lastSave = now
+			l.save(model)
+		}
+		l.emit(model, speed)
+	})
+
+	if err == nil {
+		model.Size = total
+		model.Downloaded = total
+		model.Status = models.LocalModelStatusVerifying
+		l.save(model)
+		l.emit(model, 0)
+		err = l.verify(ctx, model, partPath)
+	}
+	if err == nil {
+		err = os.Rename(partPath, filePath)
+	}
+
+	switch {
+	case err == nil:
+		model.Status = models.LocalModelStatusReady
+		l.logger.Info("模型 %s 下载完成", model.FileName)
+	case errors.Is(context.Cause(ctx), errDownloadCanceled):
+		// 记录和文件由 Cancel 和 Delete 清理
+		return
+	case errors.Is(context.Cause(ctx), errDownloadPaused):
+		model.Status = models.LocalModelStatusPaused
+		if info, statErr := os.Stat(partPath); statErr == nil {
+			model.Downloaded = info.Size()
+		}
+	default:
+		l.logger.Error("下载模型 %s 失败: %v", model.FileName, err)
+		model.Status = models.LocalModelStatusFailed
+		model.Error = err.Error()
+		if info, statErr := os.Stat(partPath); statErr == nil {
+			model.Downloaded = info.Size()
+		}
+	}
+	l.save(model)
+	l.emit(model, 0)
+}
+
+// verify 校验下载的文件并读取模型信息，校验失败时删除文件，下次需要重新下载
+func (l *LocalModelService) verify(ctx context.Context, model *models.LocalModel, partPath string) error {
+	if model.SHA256 != "" {
+		checksum, err := fileSHA256(ctx, partPath)
+		if err != nil {
+			return err
+		}
+		if checksum != model.SHA256 {
+			os.Remove(partPath)
+			model.Downloaded = 0
+			return fmt.Errorf("文件校验失败，sha256 为 %s，与期望的 %s 不一致", checksum, model.SHA256)
+		}
+	}
+
+	// 下载地址指向网页等情况下文件不是GGUF格式
+	meta, err := gguf.ReadFile(partPath)
+	if err != nil {
+		os.Remove(partPath)
+		model.Downloaded = 0
+		return fmt.Errorf("读取模型信息失败: %v", err)
+	}
+	applyMetadata(model, meta)
+	return nil
+}
+
+// applyMetadata 把GGUF文件头的信息保存到模型记录
+func applyMetadata(model *models.LocalModel, meta *gguf.Metadata) {
+	model.Architecture = meta.Architecture
+	model.ParameterCount = meta.ParameterCount
+	model.Quantization = meta.Quantization
+	model.ContextLength = meta.ContextLength
+	model.TokenizerModel = meta.Tokenizer.Model
+	model.VocabSize = meta.Tokenizer.VocabSize
+	model.ChatTemplate = meta.ChatTemplate
+}
+
+// Info 读取已下载模型的GGUF文件头信息，并更新模型记录
+func (l *LocalModelService) Info(id uint) (*gguf.Metadata, error) {
+	model, err := l.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if model.Status != models.LocalModelStatusReady {
+		return nil, errors.New("模型还没有下载完成")
+	}
+	filePath, err := l.modelPath(model)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := gguf.ReadFile(filePath)
+	if err != nil {
+		l.logger.Error("读取模型 %s 的信息失败: %v", model.FileName, err)
+		return nil, err
+	}
+	applyMetadata(model, meta)
+	l.save(model)
+	return meta, nil
+}
+
+// save 保存下载状态
+func (l *LocalModelService) save(model *models.LocalModel) {
+	err := database.DB.Model(model).Select("size", "downloaded", "status", "error",
+		"architecture", "parameter_count", "quantization", "context_length", "tokenizer_model", "vocab_size", "chat_template").
+		Updates(model).Error
+	if err != nil {
+		l.logger.Error("保存本地模型状态失败: %v", err)
+	}
+}
+
+// emit 发送下载进度事件
+func (l *LocalModelService) emit(model *models.LocalModel, speed int64) {
+	event := LocalModelEvent{
+		ID:         model.ID,
+		Status:     model.Status,
+		Downloaded: model.Downloaded,
+		Size:       model.Size,
+		Speed:      speed,
+		Error:      model.Error,
+	}
+	if model.Size > 0 {
+		event.Percent = float64(model.Downloaded) / float64(model.Size) * 100
+	}
+	l.emitEvent(l.ctx, LocalModelEventName, event)
+}
+
+// stop 停止正在进行的下载并等待其结束，没有在下载时返回false
+func (l *LocalModelService) stop(id uint, cause error) bool {
+	l.mu.Lock()
+	task, ok := l.tasks[id]
+	l.mu.Unlock()
+	if !ok {
+		return false
+	}
+	task.cancel(cause)
+	<-task.done
+	return true
+}
+
+// Pause 暂停下载，已下载的部分会保留
+func (l *LocalModelService) Pause(id uint) error {
+	if !l.stop(id, errDownloadPaused) {
+		return errors.New("该模型没有在下载")
+	}
+	return nil
+}
+
+// Cancel 取消下载，删除已下载的部分和模型记录
+func (l *LocalModelService) Cancel(id uint) error {
+	model, err := l.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if model.Status == models.LocalModelStatusReady {
+		return errors.New("模型已下载完成，请使用删除")
+	}
+	return l.Delete(id)
+}
+
+// Delete 删除模型文件和记录，正在下载时会先停止下载
+func (l *LocalModelService) Delete(id uint) error {
+	model, err := l.GetByID(id)
+	if err != nil {
+		return err
+	}
+	l.stop(id, errDownloadCanceled)
+	if l.localRuntime != nil {
+		l.localRuntime.Stop(id)
+	}
+	if err := deleteManagedCloudLLM(id); err != nil {
+		l.logger.Error("删除本地模型配置失败: %v", err)
+		return err
+	}
+
+	filePath, err := l.modelPath(model)
+	if err != nil {
+		return err
+	}
+	for _, item := range []string{filePath, filePath + partialSuffix} {
+		if err := os.Remove(item); err != nil && !errors.Is(err, os.ErrNotExist) {
+			l.logger.Error("删除模型文件失败: %v", err)
+			return err
+		}
+	}
+
+	if err := database.DB.Delete(&models.LocalModel{}, id).Error; err != nil {
+		l.logger.Error("删除本地模型失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DiskUsage 统计模型目录占用的磁盘空间
+func (l *LocalModelService) DiskUsage() (*LocalModelDiskUsage, error) {
+	dir, err := l.ModelsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &LocalModelDiskUsage{Path: dir}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		usage.TotalBytes += info.Size()
+		if strings.HasSuffix(entry.Name(), partialSuffix) {
+			usage.PartialBytes += info.Size()
+		} else {
+			usage.ReadyBytes += info.Size()
+		}
+	}
+	return usage, nil
+}
+
+// Shutdown 暂停所有正在进行的下载，应用退出时调用
+func (l *LocalModelService) Shutdown() {
+	l.mu.Lock()
+	ids := make([]uint, 0, len(l.tasks))
+	for id := range l.tasks {
+		ids = append(ids, id)
+	}
+	l.mu.Unlock()
+
+	for _, id := range ids {
+		l.stop(id, errDownloadPaused)
+	}
+}
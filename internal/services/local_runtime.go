@@ -0,0 +1,673 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"grove-studio/internal/utils"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// 本地推理服务相关的设置项
+const (
+	// SettingLocalRuntimeBinary 推理服务程序（如 llama-server）的路径，为空时从 PATH 中查找
+	SettingLocalRuntimeBinary = "local_runtime_binary"
+	// SettingLocalRuntimeArgs 启动推理服务时追加的参数，以空格分隔，含空格的参数用引号括起来
+	SettingLocalRuntimeArgs = "local_runtime_args"
+	// SettingLocalRuntimeIdleMinutes 多久没有请求后卸载模型（分钟），0表示不自动卸载
+	SettingLocalRuntimeIdleMinutes = "local_runtime_idle_minutes"
+
+	// LocalRuntimeEventName 推理服务状态变化事件
+	LocalRuntimeEventName = "local-runtime-status"
+
+	defaultRuntimeBinary      = "llama-server"
+	defaultRuntimeIdleTimeout = 15 * time.Minute
+	// runtimeStartTimeout 等待模型加载完成的最长时间
+	runtimeStartTimeout = 3 * time.Minute
+	// runtimeStopTimeout 停止时等待进程退出的时间，超时后强制结束
+	runtimeStopTimeout = 10 * time.Second
+	// runtimeHealthInterval 启动时检查服务是否就绪的间隔
+	runtimeHealthInterval = 500 * time.Millisecond
+	// runtimeIdleCheckInterval 检查是否空闲的间隔
+	runtimeIdleCheckInterval = 30 * time.Second
+	// runtimeRestartDelay 崩溃后重启前的等待时间，按重启次数递增
+	runtimeRestartDelay = time.Second
+	// maxRuntimeRestarts 崩溃后自动重启的次数，运行超过 runtimeStableAfter 后重新计数
+	maxRuntimeRestarts = 3
+	runtimeStableAfter = 5 * time.Minute
+)
+
+// 推理服务状态
+const (
+	RuntimeStatusStarting   = "starting"
+	RuntimeStatusRunning    = "running"
+	RuntimeStatusRestarting = "restarting"
+	RuntimeStatusStopped    = "stopped"
+	RuntimeStatusFailed     = "failed"
+)
+
+// LocalRuntimeStatus 本地模型推理服务的状态
+type LocalRuntimeStatus struct {
+	LocalModelID uint   `json:"local_model_id"`
+	CloudLLMId   uint   `json:"cloud_llm_id"` // 对话时使用的模型配置
+	Status       string `json:"status"`
+	Port         int    `json:"port"`
+	PID          int    `json:"pid"`
+	Restarts     int    `json:"restarts"`
+	Error        string `json:"error,omitempty"`
+}
+
+// runtimeProcess 一个本地模型的推理服务进程，字段由 LocalRuntimeService.mu 保护
+type runtimeProcess struct {
+	status   LocalRuntimeStatus
+	alias    string
+	lastUsed time.Time
+	// inFlight 正在进行的请求数，大于0时不会空闲卸载
+	inFlight int
+	cancel   context.CancelFunc
+	// ready 当前这次启动就绪或不再重启时关闭，崩溃重启时换成新的通道
+	ready chan struct{}
+	// done 不再重启、进程已退出时关闭
+	done chan struct{}
+}
+
+// LocalRuntimeService 本地推理服务管理，为每个本地模型启动一个推理服务进程
+type LocalRuntimeService struct {
+	ctx    context.Context
+	logger *utils.Logger
+
+	// 正在运行的推理服务，key为本地模型ID
+	mu        sync.Mutex
+	processes map[uint]*runtimeProcess
+
+	// 以下字段测试时替换
+	emitEvent         func(ctx context.Context, eventName string, data ...interface{})
+	idleTimeout       func() time.Duration
+	idleCheckInterval time.Duration
+	restartDelay      time.Duration
+}
+
+// NewLocalRuntimeService 创建本地推理服务管理
+func NewLocalRuntimeService(ctx context.Context) *LocalRuntimeService {
+	return &LocalRuntimeService{
+		ctx:               ctx,
+		logger:            utils.NewLogger(ctx),
+		processes:         make(map[uint]*runtimeProcess),
+		emitEvent:         runtime.EventsEmit,
+		idleTimeout:       runtimeIdleTimeout,
+		idleCheckInterval: runtimeIdleCheckInterval,
+		restartDelay:      runtimeRestartDelay,
+	}
+}
+
+// ResetManaged 禁用上次运行时自动创建的模型配置，启动时调用
+func (l *LocalRuntimeService) ResetManaged() error {
+	return database.DB.Model(&models.CloudLLMModel{}).Where("local_model_id > 0").Update("enabled", false).Error
+}
+
+// GetStatusList 获取所有推理服务的状态
+func (l *LocalRuntimeService) GetStatusList() []LocalRuntimeStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	items := make([]LocalRuntimeStatus, 0, len(l.processes))
+	for _, process := range l.processes {
+		items = append(items, process.status)
+	}
+	return items
+}
+
+// Start 启动本地模型的推理服务，已在运行时直接返回当前状态
+func (l *LocalRuntimeService) Start(localModelID uint) (*LocalRuntimeStatus, error) {
+	var model models.LocalModel
+	if err := database.DB.First(&model, localModelID).Error; err != nil {
+		l.logger.Error("获取本地模型详情失败: %v", err)
+		return nil, err
+	}
+	if model.Status != models.LocalModelStatusReady {
+		return nil, errors.New("模型还没有下载完成")
+	}
+	dir, err := localModelsDir()
+	if err != nil {
+		return nil, err
+	}
+	binary, err := runtimeBinary()
+	if err != nil {
+		return nil, err
+	}
+	extraArgs, err := splitArgs(settingValues(SettingLocalRuntimeArgs)[SettingLocalRuntimeArgs])
+	if err != nil {
+		return nil, fmt.Errorf("推理服务参数 %s 有误: %v", SettingLocalRuntimeArgs, err)
+	}
+
+	l.mu.Lock()
+	if process, ok := l.processes[localModelID]; ok && !process.finished() {
+		status := process.status
+		l.mu.Unlock()
+		return &status, nil
+	}
+	ctx, cancel := context.WithCancel(l.ctx)
+	process := &runtimeProcess{
+		status:   LocalRuntimeStatus{LocalModelID: localModelID, Status: RuntimeStatusStarting},
+		alias:    strings.TrimSuffix(model.FileName, filepath.Ext(model.FileName)),
+		lastUsed: time.Now(),
+		cancel:   cancel,
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	l.processes[localModelID] = process
+	status := process.status
+	l.mu.Unlock()
+
+	go l.supervise(ctx, process, model, binary, filepath.Join(dir, model.FileName), extraArgs)
+	return &status, nil
+}
+
+// Stop 停止本地模型的推理服务并等待进程退出
+func (l *LocalRuntimeService) Stop(localModelID uint) error {
+	l.mu.Lock()
+	process, ok := l.processes[localModelID]
+	l.mu.Unlock()
+	if !ok {
+		return errors.New("该模型没有在运行")
+	}
+	process.cancel()
+	<-process.done
+	return nil
+}
+
+// Shutdown 停止所有推理服务，应用退出时调用
+func (l *LocalRuntimeService) Shutdown() {
+	l.mu.Lock()
+	processes := make([]*runtimeProcess, 0, len(l.processes))
+	for _, process := range l.processes {
+		processes = append(processes, process)
+	}
+	l.mu.Unlock()
+
+	for _, process := range processes {
+		process.cancel()
+	}
+	for _, process := range processes {
+		<-process.done
+	}
+}
+
+// finished 进程是否已经结束且不会再重启，调用方需持有 LocalRuntimeService.mu
+func (p *runtimeProcess) finished() bool {
+	return p.status.Status == RuntimeStatusStopped || p.status.Status == RuntimeStatusFailed
+}
+
+// supervise 启动进程并在崩溃时重启，直到被停止、空闲卸载或重启次数用完
+func (l *LocalRuntimeService) supervise(ctx context.Context, process *runtimeProcess, model models.LocalModel, binary, modelPath string, extraArgs []string) {
+	defer close(process.done)
+
+	restarts := 0
+	for {
+		startedAt := time.Now()
+		err := l.runOnce(ctx, process, model, binary, modelPath, extraArgs)
+		if ctx.Err() != nil {
+			l.setStatus(process, RuntimeStatusStopped, "")
+			l.logger.Info("本地模型 %s 的推理服务已停止", model.Name)
+			return
+		}
+
+		if time.Since(startedAt) >= runtimeStableAfter {
+			restarts = 0
+		}
+		if restarts >= maxRuntimeRestarts {
+			l.setStatus(process, RuntimeStatusFailed, err.Error())
+			l.logger.Error("本地模型 %s 的推理服务多次异常退出，不再重启: %v", model.Name, err)
+			return
+		}
+		restarts++
+
+		delay := time.Duration(restarts) * l.restartDelay
+		l.logger.Error("本地模型 %s 的推理服务异常退出，%v后重启: %v", model.Name, delay, err)
+		l.mu.Lock()
+		process.status.Restarts++
+		l.mu.Unlock()
+		l.setStatus(process, RuntimeStatusRestarting, err.Error())
+
+		select {
+		case <-ctx.Done():
+			l.setStatus(process, RuntimeStatusStopped, "")
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runOnce 启动一次进程并等待其退出，返回退出原因
+func (l *LocalRuntimeService) runOnce(ctx context.Context, process *runtimeProcess, model models.LocalModel, binary, modelPath string, extraArgs []string) error {
+	port, err := freePort()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-m", modelPath, "--host", "127.0.0.1", "--port", strconv.Itoa(port), "--alias", process.alias}
+	args = append(args, extraArgs...)
+	cmd := exec.Command(binary, args...)
+	prefix := fmt.Sprintf("[%s] ", model.Name)
+	cmd.Stdout = &logWriter{log: l.logger.Info, prefix: prefix}
+	cmd.Stderr = &logWriter{log: l.stderrLog, prefix: prefix}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动推理服务失败: %v", err)
+	}
+	l.logger.Info("本地模型 %s 的推理服务已启动，端口 %d，进程 %d", model.Name, port, cmd.Process.Pid)
+
+	exited := waitExit(cmd)
+
+	l.mu.Lock()
+	process.status.Port = port
+	process.status.PID = cmd.Process.Pid
+	l.mu.Unlock()
+	l.setStatus(process, RuntimeStatusStarting, "")
+
+	if err := waitHealthy(ctx, port, exited); err != nil {
+		stopProcess(cmd, exited)
+		return err
+	}
+
+	cloudLLMID, err := l.register(model, process.alias, port)
+	if err != nil {
+		stopProcess(cmd, exited)
+		return err
+	}
+	l.mu.Lock()
+	process.status.CloudLLMId = cloudLLMID
+	l.mu.Unlock()
+	l.setStatus(process, RuntimeStatusRunning, "")
+	defer l.unregister(cloudLLMID)
+
+	ticker := time.NewTicker(l.idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited.done:
+			return exited.error()
+		case <-ctx.Done():
+			stopProcess(cmd, exited)
+			return ctx.Err()
+		case <-ticker.C:
+			idleTimeout := l.idleTimeout()
+			l.mu.Lock()
+			idle := time.Since(process.lastUsed)
+			inFlight := process.inFlight
+			l.mu.Unlock()
+			// 长时间生成的回答期间不会更新使用时间，有请求在进行时不卸载
+			if idleTimeout > 0 && idle >= idleTimeout && inFlight == 0 {
+				l.logger.Info("本地模型 %s 已空闲 %v，卸载模型", model.Name, idle.Round(time.Second))
+				process.cancel()
+			}
+		}
+	}
+}
+
+// register 创建或更新对应的模型配置，使其出现在对话模型列表中，返回配置ID
+func (l *LocalRuntimeService) register(model models.LocalModel, alias string, port int) (uint, error) {
+	var cloudLLM models.CloudLLMModel
+	err := database.DB.Where("local_model_id = ?", model.ID).Limit(1).Find(&cloudLLM).Error
+	if err != nil {
+		return 0, err
+	}
+	cloudLLM.Name = model.Name
+	cloudLLM.Provider = providers.LocalOpenAI
+	cloudLLM.EndPoint = fmt.Sprintf("http://127.0.0.1:%d/v1", port)
+	cloudLLM.Enabled = true
+	cloudLLM.LocalModelID = model.ID
+	if err := database.DB.Save(&cloudLLM).Error; err != nil {
+		l.logger.Error("保存本地模型配置失败: %v", err)
+		return 0, err
+	}
+
+	item := models.ProviderModel{CloudLLMModelID: cloudLLM.ID, ModelID: alias}
+	database.DB.Where("cloud_llm_model_id = ? AND model_id = ?", cloudLLM.ID, alias).Limit(1).Find(&item)
+	item.OwnedBy = providers.LocalOpenAI
+	item.RefreshedAt = time.Now()
+	applyModelHints(&item, providers.LocalOpenAI, providers.ModelInfo{ID: alias})
+	if err := database.DB.Save(&item).Error; err != nil {
+		l.logger.Error("保存本地模型列表失败: %v", err)
+		return 0, err
+	}
+	return cloudLLM.ID, nil
+}
+
+// unregister 推理服务停止后禁用对应的模型配置
+func (l *LocalRuntimeService) unregister(cloudLLMID uint) {
+	err := database.DB.Model(&models.CloudLLMModel{}).Where("id = ?", cloudLLMID).Update("enabled", false).Error
+	if err != nil {
+		l.logger.Error("禁用本地模型配置失败: %v", err)
+	}
+}
+
+// deleteManagedCloudLLM 删除本地模型自动创建的模型配置
+func deleteManagedCloudLLM(localModelID uint) error {
+	var ids []uint
+	if err := database.DB.Model(&models.CloudLLMModel{}).Where("local_model_id = ?", localModelID).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := database.DB.Where("cloud_llm_model_id IN ?", ids).Delete(&models.ProviderModel{}).Error; err != nil {
+		return err
+	}
+	return database.DB.Delete(&models.CloudLLMModel{}, ids).Error
+}
+
+// setStatus 更新状态并通知前端
+func (l *LocalRuntimeService) setStatus(process *runtimeProcess, status, message string) {
+	l.mu.Lock()
+	process.status.Status = status
+	process.status.Error = message
+	if process.finished() {
+		process.status.PID = 0
+	}
+	settled := status == RuntimeStatusRunning || process.finished()
+	select {
+	case <-process.ready:
+		// 崩溃后重新启动，等待方需要等这一次启动的结果
+		if !settled {
+			process.ready = make(chan struct{})
+		}
+	default:
+		if settled {
+			close(process.ready)
+		}
+	}
+	event := process.status
+	l.mu.Unlock()
+	l.emitEvent(l.ctx, LocalRuntimeEventName, event)
+}
+
+// ensure 对话使用自动创建的本地模型配置时，按需启动推理服务并等待就绪，
+// 返回最新的模型配置（重启后端口可能变化）
+func (l *LocalRuntimeService) ensure(ctx context.Context, cloudLLM models.CloudLLMModel) (models.CloudLLMModel, error) {
+	if l == nil || cloudLLM.LocalModelID == 0 {
+		return cloudLLM, nil
+	}
+	if _, err := l.Start(cloudLLM.LocalModelID); err != nil {
+		return cloudLLM, err
+	}
+
+	l.mu.Lock()
+	process := l.processes[cloudLLM.LocalModelID]
+	process.lastUsed = time.Now()
+	process.inFlight++
+	l.mu.Unlock()
+
+	// 成功时由 release 减少请求数
+	fail := func(err error) (models.CloudLLMModel, error) {
+		l.mu.Lock()
+		process.inFlight--
+		l.mu.Unlock()
+		return cloudLLM, err
+	}
+
+	for {
+		l.mu.Lock()
+		status, ready := process.status, process.ready
+		l.mu.Unlock()
+
+		switch status.Status {
+		case RuntimeStatusRunning:
+			if err := database.DB.First(&cloudLLM, status.CloudLLMId).Error; err != nil {
+				return fail(err)
+			}
+			return cloudLLM, nil
+		case RuntimeStatusStopped:
+			return fail(errors.New("本地模型的推理服务已停止"))
+		case RuntimeStatusFailed:
+			return fail(fmt.Errorf("本地模型启动失败: %s", status.Error))
+		}
+
+		// 启动中或崩溃后等待重启，等这一次启动有结果后重新检查状态
+		select {
+		case <-ctx.Done():
+			return fail(ctx.Err())
+		case <-ready:
+		}
+	}
+}
+
+// release 请求结束时调用，与 ensure 成对使用，
+// 记录本地模型的使用时间并减少正在进行的请求数
+func (l *LocalRuntimeService) release(cloudLLM models.CloudLLMModel) {
+	if l == nil || cloudLLM.LocalModelID == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if process, ok := l.processes[cloudLLM.LocalModelID]; ok {
+		process.lastUsed = time.Now()
+		// 请求期间进程可能被停止后重新启动，新进程没有计入这个请求
+		if process.inFlight > 0 {
+			process.inFlight--
+		}
+	}
+}
+
+// localChatTemplate 通过 /completion 接口对话时，查找已下载的同名模型文件自带的对话模板
+func localChatTemplate(cloudLLM models.CloudLLMModel, modelName string) string {
+	if cloudLLM.Provider != providers.LocalCompletion {
+		return ""
+	}
+	query := database.DB.Model(&models.LocalModel{})
+	if cloudLLM.LocalModelID > 0 {
+		query = query.Where("id = ?", cloudLLM.LocalModelID)
+	} else {
+		// 服务端以去掉扩展名的文件名作为模型名称
+		query = query.Where("file_name = ? AND status = ?", modelName+".gguf", models.LocalModelStatusReady)
+	}
+	var templates []string
+	if err := query.Limit(1).Pluck("chat_template", &templates).Error; err != nil || len(templates) == 0 {
+		return ""
+	}
+	return templates[0]
+}
+
+// runtimeBinary 查找推理服务程序
+func runtimeBinary() (string, error) {
+	binary := settingValues(SettingLocalRuntimeBinary)[SettingLocalRuntimeBinary]
+	if binary == "" {
+		binary = defaultRuntimeBinary
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", fmt.Errorf("未找到推理服务程序 %s，请在设置中配置 %s: %v", binary, SettingLocalRuntimeBinary, err)
+	}
+	return path, nil
+}
+
+// splitArgs 按空白拆分参数，单引号或双引号括起来的部分不拆分，
+// 双引号内可以用 \" 表示引号。其余的反斜杠原样保留，以便填写 Windows 路径
+func splitArgs(value string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '\\' && i+1 < len(runes) && runes[i+1] == '"' {
+				current.WriteRune('"')
+				i++
+			} else if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("引号 %c 没有闭合", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// runtimeIdleTimeout 读取空闲卸载时间
+func runtimeIdleTimeout() time.Duration {
+	value, err := strconv.Atoi(settingValues(SettingLocalRuntimeIdleMinutes)[SettingLocalRuntimeIdleMinutes])
+	if err != nil || value < 0 {
+		return defaultRuntimeIdleTimeout
+	}
+	return time.Duration(value) * time.Minute
+}
+
+// freePort 获取一个本机可用的端口
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitHealthy 等待服务的 /health 返回200，模型加载期间返回503
+func waitHealthy(ctx context.Context, port int, exited *processExit) error {
+	ctx, cancel := context.WithTimeout(ctx, runtimeStartTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/health", port)
+	client := &http.Client{Timeout: runtimeHealthInterval * 4}
+	ticker := time.NewTicker(runtimeHealthInterval)
+	defer ticker.Stop()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-exited.done:
+			return fmt.Errorf("推理服务启动失败: %v", exited.error())
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errors.New("等待模型加载超时")
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopProcess 请求进程退出，超时后强制结束
+func stopProcess(cmd *exec.Cmd, exited *processExit) {
+	// Windows 不支持发送中断信号，直接结束进程
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+	select {
+	case <-exited.done:
+	case <-time.After(runtimeStopTimeout):
+		cmd.Process.Kill()
+		<-exited.done
+	}
+}
+
+// processExit 进程退出时关闭 done，之后可以多次读取退出原因
+type processExit struct {
+	done chan struct{}
+	err  error
+}
+
+// waitExit 在后台等待进程退出
+func waitExit(cmd *exec.Cmd) *processExit {
+	exited := &processExit{done: make(chan struct{})}
+	go func() {
+		exited.err = cmd.Wait()
+		close(exited.done)
+	}()
+	return exited
+}
+
+// error 进程的退出原因，需要在 done 关闭后调用
+func (e *processExit) error() error {
+	if e.err == nil {
+		return errors.New("进程已退出")
+	}
+	return e.err
+}
+
+// stderrLog 推理服务的标准错误输出，包含错误信息的行记为错误，其余记为警告
+func (l *LocalRuntimeService) stderrLog(format string, args ...interface{}) {
+	line := strings.ToLower(fmt.Sprint(args...))
+	if strings.Contains(line, "error") || strings.Contains(line, "failed") {
+		l.logger.Error(format, args...)
+		return
+	}
+	l.logger.Warning(format, args...)
+}
+
+// logWriter 按行把进程输出写入日志
+type logWriter struct {
+	mu     sync.Mutex
+	log    func(format string, args ...interface{})
+	prefix string
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		index := bytes.IndexByte(w.buf, '\n')
+		if index < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.buf[:index]), "\r")
+		w.buf = w.buf[index+1:]
+		if line != "" {
+			w.log("%s%s", w.prefix, line)
+		}
+	}
+	return len(p), nil
+}
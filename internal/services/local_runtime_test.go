@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"grove-studio/internal/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 测试时用测试程序自身模拟推理服务，通过环境变量选择行为
+const (
+	fakeRuntimeEnv    = "GROVE_FAKE_RUNTIME"
+	fakeRuntimeDirEnv = "GROVE_FAKE_RUNTIME_DIR"
+
+	fakeRuntimeServe     = "serve"      // 加载片刻后正常提供服务
+	fakeRuntimeCrashOnce = "crash-once" // 第一次启动就绪后很快崩溃，之后正常
+	fakeRuntimeExit      = "exit"       // 启动后立即退出
+)
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeRuntimeEnv); mode != "" {
+		os.Exit(fakeRuntime(mode))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeRuntime 模拟 llama-server：解析 --port 和 --alias，模型加载期间 /health 返回503
+func fakeRuntime(mode string) int {
+	port, alias := "", ""
+	for i, arg := range os.Args[:len(os.Args)-1] {
+		switch arg {
+		case "--port":
+			port = os.Args[i+1]
+		case "--alias":
+			alias = os.Args[i+1]
+		}
+	}
+	if mode == fakeRuntimeExit || port == "" {
+		fmt.Fprintln(os.Stderr, "error: failed to load model")
+		return 1
+	}
+
+	crash := false
+	if mode == fakeRuntimeCrashOnce {
+		marker := filepath.Join(os.Getenv(fakeRuntimeDirEnv), "crashed")
+		if _, err := os.Stat(marker); err != nil {
+			crash = true
+			os.WriteFile(marker, nil, 0644)
+		}
+	}
+
+	startedAt := time.Now()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if time.Since(startedAt) < 200*time.Millisecond {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"object":"list","data":[{"id":%q,"object":"model","owned_by":"llamacpp"}]}`, alias)
+	})
+	if crash {
+		go func() {
+			time.Sleep(time.Second)
+			os.Exit(1)
+		}()
+	}
+	if err := http.ListenAndServe("127.0.0.1:"+port, mux); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// newTestLocalRuntimeService 创建使用模拟推理服务的服务和一个已下载的模型，返回状态事件
+func newTestLocalRuntimeService(t *testing.T, mode string) (*LocalRuntimeService, models.LocalModel, <-chan LocalRuntimeStatus) {
+	t.Helper()
+	newTestDB(t)
+	t.Setenv(fakeRuntimeEnv, mode)
+	t.Setenv(fakeRuntimeDirEnv, t.TempDir())
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Create(&models.Setting{Key: SettingLocalRuntimeBinary, Value: binary}).Error; err != nil {
+		t.Fatal(err)
+	}
+	model := models.LocalModel{Name: "Test", FileName: "test-7b.gguf", Status: models.LocalModelStatusReady}
+	if err := database.DB.Create(&model).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan LocalRuntimeStatus, 1024)
+	service := &LocalRuntimeService{
+		ctx:       context.Background(),
+		logger:    utils.NewLogger(nil),
+		processes: make(map[uint]*runtimeProcess),
+		emitEvent: func(ctx context.Context, eventName string, data ...interface{}) {
+			events <- data[0].(LocalRuntimeStatus)
+		},
+		idleTimeout:       func() time.Duration { return 0 },
+		idleCheckInterval: 20 * time.Millisecond,
+		restartDelay:      300 * time.Millisecond,
+	}
+	t.Cleanup(service.Shutdown)
+	return service, model, events
+}
+
+// waitRuntimeStatus 等待进入指定状态
+func waitRuntimeStatus(t *testing.T, events <-chan LocalRuntimeStatus, status string) LocalRuntimeStatus {
+	t.Helper()
+	timeout := time.After(15 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Status == status {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("等待推理服务状态 %s 超时", status)
+		}
+	}
+}
+
+func TestLocalRuntimeStartStop(t *testing.T) {
+	service, model, events := newTestLocalRuntimeService(t, fakeRuntimeServe)
+
+	status, err := service.Start(model.ID)
+	if err != nil {
+		t.Fatalf("Start 返回错误: %v", err)
+	}
+	if status.Status != RuntimeStatusStarting {
+		t.Errorf("状态 = %s，期望 %s", status.Status, RuntimeStatusStarting)
+	}
+
+	running := waitRuntimeStatus(t, events, RuntimeStatusRunning)
+	var cloudLLM models.CloudLLMModel
+	if err := database.DB.First(&cloudLLM, running.CloudLLMId).Error; err != nil {
+		t.Fatalf("没有创建模型配置: %v", err)
+	}
+	if !cloudLLM.Enabled || cloudLLM.LocalModelID != model.ID || cloudLLM.EndPoint != fmt.Sprintf("http://127.0.0.1:%d/v1", running.Port) {
+		t.Errorf("模型配置 = %+v", cloudLLM)
+	}
+	var item models.ProviderModel
+	if err := database.DB.Where("cloud_llm_model_id = ? AND model_id = ?", cloudLLM.ID, "test-7b").First(&item).Error; err != nil {
+		t.Errorf("没有以文件名作为模型名称: %v", err)
+	}
+
+	if err := service.Stop(model.ID); err != nil {
+		t.Fatalf("Stop 返回错误: %v", err)
+	}
+	if stopped := waitRuntimeStatus(t, events, RuntimeStatusStopped); stopped.PID != 0 {
+		t.Errorf("停止后 PID = %d", stopped.PID)
+	}
+	database.DB.First(&cloudLLM, cloudLLM.ID)
+	if cloudLLM.Enabled {
+		t.Error("停止后模型配置没有禁用")
+	}
+}
+
+func TestLocalRuntimeRestartAfterCrash(t *testing.T) {
+	service, model, events := newTestLocalRuntimeService(t, fakeRuntimeCrashOnce)
+
+	if _, err := service.Start(model.ID); err != nil {
+		t.Fatalf("Start 返回错误: %v", err)
+	}
+	first := waitRuntimeStatus(t, events, RuntimeStatusRunning)
+	waitRuntimeStatus(t, events, RuntimeStatusRestarting)
+
+	// 重启期间发起的请求等待重启完成，而不是拿到崩溃前的状态
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	cloudLLM, err := service.ensure(ctx, models.CloudLLMModel{LocalModelID: model.ID})
+	if err != nil {
+		t.Fatalf("ensure 返回错误: %v", err)
+	}
+	defer service.release(cloudLLM)
+
+	statuses := service.GetStatusList()
+	if len(statuses) != 1 || statuses[0].Status != RuntimeStatusRunning || statuses[0].Restarts != 1 {
+		t.Fatalf("状态 = %+v，期望重启一次后运行中", statuses)
+	}
+	if cloudLLM.ID != first.CloudLLMId || cloudLLM.EndPoint != fmt.Sprintf("http://127.0.0.1:%d/v1", statuses[0].Port) {
+		t.Errorf("模型配置 = %+v，期望使用重启后的端口 %d", cloudLLM, statuses[0].Port)
+	}
+}
+
+func TestLocalRuntimeFailed(t *testing.T) {
+	service, model, events := newTestLocalRuntimeService(t, fakeRuntimeExit)
+	service.restartDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if _, err := service.ensure(ctx, models.CloudLLMModel{LocalModelID: model.ID}); err == nil {
+		t.Fatal("启动失败时 ensure 没有返回错误")
+	}
+	failed := waitRuntimeStatus(t, events, RuntimeStatusFailed)
+	if failed.Restarts != maxRuntimeRestarts || failed.Error == "" {
+		t.Errorf("状态 = %+v", failed)
+	}
+}
+
+func TestLocalRuntimeIdleUnload(t *testing.T) {
+	service, model, events := newTestLocalRuntimeService(t, fakeRuntimeServe)
+	service.idleTimeout = func() time.Duration { return 100 * time.Millisecond }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	cloudLLM, err := service.ensure(ctx, models.CloudLLMModel{LocalModelID: model.ID})
+	if err != nil {
+		t.Fatalf("ensure 返回错误: %v", err)
+	}
+
+	// 请求进行中，超过空闲时间也不卸载
+	time.Sleep(500 * time.Millisecond)
+	if statuses := service.GetStatusList(); statuses[0].Status != RuntimeStatusRunning {
+		t.Fatalf("请求进行中被卸载: %+v", statuses[0])
+	}
+
+	service.release(cloudLLM)
+	waitRuntimeStatus(t, events, RuntimeStatusStopped)
+	if _, err := service.ensure(ctx, models.CloudLLMModel{}); err != nil {
+		t.Errorf("非本地模型配置 ensure 返回错误: %v", err)
+	}
+}
+
+func TestLocalRuntimeEnsureCanceled(t *testing.T) {
+	service, model, _ := newTestLocalRuntimeService(t, fakeRuntimeServe)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.ensure(ctx, models.CloudLLMModel{LocalModelID: model.ID}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ensure 返回 %v，期望 context.Canceled", err)
+	}
+	if statuses := service.GetStatusList(); len(statuses) != 1 {
+		t.Fatalf("状态 = %+v", statuses)
+	}
+	service.mu.Lock()
+	inFlight := service.processes[model.ID].inFlight
+	service.mu.Unlock()
+	if inFlight != 0 {
+		t.Errorf("取消后请求数 = %d，期望 0", inFlight)
+	}
+}
+
+func TestLocalRuntimeOnDemand(t *testing.T) {
+	service, model, _ := newTestLocalRuntimeService(t, fakeRuntimeServe)
+	// 上次运行时创建的模型配置，启动时已被禁用，端口也已失效
+	cloudLLM := models.CloudLLMModel{Name: model.Name, Provider: providers.LocalOpenAI, EndPoint: "http://127.0.0.1:1/v1", LocalModelID: model.ID}
+	if err := database.DB.Create(&cloudLLM).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	providerModels := &ProviderModelService{ctx: context.Background(), logger: utils.NewLogger(nil), localRuntime: service}
+	items, err := providerModels.Refresh(cloudLLM.ID)
+	if err != nil {
+		t.Fatalf("Refresh 返回错误: %v", err)
+	}
+	if len(items) != 1 || items[0].ModelID != "test-7b" {
+		t.Errorf("模型列表 = %+v", items)
+	}
+
+	cloudLLMs := &CloudLLMModelService{ctx: context.Background(), logger: utils.NewLogger(nil), localRuntime: service}
+	service.Stop(model.ID)
+	status, err := cloudLLMs.ServerStatus(cloudLLM.ID)
+	if err != nil || !status.Reachable || status.Error != "" {
+		t.Fatalf("ServerStatus = %+v, %v", status, err)
+	}
+	if len(status.Models) != 1 || status.Models[0].ID != "test-7b" {
+		t.Errorf("模型列表 = %+v", status.Models)
+	}
+
+	service.mu.Lock()
+	inFlight := service.processes[model.ID].inFlight
+	service.mu.Unlock()
+	if inFlight != 0 {
+		t.Errorf("请求结束后请求数 = %d，期望 0", inFlight)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "  -c 4096\t--flash-attn ", want: []string{"-c", "4096", "--flash-attn"}},
+		{value: `--chat-template-file "/Users/me/My Templates/qwen.jinja"`, want: []string{"--chat-template-file", "/Users/me/My Templates/qwen.jinja"}},
+		{value: `--system-prompt 'say "hi"'`, want: []string{"--system-prompt", `say "hi"`}},
+		{value: `--alias "a \"b\""`, want: []string{"--alias", `a "b"`}},
+		{value: `--lora C:\models\lora.gguf`, want: []string{"--lora", `C:\models\lora.gguf`}},
+		{value: `--api-key ""`, want: []string{"--api-key", ""}},
+		{value: `--prefix="a b"c`, want: []string{"--prefix=a bc"}},
+		{value: `--system-prompt "unterminated`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitArgs(%q) 没有返回错误", tt.value)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, %v，期望 %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestLocalRuntimeStartInvalidArgs(t *testing.T) {
+	service, model, _ := newTestLocalRuntimeService(t, fakeRuntimeServe)
+	if err := database.DB.Create(&models.Setting{Key: SettingLocalRuntimeArgs, Value: `--alias "x`}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Start(model.ID); err == nil {
+		t.Fatal("参数有误时 Start 没有返回错误")
+	}
+	if statuses := service.GetStatusList(); len(statuses) != 0 {
+		t.Errorf("参数有误时不应启动进程: %+v", statuses)
+	}
+}
+
+func TestLocalChatTemplate(t *testing.T) {
+	newTestLocalModelService(t)
+	items := []models.LocalModel{
+		{FileName: "qwen2.5-7b.gguf", Status: models.LocalModelStatusReady, ChatTemplate: "qwen"},
+		{FileName: "llama-3.gguf", Status: models.LocalModelStatusDownloading, ChatTemplate: "llama"},
+	}
+	if err := database.DB.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cloudLLM models.CloudLLMModel
+		model    string
+		want     string
+	}{
+		{"按文件名查找", models.CloudLLMModel{Provider: providers.LocalCompletion}, "qwen2.5-7b", "qwen"},
+		{"按本地模型查找", models.CloudLLMModel{Provider: providers.LocalCompletion, LocalModelID: items[0].ID}, "default", "qwen"},
+		{"未下载完成", models.CloudLLMModel{Provider: providers.LocalCompletion}, "llama-3", ""},
+		{"没有对应的文件", models.CloudLLMModel{Provider: providers.LocalCompletion}, "default", ""},
+		{"其他提供商不需要模板", models.CloudLLMModel{Provider: providers.LocalOpenAI}, "qwen2.5-7b", ""},
+	}
+	for _, tt := range tests {
+		if got := localChatTemplate(tt.cloudLLM, tt.model); got != tt.want {
+			t.Errorf("%s: localChatTemplate = %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grove-studio/internal/providers"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// ModelPullEventName 模型下载进度事件
+	ModelPullEventName = "model-pull-progress"
+
+	// serverStatusTimeout 检查本地服务状态的超时时间，本地服务应当很快响应
+	serverStatusTimeout = 5 * time.Second
+)
+
+// LocalServerStatus 本地模型服务的状态
+type LocalServerStatus struct {
+	Reachable bool                  `json:"reachable"`
+	Version   string                `json:"version"`
+	Models    []providers.ModelInfo `json:"models"`
+	Error     string                `json:"error"`
+}
+
+// ModelPullEvent 模型下载进度事件内容
+type ModelPullEvent struct {
+	CloudLLMId uint   `json:"cloud_llm_id"`
+	Model      string `json:"model"`
+	providers.PullProgress
+	Percent float64 `json:"percent"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// ServerStatus 检查服务是否可以访问，并列出已安装的模型
+func (c *CloudLLMModelService) ServerStatus(id uint) (*LocalServerStatus, error) {
+	cloudLLM, err := c.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	*cloudLLM, err = c.localRuntime.ensure(c.ctx, *cloudLLM)
+	if err != nil {
+		return &LocalServerStatus{Error: err.Error()}, nil
+	}
+	defer c.localRuntime.release(*cloudLLM)
+	provider, err := providers.New(*cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, serverStatusTimeout)
+	defer cancel()
+
+	status := &LocalServerStatus{}
+	if reporter, ok := provider.(providers.VersionReporter); ok {
+		status.Version, err = reporter.Version(ctx)
+	} else {
+		err = provider.TestConnection(ctx)
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+	status.Reachable = true
+
+	if status.Models, err = provider.ListModels(ctx); err != nil {
+		status.Error = err.Error()
+	}
+	return status, nil
+}
+
+// PullModel 让本地服务下载模型，下载进度通过 ModelPullEventName 事件发送
+func (c *CloudLLMModelService) PullModel(id uint, model string) error {
+	if model == "" {
+		return errors.New("模型名称不能为空")
+	}
+	cloudLLM, err := c.GetByID(id)
+	if err != nil {
+		return err
+	}
+	provider, err := providers.New(*cloudLLM)
+	if err != nil {
+		return err
+	}
+	puller, ok := provider.(providers.ModelPuller)
+	if !ok {
+		return fmt.Errorf("提供商 %s 不支持下载模型", cloudLLM.Provider)
+	}
+
+	key := fmt.Sprintf("%d/%s", id, model)
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.mu.Lock()
+	if _, exists := c.pulls[key]; exists {
+		c.mu.Unlock()
+		cancel()
+		return errors.New("该模型正在下载中")
+	}
+	c.pulls[key] = cancel
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pulls, key)
+		c.mu.Unlock()
+		cancel()
+	}()
+
+	event := ModelPullEvent{CloudLLMId: id, Model: model}
+	err = puller.PullModel(ctx, model, func(progress providers.PullProgress) {
+		event.PullProgress = progress
+		event.Percent = 0
+		if progress.Total > 0 {
+			event.Percent = float64(progress.Completed) / float64(progress.Total) * 100
+		}
+		runtime.EventsEmit(c.ctx, ModelPullEventName, event)
+	})
+
+	event.Done = true
+	if errors.Is(err, context.Canceled) {
+		event.Error = "已取消下载"
+	} else if err != nil {
+		c.logger.Error("下载模型 %s 失败: %v", model, err)
+		event.Error = err.Error()
+	}
+	runtime.EventsEmit(c.ctx, ModelPullEventName, event)
+	return err
+}
+
+// CancelPull 取消正在进行的模型下载
+func (c *CloudLLMModelService) CancelPull(id uint, model string) error {
+	key := fmt.Sprintf("%d/%s", id, model)
+	c.mu.Lock()
+	cancel, ok := c.pulls[key]
+	c.mu.Unlock()
+	if !ok {
+		return errors.New("没有正在下载的该模型")
+	}
+	cancel()
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// StreamEventName 流式消息事件名，前端通过 request_id 区分不同请求
+const StreamEventName = "stream-request-message"
+
+// 流式消息事件类型
+const (
+	StreamEventStart     = "start"
+	StreamEventQueued    = "queued" // 触发限流，正在排队
+	StreamEventContext   = "context"
+	StreamEventDelta     = "delta"
+	StreamEventReasoning = "reasoning"
+	StreamEventUsage     = "usage"
+	StreamEventRetry     = "retry"    // 遇到临时错误，等待后重试
+	StreamEventFallback  = "fallback" // 切换到备用模型
+	StreamEventError     = "error"
+	StreamEventDone      = "done"
+)
+
+// StreamUsage token用量
+type StreamUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// StreamEvent 流式消息事件
+type StreamEvent struct {
+	Type           string         `json:"type"`
+	RequestId      string         `json:"request_id"`
+	ConversationId uint           `json:"conversation_id"`
+	Index          int            `json:"index"`
+	Content        string         `json:"content,omitempty"`
+	Usage          *StreamUsage   `json:"usage,omitempty"`
+	Context        *ContextReport `json:"context,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	Status         string         `json:"status,omitempty"`
+	MessageId      uint           `json:"message_id,omitempty"`
+	Attempt        int            `json:"attempt,omitempty"`
+	DelayMs        int64          `json:"delay_ms,omitempty"`
+	ModelName      string         `json:"model_name,omitempty"`
+	Position       int            `json:"position,omitempty"`
+}
+
+// streamEmitter 绑定单个请求的事件发送器
+type streamEmitter struct {
+	ctx            context.Context
+	requestId      string
+	conversationId uint
+	index          int
+}
+
+func newStreamEmitter(ctx context.Context, requestId string, conversationId uint) *streamEmitter {
+	return &streamEmitter{
+		ctx:            ctx,
+		requestId:      requestId,
+		conversationId: conversationId,
+	}
+}
+
+// emit 发送事件，自动填充请求ID、会话ID和序号
+func (e *streamEmitter) emit(event StreamEvent) {
+	event.RequestId = e.requestId
+	event.ConversationId = e.conversationId
+	event.Index = e.index
+	e.index++
+	runtime.EventsEmit(e.ctx, StreamEventName, event)
+}
+
+func (e *streamEmitter) start() {
+	e.emit(StreamEvent{Type: StreamEventStart})
+}
+
+func (e *streamEmitter) context(report *ContextReport) {
+	e.emit(StreamEvent{Type: StreamEventContext, Context: report})
+}
+
+func (e *streamEmitter) delta(content string) {
+	e.emit(StreamEvent{Type: StreamEventDelta, Content: content})
+}
+
+func (e *streamEmitter) reasoning(content string) {
+	e.emit(StreamEvent{Type: StreamEventReasoning, Content: content})
+}
+
+func (e *streamEmitter) usage(usage StreamUsage) {
+	e.emit(StreamEvent{Type: StreamEventUsage, Usage: &usage})
+}
+
+func (e *streamEmitter) queued(position int) {
+	e.emit(StreamEvent{Type: StreamEventQueued, Position: position})
+}
+
+func (e *streamEmitter) retry(attempt int, delay time.Duration, err error) {
+	e.emit(StreamEvent{Type: StreamEventRetry, Attempt: attempt, DelayMs: delay.Milliseconds(), Error: err.Error()})
+}
+
+func (e *streamEmitter) fallback(modelName string, err error) {
+	e.emit(StreamEvent{Type: StreamEventFallback, ModelName: modelName, Error: err.Error()})
+}
+
+func (e *streamEmitter) error(err error) {
+	e.emit(StreamEvent{Type: StreamEventError, Error: err.Error()})
+}
+
+func (e *streamEmitter) done(status string, messageId uint) {
+	e.emit(StreamEvent{Type: StreamEventDone, Status: status, MessageId: messageId})
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"time"
+)
+
+// modelCandidate 一个可以生成回答的模型
+type modelCandidate struct {
+	cloudLLM  models.CloudLLMModel
+	modelName string
+}
+
+// fallbackCandidates 主模型之后依次尝试的备用模型，跳过与主模型相同或已禁用的配置
+func (n *MessageService) fallbackCandidates(primary modelCandidate) []modelCandidate {
+	var candidates []modelCandidate
+	for _, item := range loadFallbackModels() {
+		if item.ModelName == "" || (item.CloudLLMId == primary.cloudLLM.ID && item.ModelName == primary.modelName) {
+			continue
+		}
+		cloudLLM, err := n.getCloudLLM(int(item.CloudLLMId))
+		if err != nil || !cloudLLM.Enabled {
+			continue
+		}
+		candidates = append(candidates, modelCandidate{cloudLLM: cloudLLM, modelName: item.ModelName})
+	}
+	return candidates
+}
+
+// streamWithFallback 请求主模型，失败且尚未输出任何内容时依次尝试备用模型，
+// 返回实际使用的模型和总请求次数
+func (n *MessageService) streamWithFallback(ctx context.Context, primary modelCandidate, req providers.ChatRequest, emitter *streamEmitter, assistantMessage *models.Message) (*streamResult, modelCandidate, int, error) {
+	policy := loadRetryPolicy()
+	candidates := append([]modelCandidate{primary}, n.fallbackCandidates(primary)...)
+
+	var result *streamResult
+	var err error
+	attempts := 0
+	for i, candidate := range candidates {
+		if i > 0 {
+			n.logger.Info("模型%s请求失败，切换到备用模型%s", candidates[i-1].modelName, candidate.modelName)
+			emitter.fallback(candidate.modelName, err)
+		}
+
+		var tries int
+		result, tries, err = n.streamWithRetry(ctx, candidate, req, policy, emitter, assistantMessage)
+		attempts += tries
+		if err == nil || result.streamed() || ctx.Err() != nil {
+			return result, candidate, attempts, err
+		}
+	}
+	return result, candidates[len(candidates)-1], attempts, err
+}
+
+// streamWithRetry 请求单个模型，尚未输出内容时对临时错误按退避策略重试，返回请求次数
+func (n *MessageService) streamWithRetry(ctx context.Context, candidate modelCandidate, req providers.ChatRequest, policy retryPolicy, emitter *streamEmitter, assistantMessage *models.Message) (*streamResult, int, error) {
+	empty := &streamResult{ChatResult: &providers.ChatResult{}}
+	cloudLLM, err := n.localRuntime.ensure(ctx, candidate.cloudLLM)
+	if err != nil {
+		return empty, 0, err
+	}
+	defer n.localRuntime.release(cloudLLM)
+	provider, err := providers.New(cloudLLM)
+	if err != nil {
+		return empty, 0, err
+	}
+	req.Model = candidate.modelName
+	req, err = shapeRequest(n.logger, candidate.cloudLLM.Provider, req)
+	if err != nil {
+		return empty, 0, err
+	}
+	req.ChatTemplate = localChatTemplate(cloudLLM, req.Model)
+
+	for attempt := 0; ; attempt++ {
+		release, err := acquireModel(ctx, cloudLLM, req, emitter.queued)
+		if err != nil {
+			return empty, attempt, err
+		}
+		result, err := n.streamChat(ctx, provider, req, emitter, assistantMessage)
+		release(int(result.Usage.TotalTokens))
+		if err == nil || result.streamed() || attempt >= policy.maxAttempts || !providers.IsRetryable(err) {
+			return result, attempt + 1, err
+		}
+
+		delay := policy.delay(attempt, err)
+		n.logger.Info("请求模型%s失败，%v后进行第%d次重试: %v", candidate.modelName, delay, attempt+1, err)
+		emitter.retry(attempt+1, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, attempt + 1, err
+		case <-timer.C:
+		}
+	}
+}
@@ -6,44 +6,55 @@ import (
 	"fmt"
 	"grove-studio/internal/database"
 	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
 	"grove-studio/internal/utils"
-	"unicode/utf8"
+	"sync"
+	"time"
 
-	"github.com/openai/openai-go/packages/ssestream"
-
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
-	"github.com/openai/openai-go/packages/param"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/google/uuid"
 )
 
 type MessageService struct {
 	ctx    context.Context
 	logger *utils.Logger
+
+	// 进行中的请求，key为请求ID，用于中止生成
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+
+	// 正在生成摘要的会话，避免同一会话重复生成
+	summarizing sync.Map
+
+	// localRuntime 使用本地模型时按需启动推理服务
+	localRuntime *LocalRuntimeService
 }
 
-func NewMessageService(ctx context.Context) *MessageService {
+func NewMessageService(ctx context.Context, localRuntime *LocalRuntimeService) *MessageService {
 	return &MessageService{
-		ctx:    ctx,
-		logger: utils.NewLogger(ctx),
+		ctx:          ctx,
+		logger:       utils.NewLogger(ctx),
+		cancels:      make(map[string]context.CancelFunc),
+		localRuntime: localRuntime,
 	}
 }
 
 type MessageRequestParams struct {
-	CloudLLMId          int     `json:"cloud_llm_id"`
-	ConversationId      int     `json:"conversation_id"`
-	Question            string  `json:"question"`
-	ModelName           string  `json:"model_name"`
-	Temperature         float64 `json:"temperature"`
-	MaxCompletionTokens uint32  `json:"max_completion_tokens"`
-	HistoryLength       uint32  `json:"history_length"`
+	RequestId           string   `json:"request_id"`
+	CloudLLMId          int      `json:"cloud_llm_id"`
+	ConversationId      int      `json:"conversation_id"`
+	AssistantId         int      `json:"assistant_id"` // 仅新建会话时生效
+	Question            string   `json:"question"`
+	ModelName           string   `json:"model_name"`
+	Temperature         *float64 `json:"temperature"` // 为空时使用助手或服务端的默认值
+	MaxCompletionTokens uint32   `json:"max_completion_tokens"`
+	HistoryLength       uint32   `json:"history_length"` // 最多发送的历史对话轮数，0表示只受token预算限制
 }
 
 type MessagePageResult struct {
 	Items []models.Message `json:"items"`
 }
 
-// GetList 获取消息列表
+// GetList 获取当前分支上的消息列表，minId为已加载的最早一条消息
 func (n *MessageService) GetList(conversationId, minId, size int) (*MessagePageResult, error) {
 	minId = max(minId, 0)
 	size = max(size, 10)
@@ -52,28 +63,40 @@ func (n *MessageService) GetList(conversationId, minId, size int) (*MessagePageR
 		return nil, errors.New("会话ID不能为空")
 	}
 
-	var items []models.Message
-	db := database.DB.
-		Model(&models.Message{}).
-		Where("conversation_id = ?", conversationId)
-	if minId > 0 {
-		db = db.Where("id < ?", minId)
+	conversation, err := getConversation(uint(conversationId))
+	if err != nil {
+		n.logger.Error("查询会话失败: %v", err)
+		return nil, err
 	}
-	if err := db.Order("id desc").Limit(size).Find(&items).Error; err != nil {
+
+	tree, err := loadMessageTree(conversation.ID)
+	if err != nil {
 		n.logger.Error("消息列表获取失败: %v", err)
 		return nil, err
 	}
 
-	// 查询后反转
-	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
-		items[i], items[j] = items[j], items[i]
+	// 从当前分支末端开始，分页时从已加载的最早消息的父消息继续向上
+	leafID := conversation.ActiveMessageID
+	if minId > 0 {
+		// 摘要不属于消息树，也不能作为分页位置
+		oldest, ok := tree.byID[uint(minId)]
+		if !ok {
+			msg := fmt.Sprintf("ID=%d的消息不在该会话中", minId)
+			n.logger.Error(msg)
+			return nil, errors.New(msg)
+		}
+		leafID = oldest.ParentID
+	}
+	path := tree.pathTo(leafID)
+	if len(path) > size {
+		path = path[len(path)-size:]
 	}
 
-	if len(items) == 0 {
+	if len(path) == 0 {
 		return &MessagePageResult{Items: []models.Message{}}, nil
 	}
 
-	return &MessagePageResult{Items: items}, nil
+	return &MessagePageResult{Items: path}, nil
 }
 
 // validateRequestParams 验证请求参数
@@ -87,30 +110,40 @@ func (n *MessageService) validateRequestParams(params MessageRequestParams) erro
 	if params.ModelName == "" {
 		return errors.New("模型名称不能为空")
 	}
-	if params.Temperature <= 0 {
-		return errors.New("温蒂不能为空")
+	if params.Temperature != nil && *params.Temperature < 0 {
+		return errors.New("温度不能为负数")
 	}
 	return nil
 }
 
+// getCloudLLM 获取模型信息
+func (n *MessageService) getCloudLLM(cloudLLMId int) (models.CloudLLMModel, error) {
+	var cloudLLM models.CloudLLMModel
+	if err := database.DB.Where("id = ?", cloudLLMId).First(&cloudLLM).Error; err != nil {
+		n.logger.Error("查找模型失败: %v", err)
+		return cloudLLM, err
+	}
+	if cloudLLM.ID == 0 {
+		msg := fmt.Sprintf("ID=%d的模型不存在", cloudLLMId)
+		n.logger.Error(msg)
+		return cloudLLM, errors.New(msg)
+	}
+	return cloudLLM, nil
+}
+
 // getModelAndConversation 获取模型和会话信息
 func (n *MessageService) getModelAndConversation(params MessageRequestParams) (models.CloudLLMModel, models.Conversation, []models.Message, error) {
-	var cloudLLM models.CloudLLMModel
 	var conversation models.Conversation
 	var historyMessages []models.Message
 
-	if err := database.DB.Where("id = ?", params.CloudLLMId).First(&cloudLLM).Error; err != nil {
-		n.logger.Error("查找模型失败: %v", err)
+	cloudLLM, err := n.getCloudLLM(params.CloudLLMId)
+	if err != nil {
 		return cloudLLM, conversation, historyMessages, err
 	}
-	if cloudLLM.ID == 0 {
-		msg := fmt.Sprintf("ID=%d的模型不存在", params.CloudLLMId)
-		n.logger.Error(msg)
-		return cloudLLM, conversation, historyMessages, errors.New(msg)
-	}
 
 	if params.ConversationId > 0 {
-		if err := database.DB.Where("id = ?", params.ConversationId).First(&conversation).Error; err != nil {
+		conversation, err = getConversation(uint(params.ConversationId))
+		if err != nil {
 			n.logger.Error("查询会话失败: %v", err)
 			return cloudLLM, conversation, historyMessages, err
 		}
@@ -119,19 +152,15 @@ func (n *MessageService) getModelAndConversation(params MessageRequestParams) (m
 			n.logger.Error(msg)
 			return cloudLLM, conversation, historyMessages, errors.New(msg)
 		}
-		if err := database.DB.Where("conversation_id = ?", params.ConversationId).Order("id desc").Limit(int(params.HistoryLength) * 2).Find(&historyMessages).Error; err != nil {
+		// 沿当前分支向上获取历史消息
+		historyMessages, err = historyBefore(conversation.ID, conversation.ActiveMessageID)
+		if err != nil {
 			return cloudLLM, conversation, historyMessages, err
 		}
 	} else {
-		// 限制标题长度，避免过长的问题
-		title := params.Question
-		// 使用utf8.RuneCountInString正确计算包含中文在内的字符数
-		if utf8.RuneCountInString(title) > 100 {
-			// 截取前100个Unicode字符
-			runes := []rune(title)
-			title = string(runes[:100]) + "..."
-		}
-		conversation.Title = title
+		// 先用问题作为临时标题，第一轮回答完成后再生成正式标题
+		conversation.Title = truncateTitle(params.Question)
+		conversation.AssistantID = uint(max(params.AssistantId, 0))
 		if err := database.DB.Create(&conversation).Error; err != nil {
 			return cloudLLM, conversation, historyMessages, err
 		}
@@ -140,117 +169,433 @@ func (n *MessageService) getModelAndConversation(params MessageRequestParams) (m
 	return cloudLLM, conversation, historyMessages, nil
 }
 
-// prepareMessages 准备消息历史
-func (n *MessageService) prepareMessages(historyMessages []models.Message, question string) []openai.ChatCompletionMessageParamUnion {
-	var messages []openai.ChatCompletionMessageParamUnion
-	for i := len(historyMessages) - 1; i >= 0; i-- {
-		msg := historyMessages[i]
+// resolveAssistant 查找会话绑定的助手（新会话使用请求中的助手），并补齐默认参数
+func (n *MessageService) resolveAssistant(params *MessageRequestParams) (*models.Assistant, error) {
+	assistantId := uint(max(params.AssistantId, 0))
+	if params.ConversationId > 0 {
+		var conversation models.Conversation
+		if err := database.DB.First(&conversation, params.ConversationId).Error; err != nil {
+			n.logger.Error("查询会话失败: %v", err)
+			return nil, err
+		}
+		assistantId = conversation.AssistantID
+	}
+	if assistantId == 0 {
+		return nil, nil
+	}
+
+	var assistant models.Assistant
+	if err := database.DB.Limit(1).Find(&assistant, assistantId).Error; err != nil {
+		n.logger.Error("查询助手失败: %v", err)
+		return nil, err
+	}
+	if assistant.ID == 0 {
+		n.logger.Warning("ID=%d的助手不存在，按未绑定助手处理", assistantId)
+		return nil, nil
+	}
+
+	applyAssistantDefaults(&assistant, params)
+	return &assistant, nil
+}
+
+// prepareMessages 准备消息历史：系统提示词和会话摘要放在最前面，
+// 摘要之后的历史消息从新到旧填充直到用完token预算
+func (n *MessageService) prepareMessages(gen *generation) ([]providers.ChatMessage, *ContextReport) {
+	params := gen.params
+	window, reserve, budget := promptBudget(gen.cloudLLM, params.ModelName, params.MaxCompletionTokens)
+	report := &ContextReport{
+		ContextWindow:    window,
+		CompletionTokens: reserve,
+		PromptTokens:     messageTokens(params.Question) + replyTokenOverhead,
+	}
+
+	var messages []providers.ChatMessage
+	if gen.assistant != nil && gen.assistant.SystemPrompt != "" {
+		messages = append(messages, providers.ChatMessage{Role: providers.RoleSystem, Content: gen.assistant.SystemPrompt})
+		report.PromptTokens += messageTokens(gen.assistant.SystemPrompt)
+	}
+	if gen.summary != nil {
+		messages = append(messages, providers.ChatMessage{Role: providers.RoleSystem, Content: summaryPrefix + gen.summary.Content})
+		report.PromptTokens += messageTokens(summaryPrefix + gen.summary.Content)
+		report.Summarized = true
+	}
+	if report.PromptTokens > budget {
+		report.Overflow = true
+	}
+
+	fitted, dropped := fitHistory(afterSummary(gen.historyMessages, gen.summary), budget, report)
+	gen.droppedMessages = dropped
+	fitted = capHistory(fitted, params.HistoryLength, report)
+
+	for i := len(fitted) - 1; i >= 0; i-- {
+		msg := fitted[i]
 		if msg.Role == "user" {
-			messages = append(messages, openai.UserMessage(msg.Content))
+			messages = append(messages, providers.ChatMessage{Role: providers.RoleUser, Content: msg.Content})
 		}
+		// 思考过程不回放，deepseek-reasoner等模型会拒绝带有reasoning_content的历史消息
 		if msg.Role == "assistant" {
-			messages = append(messages, openai.AssistantMessage(msg.Content))
+			messages = append(messages, providers.ChatMessage{Role: providers.RoleAssistant, Content: msg.Content})
 		}
 	}
-	messages = append(messages, openai.UserMessage(question))
-	return messages
+	messages = append(messages, providers.ChatMessage{Role: providers.RoleUser, Content: params.Question})
+	return messages, report
 }
 
-// handleStreamResponse 处理流式响应
-func (n *MessageService) handleStreamResponse(stream *ssestream.Stream[openai.ChatCompletionChunk]) (*openai.ChatCompletionAccumulator, error) {
-	acc := openai.ChatCompletionAccumulator{}
-	chunkIndex := 0
-
-	for stream.Next() {
-		chunk := stream.Current()
-		acc.AddChunk(chunk)
-		if _, ok := acc.JustFinishedContent(); ok {
-			runtime.EventsEmit(n.ctx, "stream-request-message", map[string]any{
-				"content": "",
-				"done":    true,
-				"index":   chunkIndex,
-			})
-		}
+// streamResult 流式响应的结果和耗时
+type streamResult struct {
+	*providers.ChatResult
+	startedAt    time.Time
+	firstTokenAt time.Time
+}
 
-		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
-			runtime.EventsEmit(n.ctx, "stream-request-message", map[string]any{
-				"content": chunk.Choices[0].Delta.Content,
-				"done":    false,
-				"index":   chunkIndex,
-			})
-			chunkIndex++
+// streamChat 通过提供商接口流式请求，把增量转发给前端，出错时仍返回已累积的内容
+func (n *MessageService) streamChat(ctx context.Context, provider providers.ChatProvider, req providers.ChatRequest, emitter *streamEmitter, assistantMessage *models.Message) (*streamResult, error) {
+	result := &streamResult{startedAt: time.Now()}
+
+	chatResult, err := provider.StreamChat(ctx, req, func(delta providers.StreamDelta) {
+		if delta.Content != "" || delta.Reasoning != "" {
+			// 收到首个token后标记为生成中
+			if result.firstTokenAt.IsZero() {
+				result.firstTokenAt = time.Now()
+				n.updateMessageStatus(assistantMessage, models.MessageStatusStreaming)
+			}
 		}
+		if delta.Reasoning != "" {
+			emitter.reasoning(delta.Reasoning)
+		}
+		if delta.Content != "" {
+			emitter.delta(delta.Content)
+		}
+		if delta.Usage != nil {
+			emitter.usage(StreamUsage(*delta.Usage))
+		}
+	})
+	if chatResult == nil {
+		chatResult = &providers.ChatResult{}
 	}
+	result.ChatResult = chatResult
 
-	if err := stream.Err(); err != nil {
+	if err != nil {
 		n.logger.Error("流式输出请求失败: %v", err)
-		return nil, err
+		return result, err
 	}
+	return result, nil
+}
+
+// updateMessageStatus 更新消息状态
+func (n *MessageService) updateMessageStatus(message *models.Message, status string) {
+	message.Status = status
+	if err := database.DB.Model(message).Update("status", status).Error; err != nil {
+		n.logger.Error("更新消息状态失败: %v", err)
+	}
+}
 
-	return &acc, nil
+// streamed 是否已经向前端输出过内容
+func (r *streamResult) streamed() bool {
+	return !r.firstTokenAt.IsZero()
+}
+
+// applyGenerationInfo 记录回答的生成信息
+func (r *streamResult) applyGenerationInfo(message *models.Message) {
+	message.Content = r.Content
+	message.ReasoningContent = r.Reasoning
+	message.FinishReason = r.FinishReason
+	message.PromptTokens = r.Usage.PromptTokens
+	message.CompletionTokens = r.Usage.CompletionTokens
+	message.LatencyMs = time.Since(r.startedAt).Milliseconds()
+	if !r.firstTokenAt.IsZero() {
+		message.FirstTokenMs = r.firstTokenAt.Sub(r.startedAt).Milliseconds()
+	}
+}
+
+// finishMessage 保存回答的最终内容、状态和生成信息
+func (n *MessageService) finishMessage(message *models.Message, status string, errMsg string) error {
+	message.Status = status
+	message.Error = errMsg
+	return database.DB.Save(message).Error
+}
+
+// registerRequest 登记进行中的请求
+func (n *MessageService) registerRequest(requestId string, cancel context.CancelFunc) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.cancels[requestId]; ok {
+		return fmt.Errorf("请求%s已在进行中", requestId)
+	}
+	n.cancels[requestId] = cancel
+	return nil
+}
+
+// unregisterRequest 移除已结束的请求
+func (n *MessageService) unregisterRequest(requestId string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if cancel, ok := n.cancels[requestId]; ok {
+		cancel()
+		delete(n.cancels, requestId)
+	}
 }
 
-// saveMessages 保存消息记录
-func (n *MessageService) saveMessages(conversationID uint, question string, response string) error {
-	// 保存用户消息
+// Stop 中止正在生成的请求
+func (n *MessageService) Stop(requestId string) error {
+	n.mu.Lock()
+	cancel, ok := n.cancels[requestId]
+	n.mu.Unlock()
+	if !ok {
+		return errors.New("请求不存在或已结束")
+	}
+	cancel()
+	return nil
+}
+
+// Request 给大模型发消息
+func (n *MessageService) Request(params MessageRequestParams) (int64, error) {
+	assistant, err := n.resolveAssistant(&params)
+	if err != nil {
+		return 0, err
+	}
+	if err := n.validateRequestParams(params); err != nil {
+		return 0, err
+	}
+
+	cloudLLM, conversation, historyMessages, err := n.getModelAndConversation(params)
+	if err != nil {
+		return 0, err
+	}
+
+	// 先保存用户消息，避免请求失败时丢失问题
 	userMessage := models.Message{
-		ConversationID: conversationID,
+		ConversationID: conversation.ID,
+		ParentID:       conversation.ActiveMessageID,
 		Role:           "user",
-		Content:        question,
+		Content:        params.Question,
+		Status:         models.MessageStatusComplete,
 	}
 	if err := database.DB.Create(&userMessage).Error; err != nil {
-		return err
+		n.logger.Error("保存用户消息失败: %v", err)
+		return 0, err
 	}
 
-	// 保存AI消息
 	assistantMessage := models.Message{
-		ConversationID: conversationID,
+		ConversationID: conversation.ID,
+		ParentID:       userMessage.ID,
 		Role:           "assistant",
-		Content:        response,
+		Status:         models.MessageStatusPending,
 	}
 	if err := database.DB.Create(&assistantMessage).Error; err != nil {
-		return err
+		n.logger.Error("保存AI消息失败: %v", err)
+		return 0, err
+	}
+	if err := setActiveMessage(conversation.ID, assistantMessage.ID); err != nil {
+		n.logger.Error("更新当前分支失败: %v", err)
+		return 0, err
 	}
 
-	return nil
+	totalTokens, err := n.generate(&generation{
+		params:           params,
+		cloudLLM:         cloudLLM,
+		assistant:        assistant,
+		historyMessages:  historyMessages,
+		assistantMessage: &assistantMessage,
+	})
+
+	// 新会话的第一轮回答完成后，在后台生成标题
+	if err == nil && params.ConversationId == 0 && assistantMessage.Status == models.MessageStatusComplete {
+		go n.generateTitle(conversation.ID, params.Question, assistantMessage.Content, params.CloudLLMId, params.ModelName)
+	}
+
+	return totalTokens, err
 }
 
-// Request 给大模型发消息
-func (n *MessageService) Request(params MessageRequestParams) (int64, error) {
+// Retry 原地重新生成失败或中断的一轮对话，可以换用其他模型；已完成的回答不会被覆盖，改为生成新的分支
+func (n *MessageService) Retry(messageId int, params MessageRequestParams) (int64, error) {
+	message, err := findMessage(messageId)
+	if err != nil {
+		n.logger.Error("查找消息失败: %v", err)
+		return 0, err
+	}
+
+	// 定位这一轮的用户问题和回答
+	var userMessage, assistantMessage models.Message
+	if message.Role == "user" {
+		userMessage = message
+		if err := database.DB.
+			Where("parent_id = ? AND role = ?", message.ID, "assistant").
+			Order("id desc").
+			Limit(1).
+			Find(&assistantMessage).Error; err != nil {
+			n.logger.Error("查找对应的AI消息失败: %v", err)
+			return 0, err
+		}
+		if assistantMessage.ID == 0 {
+			return 0, errors.New("该问题还没有回答，无法重试")
+		}
+	} else {
+		assistantMessage = message
+		if err := database.DB.First(&userMessage, message.ParentID).Error; err != nil {
+			n.logger.Error("查找对应的用户消息失败: %v", err)
+			return 0, err
+		}
+	}
+
+	params.ConversationId = int(userMessage.ConversationID)
+	params.Question = userMessage.Content
+	switch assistantMessage.Status {
+	case models.MessageStatusPending, models.MessageStatusStreaming:
+		return 0, errors.New("该消息正在生成中")
+	case models.MessageStatusError, models.MessageStatusInterrupted:
+		// 失败或中断的回答原地重新生成
+	default:
+		// 已完成的回答不覆盖，作为新分支重新生成
+		return n.branch(params, userMessage, false)
+	}
+
+	assistant, err := n.resolveAssistant(&params)
+	if err != nil {
+		return 0, err
+	}
 	if err := n.validateRequestParams(params); err != nil {
 		return 0, err
 	}
 
-	cloudLLM, conversation, historyMessages, err := n.getModelAndConversation(params)
+	cloudLLM, err := n.getCloudLLM(params.CloudLLMId)
 	if err != nil {
 		return 0, err
 	}
 
-	messages := n.prepareMessages(historyMessages, params.Question)
+	historyMessages, err := historyBefore(userMessage.ConversationID, userMessage.ParentID)
+	if err != nil {
+		n.logger.Error("加载历史消息失败: %v", err)
+		return 0, err
+	}
 
-	openaiParams := openai.ChatCompletionNewParams{
-		Messages: messages,
-		Model:    params.ModelName,
-		StreamOptions: openai.ChatCompletionStreamOptionsParam{
-			IncludeUsage: param.NewOpt(true),
-		},
-		Temperature: param.NewOpt(params.Temperature),
+	// 复用原回答记录
+	assistantMessage.Content = ""
+	assistantMessage.ReasoningContent = ""
+	assistantMessage.Status = models.MessageStatusPending
+	assistantMessage.Error = ""
+	if err := database.DB.Save(&assistantMessage).Error; err != nil {
+		n.logger.Error("保存AI消息失败: %v", err)
+		return 0, err
 	}
-	if params.MaxCompletionTokens > 0 {
-		openaiParams.MaxCompletionTokens = param.NewOpt(int64(params.MaxCompletionTokens))
+	if err := setActiveMessage(assistantMessage.ConversationID, assistantMessage.ID); err != nil {
+		n.logger.Error("切换分支失败: %v", err)
+		return 0, err
 	}
 
-	client := openai.NewClient(option.WithAPIKey(cloudLLM.ApiKey), option.WithBaseURL(cloudLLM.EndPoint))
-	stream := client.Chat.Completions.NewStreaming(n.ctx, openaiParams)
+	return n.generate(&generation{
+		params:           params,
+		cloudLLM:         cloudLLM,
+		assistant:        assistant,
+		historyMessages:  historyMessages,
+		assistantMessage: &assistantMessage,
+	})
+}
+
+// generation 一次生成所需的全部信息
+type generation struct {
+	params           MessageRequestParams
+	cloudLLM         models.CloudLLMModel
+	assistant        *models.Assistant // 会话绑定的助手，可能为空
+	historyMessages  []models.Message  // 按时间倒序
+	assistantMessage *models.Message   // 写入回答的消息记录
+
+	summary         *models.Message  // 当前路径上最新的会话摘要
+	droppedMessages []models.Message // 超出上下文预算、需要合并进摘要的消息
+}
+
+// generate 请求大模型并把结果写入回答记录
+func (n *MessageService) generate(gen *generation) (int64, error) {
+	params := gen.params
+	cloudLLM := gen.cloudLLM
+	assistantMessage := gen.assistantMessage
+	if params.RequestId == "" {
+		params.RequestId = uuid.NewString()
+	}
 
-	acc, err := n.handleStreamResponse(stream)
+	summary, err := latestSummary(assistantMessage.ConversationID, gen.historyMessages)
 	if err != nil {
+		n.logger.Error("查询会话摘要失败: %v", err)
+	}
+	gen.summary = summary
+
+	messages, contextReport := n.prepareMessages(gen)
+	if contextReport.DroppedMessages > 0 {
+		n.logger.Info("上下文超出预算，%d条历史消息未发送，将合并到会话摘要", contextReport.DroppedMessages)
+	}
+
+	chatRequest := providers.ChatRequest{
+		Model:       params.ModelName,
+		Messages:    messages,
+		Temperature: params.Temperature,
+		MaxTokens:   int64(params.MaxCompletionTokens),
+	}
+
+	// 记录生成所用的模型和参数
+	assistantMessage.CloudLLMModelID = cloudLLM.ID
+	assistantMessage.ModelName = params.ModelName
+	assistantMessage.Temperature = params.Temperature
+	assistantMessage.MaxTokens = params.MaxCompletionTokens
+	assistantMessage.FallbackFrom = ""
+
+	emitter := newStreamEmitter(n.ctx, params.RequestId, assistantMessage.ConversationID)
+
+	// 每个请求使用独立的可取消上下文
+	ctx, cancel := context.WithCancel(n.ctx)
+	if err := n.registerRequest(params.RequestId, cancel); err != nil {
+		cancel()
+		_ = n.finishMessage(assistantMessage, models.MessageStatusError, err.Error())
 		return 0, err
 	}
+	defer n.unregisterRequest(params.RequestId)
+
+	emitter.start()
+	emitter.context(contextReport)
+
+	startedAt := time.Now()
+	primary := modelCandidate{cloudLLM: cloudLLM, modelName: params.ModelName}
+	result, used, attempts, streamErr := n.streamWithFallback(ctx, primary, chatRequest, emitter, assistantMessage)
+	// 耗时从第一次请求开始计算，包含重试的等待时间
+	result.startedAt = startedAt
+	result.applyGenerationInfo(assistantMessage)
+
+	// 记录实际生成回答的模型
+	assistantMessage.Attempts = attempts
+	if used.cloudLLM.ID != primary.cloudLLM.ID || used.modelName != primary.modelName {
+		assistantMessage.FallbackFrom = params.ModelName
+		assistantMessage.CloudLLMModelID = used.cloudLLM.ID
+		assistantMessage.ModelName = used.modelName
+	}
+
+	status := models.MessageStatusComplete
+	errMsg := ""
+	if streamErr != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			// 用户主动中止，保留已生成的部分回答
+			n.logger.Info("请求%s已被中止", params.RequestId)
+			status = models.MessageStatusInterrupted
+			streamErr = nil
+		} else {
+			status = models.MessageStatusError
+			errMsg = streamErr.Error()
+		}
+	}
 
-	if err := n.saveMessages(conversation.ID, params.Question, acc.Choices[0].Message.Content); err != nil {
+	if err := n.finishMessage(assistantMessage, status, errMsg); err != nil {
+		n.logger.Error("保存AI消息失败: %v", err)
+		emitter.error(err)
 		return 0, err
 	}
+	if streamErr != nil {
+		emitter.error(streamErr)
+		return 0, streamErr
+	}
+	emitter.done(status, assistantMessage.ID)
+
+	// 在后台把超出预算的旧消息合并进摘要，下次请求时使用
+	if len(gen.droppedMessages) > 0 {
+		go n.refreshSummary(assistantMessage.ConversationID, gen.summary, gen.droppedMessages, params.CloudLLMId, params.ModelName)
+	}
 
-	return acc.Usage.TotalTokens, nil
+	return result.Usage.TotalTokens, nil
 }
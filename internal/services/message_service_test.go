@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"testing"
+)
+
+// newTestConversation 创建一个有 n 条消息的单分支会话，返回按时间正序的消息ID
+func newTestConversation(t *testing.T, n int) (models.Conversation, []uint) {
+	t.Helper()
+	conversation := models.Conversation{Title: "测试"}
+	if err := database.DB.Create(&conversation).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	var parentID uint
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		message := models.Message{ConversationID: conversation.ID, ParentID: parentID, Role: role, Status: models.MessageStatusComplete}
+		if err := database.DB.Create(&message).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, message.ID)
+		parentID = message.ID
+	}
+	conversation.ActiveMessageID = parentID
+	if err := database.DB.Save(&conversation).Error; err != nil {
+		t.Fatal(err)
+	}
+	return conversation, ids
+}
+
+func TestMessageGetList(t *testing.T) {
+	newTestDB(t)
+	service := NewMessageService(context.Background(), nil)
+	conversation, ids := newTestConversation(t, 12)
+	summary := models.Message{ConversationID: conversation.ID, ParentID: ids[5], Role: models.MessageRoleSummary}
+	if err := database.DB.Create(&summary).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		minId     int
+		want      []uint
+		wantError bool
+	}{
+		{name: "第一页", minId: 0, want: ids[2:]},
+		{name: "继续加载更早的消息", minId: int(ids[2]), want: ids[:2]},
+		{name: "已加载到第一条消息", minId: int(ids[0]), want: nil},
+		{name: "摘要不能作为分页位置", minId: int(summary.ID), wantError: true},
+		{name: "消息不存在", minId: 9999, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.GetList(int(conversation.ID), tt.minId, 10)
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("期望返回错误，得到 %d 条消息", len(result.Items))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetList 返回错误: %v", err)
+			}
+			var got []uint
+			for _, item := range result.Items {
+				got = append(got, item.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("消息ID = %v，期望 %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("消息ID = %v，期望 %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"strings"
+	"time"
+)
+
+const (
+	// summaryTimeout 生成摘要的超时时间
+	summaryTimeout = 2 * time.Minute
+	// summaryMaxTokens 摘要的最大长度
+	summaryMaxTokens = 1024
+	// summaryTemperature 摘要需要忠实于原文，使用较低的温度
+	summaryTemperature = 0.3
+
+	summaryInstruction = "你负责压缩一段对话的历史记录。请把已有摘要和新增的对话合并成一份新的摘要，" +
+		"保留关键事实、结论、用户的偏好和要求，以及尚未解决的问题，省略寒暄和重复内容。" +
+		"直接输出摘要正文，使用对话所用的语言。"
+	summaryPrefix = "以下是此前对话的摘要，供你参考：\n"
+)
+
+// latestSummary 查找覆盖到当前路径上的最新摘要，historyMessages 按时间倒序
+func latestSummary(conversationID uint, historyMessages []models.Message) (*models.Message, error) {
+	if len(historyMessages) == 0 {
+		return nil, nil
+	}
+
+	var summaries []models.Message
+	if err := database.DB.
+		Where("conversation_id = ? AND role = ?", conversationID, models.MessageRoleSummary).
+		Order("id desc").
+		Find(&summaries).Error; err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, nil
+	}
+
+	// 摘要挂在它覆盖的最后一条消息下，只有该消息在当前路径上时才有效
+	tree, err := loadMessageTree(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	onPath := make(map[uint]bool)
+	for _, msg := range tree.pathTo(historyMessages[0].ID) {
+		onPath[msg.ID] = true
+	}
+	for _, summary := range summaries {
+		if onPath[summary.ParentID] {
+			return &summary, nil
+		}
+	}
+	return nil, nil
+}
+
+// afterSummary 去掉已被摘要覆盖的历史消息
+func afterSummary(historyMessages []models.Message, summary *models.Message) []models.Message {
+	if summary == nil {
+		return historyMessages
+	}
+	var items []models.Message
+	for _, msg := range historyMessages {
+		// 路径上的消息ID递增，比摘要位置新的消息才需要保留
+		if msg.ID > summary.ParentID {
+			items = append(items, msg)
+		}
+	}
+	return items
+}
+
+// refreshSummary 把超出上下文预算的旧消息合并进摘要，dropped 按时间倒序
+func (n *MessageService) refreshSummary(conversationID uint, previous *models.Message, dropped []models.Message, cloudLLMId int, modelName string) {
+	if len(dropped) == 0 {
+		return
+	}
+	if _, running := n.summarizing.LoadOrStore(conversationID, true); running {
+		return
+	}
+	defer n.summarizing.Delete(conversationID)
+
+	cloudLLMId, modelName = auxiliaryModel(AuxiliaryTaskSummary, cloudLLMId, modelName)
+	cloudLLM, err := n.getCloudLLM(cloudLLMId)
+	if err != nil {
+		return
+	}
+
+	// 从最旧的消息开始，在摘要模型的预算内尽量多地合并
+	_, _, budget := promptBudget(cloudLLM, modelName, summaryMaxTokens)
+	used := messageTokens(summaryInstruction)
+	var builder strings.Builder
+	if previous != nil {
+		builder.WriteString("已有摘要：\n" + previous.Content + "\n\n")
+		used += messageTokens(previous.Content)
+	}
+	builder.WriteString("新增对话：\n")
+
+	var lastID uint
+	for i := len(dropped) - 1; i >= 0; i-- {
+		msg := dropped[i]
+		line := fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
+		tokens := messageTokens(line)
+		if used+tokens > budget && lastID != 0 {
+			break
+		}
+		used += tokens
+		builder.WriteString(line)
+		lastID = msg.ID
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, summaryTimeout)
+	defer cancel()
+
+	content, err := n.completeText(ctx, cloudLLM, providers.ChatRequest{
+		Model: modelName,
+		Messages: []providers.ChatMessage{
+			{Role: providers.RoleSystem, Content: summaryInstruction},
+			{Role: providers.RoleUser, Content: builder.String()},
+		},
+		Temperature: providers.Float(summaryTemperature),
+		MaxTokens:   summaryMaxTokens,
+	})
+	if err != nil {
+		n.logger.Error("生成会话摘要失败: %v", err)
+		return
+	}
+
+	summary := models.Message{
+		ConversationID:  conversationID,
+		ParentID:        lastID,
+		Role:            models.MessageRoleSummary,
+		Content:         content,
+		Status:          models.MessageStatusComplete,
+		CloudLLMModelID: cloudLLM.ID,
+		ModelName:       modelName,
+	}
+	if err := database.DB.Create(&summary).Error; err != nil {
+		n.logger.Error("保存会话摘要失败: %v", err)
+		return
+	}
+	n.logger.Info("会话%d的摘要已更新至消息%d", conversationID, lastID)
+}
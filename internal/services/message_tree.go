@@ -0,0 +1,274 @@
+package services
+
+import (
+	"errors"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// messageTree 一个会话内的消息树
+type messageTree struct {
+	byID     map[uint]models.Message
+	children map[uint][]uint
+}
+
+// loadMessageTree 加载会话内的全部消息并建立父子关系
+func loadMessageTree(conversationID uint) (*messageTree, error) {
+	var items []models.Message
+	if err := database.DB.
+		Where("conversation_id = ? AND role <> ?", conversationID, models.MessageRoleSummary).
+		Order("id asc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &messageTree{
+		byID:     make(map[uint]models.Message, len(items)),
+		children: make(map[uint][]uint),
+	}
+	for _, item := range items {
+		tree.byID[item.ID] = item
+		tree.children[item.ParentID] = append(tree.children[item.ParentID], item.ID)
+	}
+	return tree, nil
+}
+
+// pathTo 从根消息到指定消息的路径，按时间正序
+func (t *messageTree) pathTo(leafID uint) []models.Message {
+	var path []models.Message
+	for id := leafID; id != 0; {
+		msg, ok := t.byID[id]
+		if !ok {
+			break
+		}
+		msg.SiblingIDs = t.children[msg.ParentID]
+		path = append(path, msg)
+		id = msg.ParentID
+	}
+
+	// 反转为正序
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// latestLeaf 沿最新的子消息向下找到分支末端
+func (t *messageTree) latestLeaf(id uint) uint {
+	for {
+		children := t.children[id]
+		if len(children) == 0 {
+			return id
+		}
+		id = children[len(children)-1]
+	}
+}
+
+// historyBefore 获取指定父消息之前（含）的整条分支，按时间倒序；
+// 发送多少由 fitHistory 按token预算决定，超出的部分合并到会话摘要
+func historyBefore(conversationID, parentID uint) ([]models.Message, error) {
+	tree, err := loadMessageTree(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	path := tree.pathTo(parentID)
+	slices.Reverse(path)
+	return path, nil
+}
+
+// setActiveMessage 更新会话当前分支的末端消息
+func setActiveMessage(conversationID, messageID uint) error {
+	return database.DB.Model(&models.Conversation{}).
+		Where("id = ?", conversationID).
+		Update("active_message_id", messageID).Error
+}
+
+// backfillMessageTree 为旧版的平铺消息补齐父子关系
+func backfillMessageTree(conversation *models.Conversation) error {
+	if conversation.ActiveMessageID != 0 {
+		return nil
+	}
+
+	var items []models.Message
+	if err := database.DB.Where("conversation_id = ?", conversation.ID).Order("id asc").Find(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var parentID uint
+		for _, item := range items {
+			if item.ParentID == 0 && parentID != 0 {
+				if err := tx.Model(&models.Message{}).Where("id = ?", item.ID).Update("parent_id", parentID).Error; err != nil {
+					return err
+				}
+			}
+			parentID = item.ID
+		}
+		conversation.ActiveMessageID = parentID
+		return tx.Model(conversation).Update("active_message_id", parentID).Error
+	})
+}
+
+// getConversation 获取会话，并确保消息树已建立
+func getConversation(conversationID uint) (models.Conversation, error) {
+	var conversation models.Conversation
+	if err := database.DB.First(&conversation, conversationID).Error; err != nil {
+		return conversation, err
+	}
+	if err := backfillMessageTree(&conversation); err != nil {
+		return conversation, err
+	}
+	return conversation, nil
+}
+
+// findMessage 获取消息，旧版会话会先补齐父子关系
+func findMessage(messageId int) (models.Message, error) {
+	var message models.Message
+	if err := database.DB.First(&message, messageId).Error; err != nil {
+		return message, err
+	}
+	if message.ParentID != 0 {
+		return message, nil
+	}
+	if _, err := getConversation(message.ConversationID); err != nil {
+		return message, err
+	}
+	err := database.DB.First(&message, messageId).Error
+	return message, err
+}
+
+// SwitchBranch 切换到指定消息所在的分支，并沿最新的回复定位到分支末端
+func (n *MessageService) SwitchBranch(messageId int) error {
+	message, err := findMessage(messageId)
+	if err != nil {
+		n.logger.Error("查找消息失败: %v", err)
+		return err
+	}
+
+	tree, err := loadMessageTree(message.ConversationID)
+	if err != nil {
+		n.logger.Error("加载消息树失败: %v", err)
+		return err
+	}
+
+	return setActiveMessage(message.ConversationID, tree.latestLeaf(message.ID))
+}
+
+// Siblings 获取同一父消息下的兄弟消息
+func (n *MessageService) Siblings(messageId int) ([]models.Message, error) {
+	message, err := findMessage(messageId)
+	if err != nil {
+		n.logger.Error("查找消息失败: %v", err)
+		return nil, err
+	}
+
+	var items []models.Message
+	if err := database.DB.
+		Where("conversation_id = ? AND parent_id = ? AND role <> ?", message.ConversationID, message.ParentID, models.MessageRoleSummary).
+		Order("id asc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Regenerate 在同一问题下生成新的回答分支
+func (n *MessageService) Regenerate(messageId int, params MessageRequestParams) (int64, error) {
+	message, err := findMessage(messageId)
+	if err != nil {
+		n.logger.Error("查找消息失败: %v", err)
+		return 0, err
+	}
+
+	userMessage := message
+	if message.Role != "user" {
+		if err := database.DB.First(&userMessage, message.ParentID).Error; err != nil {
+			n.logger.Error("查找对应的用户消息失败: %v", err)
+			return 0, err
+		}
+	}
+
+	params.ConversationId = int(userMessage.ConversationID)
+	params.Question = userMessage.Content
+	return n.branch(params, userMessage, false)
+}
+
+// Edit 修改之前的问题并重新发送，作为原问题的兄弟分支
+func (n *MessageService) Edit(messageId int, params MessageRequestParams) (int64, error) {
+	message, err := findMessage(messageId)
+	if err != nil {
+		n.logger.Error("查找消息失败: %v", err)
+		return 0, err
+	}
+	if message.Role != "user" {
+		return 0, errors.New("只能编辑用户消息")
+	}
+
+	params.ConversationId = int(message.ConversationID)
+	return n.branch(params, message, true)
+}
+
+// branch 创建新的分支并请求大模型，newQuestion为true时同时新建用户消息
+func (n *MessageService) branch(params MessageRequestParams, userMessage models.Message, newQuestion bool) (int64, error) {
+	assistant, err := n.resolveAssistant(&params)
+	if err != nil {
+		return 0, err
+	}
+	if err := n.validateRequestParams(params); err != nil {
+		return 0, err
+	}
+
+	cloudLLM, err := n.getCloudLLM(params.CloudLLMId)
+	if err != nil {
+		return 0, err
+	}
+
+	historyMessages, err := historyBefore(userMessage.ConversationID, userMessage.ParentID)
+	if err != nil {
+		n.logger.Error("加载历史消息失败: %v", err)
+		return 0, err
+	}
+
+	if newQuestion {
+		userMessage = models.Message{
+			ConversationID: userMessage.ConversationID,
+			ParentID:       userMessage.ParentID,
+			Role:           "user",
+			Content:        params.Question,
+			Status:         models.MessageStatusComplete,
+		}
+		if err := database.DB.Create(&userMessage).Error; err != nil {
+			n.logger.Error("保存用户消息失败: %v", err)
+			return 0, err
+		}
+	}
+
+	assistantMessage := models.Message{
+		ConversationID: userMessage.ConversationID,
+		ParentID:       userMessage.ID,
+		Role:           "assistant",
+		Status:         models.MessageStatusPending,
+	}
+	if err := database.DB.Create(&assistantMessage).Error; err != nil {
+		n.logger.Error("保存AI消息失败: %v", err)
+		return 0, err
+	}
+	if err := setActiveMessage(assistantMessage.ConversationID, assistantMessage.ID); err != nil {
+		n.logger.Error("切换分支失败: %v", err)
+		return 0, err
+	}
+
+	return n.generate(&generation{
+		params:           params,
+		cloudLLM:         cloudLLM,
+		assistant:        assistant,
+		historyMessages:  historyMessages,
+		assistantMessage: &assistantMessage,
+	})
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"grove-studio/internal/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// refreshModelsTimeout 刷新模型列表的超时时间
+const refreshModelsTimeout = 30 * time.Second
+
+// embeddingKeywords 向量模型名中常见的关键词
+var embeddingKeywords = []string{"embed", "bge-", "e5-", "gte-"}
+
+// ProviderModelService 提供商模型列表服务
+type ProviderModelService struct {
+	ctx    context.Context
+	logger *utils.Logger
+
+	// localRuntime 刷新本地模型配置时按需启动推理服务
+	localRuntime *LocalRuntimeService
+}
+
+// NewProviderModelService 创建提供商模型列表服务
+func NewProviderModelService(ctx context.Context, localRuntime *LocalRuntimeService) *ProviderModelService {
+	return &ProviderModelService{
+		ctx:          ctx,
+		logger:       utils.NewLogger(ctx),
+		localRuntime: localRuntime,
+	}
+}
+
+// Presets 获取内置的提供商预设
+func (p *ProviderModelService) Presets() []providers.Preset {
+	return providers.Presets()
+}
+
+// GetList 获取已保存的模型列表
+func (p *ProviderModelService) GetList(cloudLLMId uint) ([]models.ProviderModel, error) {
+	var items []models.ProviderModel
+	if err := database.DB.Where("cloud_llm_model_id = ?", cloudLLMId).Order("model_id asc").Find(&items).Error; err != nil {
+		p.logger.Error("获取模型列表失败: %v", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// Capabilities 获取模型的能力，服务端返回过上下文长度时以服务端为准
+func (p *ProviderModelService) Capabilities(cloudLLMId uint, modelName string) (*providers.Capabilities, error) {
+	var cloudLLM models.CloudLLMModel
+	if err := database.DB.First(&cloudLLM, cloudLLMId).Error; err != nil {
+		p.logger.Error("获取云端模型详情失败: %v", err)
+		return nil, err
+	}
+
+	caps := providers.CapabilitiesFor(cloudLLM.Provider, modelName)
+	caps.ContextWindow = contextWindowFor(cloudLLM, modelName)
+	return &caps, nil
+}
+
+// Refresh 从提供商接口获取最新的模型列表并保存，已下线的模型会被删除
+func (p *ProviderModelService) Refresh(cloudLLMId uint) ([]models.ProviderModel, error) {
+	var cloudLLM models.CloudLLMModel
+	if err := database.DB.First(&cloudLLM, cloudLLMId).Error; err != nil {
+		p.logger.Error("获取云端模型详情失败: %v", err)
+		return nil, err
+	}
+	cloudLLM, err := p.localRuntime.ensure(p.ctx, cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+	defer p.localRuntime.release(cloudLLM)
+	provider, err := providers.New(cloudLLM)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, refreshModelsTimeout)
+	defer cancel()
+	infos, err := provider.ListModels(ctx)
+	if err != nil {
+		p.logger.Error("获取提供商模型列表失败: %v", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.ProviderModel
+		if err := tx.Where("cloud_llm_model_id = ?", cloudLLMId).Find(&existing).Error; err != nil {
+			return err
+		}
+		byModelID := make(map[string]models.ProviderModel, len(existing))
+		for _, item := range existing {
+			byModelID[item.ModelID] = item
+		}
+
+		seen := make(map[string]bool, len(infos))
+		for _, info := range infos {
+			if info.ID == "" || seen[info.ID] {
+				continue
+			}
+			seen[info.ID] = true
+
+			// 保留首次发现的时间
+			item := byModelID[info.ID]
+			item.CloudLLMModelID = cloudLLMId
+			item.ModelID = info.ID
+			item.OwnedBy = info.OwnedBy
+			item.ReleasedAt = info.Created
+			item.RefreshedAt = now
+			applyModelHints(&item, cloudLLM.Provider, info)
+			if err := tx.Save(&item).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, item := range existing {
+			if !seen[item.ModelID] {
+				if err := tx.Delete(&item).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		p.logger.Error("保存模型列表失败: %v", err)
+		return nil, err
+	}
+
+	return p.GetList(cloudLLMId)
+}
+
+// applyModelHints 根据服务端信息和模型能力推断结果填充模型信息
+func applyModelHints(item *models.ProviderModel, provider string, info providers.ModelInfo) {
+	caps := providers.CapabilitiesFor(provider, info.ID)
+	item.ContextWindow = info.ContextWindow
+	if item.ContextWindow == 0 {
+		item.ContextWindow = caps.ContextWindow
+	}
+	item.Embedding = containsAny(strings.ToLower(info.ID), embeddingKeywords)
+	chat := !item.Embedding
+	item.Vision = chat && caps.Vision
+	item.Reasoning = chat && caps.Reasoning
+	item.Tools = chat && caps.Tools
+	item.JSONMode = chat && caps.JSONMode
+}
+
+// containsAny 文本是否包含任意一个关键词
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"grove-studio/internal/utils"
+	"slices"
+	"sync"
+	"time"
+)
+
+// rateWindow 请求数和token数的统计窗口
+const rateWindow = time.Minute
+
+// rateLimits 模型的限流设置，0表示不限制
+type rateLimits struct {
+	requestsPerMinute int
+	tokensPerMinute   int
+	maxConcurrency    int
+}
+
+func limitsOf(cloudLLM models.CloudLLMModel) rateLimits {
+	return rateLimits{
+		requestsPerMinute: cloudLLM.RequestsPerMinute,
+		tokensPerMinute:   cloudLLM.TokensPerMinute,
+		maxConcurrency:    cloudLLM.MaxConcurrency,
+	}
+}
+
+func (l rateLimits) unlimited() bool {
+	return l.requestsPerMinute <= 0 && l.tokensPerMinute <= 0 && l.maxConcurrency <= 0
+}
+
+// rateRecord 窗口内的一次请求
+type rateRecord struct {
+	at     time.Time
+	tokens int
+}
+
+// rateTicket 排队中的一个请求
+type rateTicket struct {
+	tokens int
+}
+
+// modelLimiter 单个模型配置的限流器，等待的请求按先后顺序排队
+type modelLimiter struct {
+	mu      sync.Mutex
+	limits  rateLimits
+	active  int
+	records []*rateRecord
+	queue   []*rateTicket
+	// changed 状态变化时关闭并替换，用于唤醒等待的请求
+	changed chan struct{}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[uint]*modelLimiter)
+)
+
+// limiterFor 获取模型配置对应的限流器，并应用最新的限流设置
+func limiterFor(cloudLLM models.CloudLLMModel) *modelLimiter {
+	limitersMu.Lock()
+	limiter, ok := limiters[cloudLLM.ID]
+	if !ok {
+		limiter = &modelLimiter{changed: make(chan struct{})}
+		limiters[cloudLLM.ID] = limiter
+	}
+	limitersMu.Unlock()
+
+	limiter.mu.Lock()
+	if limiter.limits != limitsOf(cloudLLM) {
+		limiter.limits = limitsOf(cloudLLM)
+		limiter.notify()
+	}
+	limiter.mu.Unlock()
+	return limiter
+}
+
+// notify 唤醒所有等待的请求，调用方需持有锁
+func (l *modelLimiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// prune 移除统计窗口之外的记录，调用方需持有锁
+func (l *modelLimiter) prune(now time.Time) {
+	l.records = slices.DeleteFunc(l.records, func(record *rateRecord) bool {
+		return now.Sub(record.at) >= rateWindow
+	})
+}
+
+// wait 当前请求还需要等待的时间，0表示可以立即发送，-1表示需要等其他请求结束，调用方需持有锁
+func (l *modelLimiter) wait(now time.Time, tokens int) time.Duration {
+	if l.limits.maxConcurrency > 0 && l.active >= l.limits.maxConcurrency {
+		return -1
+	}
+
+	var wait time.Duration
+	if l.limits.requestsPerMinute > 0 && len(l.records) >= l.limits.requestsPerMinute {
+		oldest := l.records[len(l.records)-l.limits.requestsPerMinute]
+		wait = max(wait, rateWindow-now.Sub(oldest.at))
+	}
+	if l.limits.tokensPerMinute > 0 && len(l.records) > 0 {
+		// 从最早的记录开始释放，直到剩余额度足够；单个请求超过额度时等窗口清空后放行
+		used := 0
+		for _, record := range l.records {
+			used += record.tokens
+		}
+		for _, record := range l.records {
+			if used+tokens <= l.limits.tokensPerMinute {
+				break
+			}
+			used -= record.tokens
+			wait = max(wait, rateWindow-now.Sub(record.at))
+		}
+	}
+	return wait
+}
+
+// acquire 排队等待发送请求的许可，position 在排队位置变化时回调（从1开始）；
+// 返回的 release 需要在请求结束后调用，传入实际消耗的token数
+func (l *modelLimiter) acquire(ctx context.Context, tokens int, position func(int)) (func(int), error) {
+	ticket := &rateTicket{tokens: tokens}
+	l.mu.Lock()
+	l.queue = append(l.queue, ticket)
+
+	lastPosition := 0
+	for {
+		now := time.Now()
+		l.prune(now)
+
+		index := slices.Index(l.queue, ticket)
+		wait := time.Duration(-1)
+		if index == 0 {
+			wait = l.wait(now, tokens)
+		}
+		if wait == 0 {
+			break
+		}
+
+		if position != nil && index+1 != lastPosition {
+			lastPosition = index + 1
+			position(lastPosition)
+		}
+
+		changed := l.changed
+		l.mu.Unlock()
+
+		var timer <-chan time.Time
+		if wait > 0 {
+			timer = time.After(wait)
+		}
+		select {
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.queue = slices.DeleteFunc(l.queue, func(item *rateTicket) bool { return item == ticket })
+			l.notify()
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		case <-changed:
+		case <-timer:
+		}
+		l.mu.Lock()
+	}
+
+	l.queue = l.queue[1:]
+	l.active++
+	record := &rateRecord{at: time.Now(), tokens: ticket.tokens}
+	l.records = append(l.records, record)
+	l.notify()
+	l.mu.Unlock()
+
+	var once sync.Once
+	release := func(usedTokens int) {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.active--
+			if usedTokens > 0 {
+				record.tokens = usedTokens
+			}
+			l.notify()
+		})
+	}
+	return release, nil
+}
+
+// acquireModel 为一次请求获取模型的发送许可，未设置限流时直接放行
+func acquireModel(ctx context.Context, cloudLLM models.CloudLLMModel, req providers.ChatRequest, position func(int)) (func(int), error) {
+	if limitsOf(cloudLLM).unlimited() {
+		return func(int) {}, nil
+	}
+	return limiterFor(cloudLLM).acquire(ctx, estimateRequestTokens(req), position)
+}
+
+// estimateRequestTokens 估算请求会消耗的token数，包括输入和预留的输出
+func estimateRequestTokens(req providers.ChatRequest) int {
+	tokens := replyTokenOverhead
+	for _, message := range req.Messages {
+		tokens += utils.EstimateTokens(message.Content) + messageTokenOverhead
+	}
+	if req.MaxTokens > 0 {
+		tokens += int(req.MaxTokens)
+	} else {
+		tokens += defaultCompletionReserve
+	}
+	return tokens
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// 重试和备用模型相关的设置项
+const (
+	// SettingRetryMaxAttempts 临时错误的最大重试次数，0表示不重试
+	SettingRetryMaxAttempts = "retry_max_attempts"
+	// SettingRetryBaseDelayMs 第一次重试前的等待时间（毫秒），之后每次翻倍
+	SettingRetryBaseDelayMs = "retry_base_delay_ms"
+	// SettingFallbackModels 主模型失败时依次尝试的备用模型，JSON数组
+	SettingFallbackModels = "fallback_models"
+
+	defaultRetryMaxAttempts = 2
+	defaultRetryBaseDelay   = time.Second
+	// maxRetryAttempts 重试次数的上限，设置更大时也以此为准
+	maxRetryAttempts = 10
+	// maxRetryDelay 单次重试的最长等待时间，Retry-After 更长时也以此为准
+	maxRetryDelay = 30 * time.Second
+)
+
+// FallbackModel 备用模型
+type FallbackModel struct {
+	CloudLLMId uint   `json:"cloud_llm_id"`
+	ModelName  string `json:"model_name"`
+}
+
+// retryPolicy 临时错误的重试策略
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// settingValues 批量读取设置项，不存在的设置项不会出现在结果中
+func settingValues(keys ...string) map[string]string {
+	var items []models.Setting
+	database.DB.Where("key IN ?", keys).Find(&items)
+
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		values[item.Key] = item.Value
+	}
+	return values
+}
+
+// loadRetryPolicy 读取重试设置，未设置或设置有误时使用默认值
+func loadRetryPolicy() retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: defaultRetryMaxAttempts,
+		baseDelay:   defaultRetryBaseDelay,
+	}
+
+	values := settingValues(SettingRetryMaxAttempts, SettingRetryBaseDelayMs)
+	if value, err := strconv.Atoi(values[SettingRetryMaxAttempts]); err == nil && value >= 0 {
+		policy.maxAttempts = min(value, maxRetryAttempts)
+	}
+	// 先比较毫秒数，避免换算成时间时溢出
+	if value, err := strconv.Atoi(values[SettingRetryBaseDelayMs]); err == nil && value > 0 {
+		policy.baseDelay = time.Duration(min(value, int(maxRetryDelay/time.Millisecond))) * time.Millisecond
+	}
+	return policy
+}
+
+// delay 第attempt次重试（从0开始）前的等待时间，优先使用服务端的 Retry-After
+func (p retryPolicy) delay(attempt int, err error) time.Duration {
+	if retryAfter := providers.RetryAfter(err); retryAfter > 0 {
+		return min(retryAfter, maxRetryDelay)
+	}
+
+	// 达到上限后不再翻倍，避免重试次数较多时溢出
+	delay := min(p.baseDelay, maxRetryDelay)
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	if delay <= 0 {
+		return 0
+	}
+	// 加入随机抖动，避免多个请求同时重试
+	delay += time.Duration(rand.Int64N(int64(delay)/4 + 1))
+	return min(delay, maxRetryDelay)
+}
+
+// loadFallbackModels 读取备用模型列表
+func loadFallbackModels() []FallbackModel {
+	value := settingValues(SettingFallbackModels)[SettingFallbackModels]
+	if value == "" {
+		return nil
+	}
+
+	var items []FallbackModel
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		return nil
+	}
+	return items
+}
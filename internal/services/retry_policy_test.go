@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"grove-studio/internal/database"
+	"grove-studio/internal/models"
+	"grove-studio/internal/providers"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseDelay time.Duration
+		attempt   int
+		err       error
+		want      time.Duration // 抖动前的等待时间
+	}{
+		{name: "第一次重试", baseDelay: time.Second, attempt: 0, want: time.Second},
+		{name: "逐次翻倍", baseDelay: time.Second, attempt: 3, want: 8 * time.Second},
+		{name: "达到上限", baseDelay: time.Second, attempt: 5, want: maxRetryDelay},
+		{name: "移位会溢出的次数", baseDelay: time.Second, attempt: 33, want: maxRetryDelay},
+		{name: "超过位数的次数", baseDelay: time.Millisecond, attempt: 64, want: maxRetryDelay},
+		{name: "很大的次数", baseDelay: time.Nanosecond, attempt: 1000, want: maxRetryDelay},
+		{name: "基础时间超过上限", baseDelay: time.Hour, attempt: 2, want: maxRetryDelay},
+		{name: "没有等待时间", baseDelay: 0, attempt: 3, want: 0},
+		{name: "使用 Retry-After", baseDelay: time.Second, attempt: 0, err: &providers.APIError{StatusCode: 429, RetryAfter: 5 * time.Second}, want: 5 * time.Second},
+		{name: "Retry-After 超过上限", baseDelay: time.Second, attempt: 0, err: &providers.APIError{StatusCode: 429, RetryAfter: time.Hour}, want: maxRetryDelay},
+	}
+	for _, tt := range tests {
+		policy := retryPolicy{maxAttempts: 2, baseDelay: tt.baseDelay}
+		err := tt.err
+		if err == nil {
+			err = errors.New("503")
+		}
+		// 抖动是随机的，多算几次
+		for i := 0; i < 100; i++ {
+			got := policy.delay(tt.attempt, err)
+			if got < tt.want || got > min(tt.want+tt.want/4, maxRetryDelay) {
+				t.Errorf("%s: delay(%d) = %v，期望在 %v 到 %v 之间", tt.name, tt.attempt, got, tt.want, min(tt.want+tt.want/4, maxRetryDelay))
+				break
+			}
+		}
+	}
+}
+
+func TestLoadRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts string
+		baseDelayMs string
+		want        retryPolicy
+	}{
+		{"未设置", "", "", retryPolicy{maxAttempts: defaultRetryMaxAttempts, baseDelay: defaultRetryBaseDelay}},
+		{"不重试", "0", "500", retryPolicy{maxAttempts: 0, baseDelay: 500 * time.Millisecond}},
+		{"设置有误", "-1", "abc", retryPolicy{maxAttempts: defaultRetryMaxAttempts, baseDelay: defaultRetryBaseDelay}},
+		{"超过上限", "1000000", strconv.Itoa(1 << 62), retryPolicy{maxAttempts: maxRetryAttempts, baseDelay: maxRetryDelay}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t)
+			for key, value := range map[string]string{SettingRetryMaxAttempts: tt.maxAttempts, SettingRetryBaseDelayMs: tt.baseDelayMs} {
+				if value == "" {
+					continue
+				}
+				if err := database.DB.Create(&models.Setting{Key: key, Value: value}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := loadRetryPolicy(); got != tt.want {
+				t.Errorf("loadRetryPolicy() = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
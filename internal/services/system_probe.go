@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"grove-studio/internal/sysinfo"
+	"grove-studio/internal/utils"
+	"runtime"
+)
+
+// SystemInfo 本机硬件信息
+type SystemInfo struct {
+	OS              string   `json:"os"`
+	Arch            string   `json:"arch"`
+	CPUName         string   `json:"cpu_name"`
+	CPUCores        int      `json:"cpu_cores"`    // 逻辑核心数
+	CPUFeatures     []string `json:"cpu_features"` // 与推理速度相关的指令集扩展，如 AVX2、NEON
+	TotalMemory     uint64   `json:"total_memory"`
+	AvailableMemory uint64   `json:"available_memory"`
+	DataPath        string   `json:"data_path"`
+	FreeDisk        uint64   `json:"free_disk"` // 数据目录所在磁盘的剩余空间
+}
+
+// SystemProbeService 检测本机硬件，并据此推荐本地模型
+type SystemProbeService struct {
+	ctx    context.Context
+	logger *utils.Logger
+}
+
+// NewSystemProbeService 创建硬件检测服务
+func NewSystemProbeService(ctx context.Context) *SystemProbeService {
+	return &SystemProbeService{
+		ctx:    ctx,
+		logger: utils.NewLogger(ctx),
+	}
+}
+
+// Probe 检测本机硬件，内存和磁盘读取失败时对应的值为0
+func (s *SystemProbeService) Probe() (*SystemInfo, error) {
+	dataPath, err := utils.GetAppDataPath()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SystemInfo{
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		CPUName:     sysinfo.CPUName(),
+		CPUCores:    runtime.NumCPU(),
+		CPUFeatures: sysinfo.CPUFeatures(),
+		DataPath:    dataPath,
+	}
+	if memory, err := sysinfo.ReadMemory(); err != nil {
+		s.logger.Warning("读取内存信息失败: %v", err)
+	} else {
+		info.TotalMemory = memory.Total
+		info.AvailableMemory = memory.Available
+	}
+	if free, err := sysinfo.DiskFree(dataPath); err != nil {
+		s.logger.Warning("读取磁盘空间失败: %v", err)
+	} else {
+		info.FreeDisk = free
+	}
+	return info, nil
+}
+
+// Recommend 检测本机硬件并为模型目录中的模型评分
+func (s *SystemProbeService) Recommend() ([]ModelRecommendation, error) {
+	info, err := s.Probe()
+	if err != nil {
+		return nil, err
+	}
+	return RecommendModels(info, LocalModelCatalog()), nil
+}
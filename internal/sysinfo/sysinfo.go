@@ -0,0 +1,70 @@
+// Package sysinfo 读取本机的CPU、内存和磁盘信息，用于判断本地模型能否运行
+package sysinfo
+
+import (
+	"runtime"
+	"slices"
+
+	"golang.org/x/sys/cpu"
+)
+
+// Memory 内存大小（字节）
+type Memory struct {
+	Total     uint64
+	Available uint64 // 可以分配给新进程的内存，包括可回收的缓存
+}
+
+// CPUName 处理器型号，读取失败时返回空字符串
+func CPUName() string {
+	return cpuName()
+}
+
+// ReadMemory 读取内存大小
+func ReadMemory() (Memory, error) {
+	return readMemory()
+}
+
+// DiskFree 路径所在磁盘当前用户可用的空间（字节）
+func DiskFree(path string) (uint64, error) {
+	return diskFree(path)
+}
+
+// CPUFeatures 与推理速度相关的指令集扩展
+func CPUFeatures() []string {
+	var features []string
+	add := func(name string, ok bool) {
+		if ok {
+			features = append(features, name)
+		}
+	}
+
+	switch runtime.GOARCH {
+	case "amd64", "386":
+		add("SSE3", cpu.X86.HasSSE3)
+		add("SSSE3", cpu.X86.HasSSSE3)
+		add("SSE4.1", cpu.X86.HasSSE41)
+		add("SSE4.2", cpu.X86.HasSSE42)
+		add("AVX", cpu.X86.HasAVX)
+		add("AVX2", cpu.X86.HasAVX2)
+		add("FMA", cpu.X86.HasFMA)
+		add("AVX-VNNI", cpu.X86.HasAVXVNNI)
+		add("AVX512F", cpu.X86.HasAVX512F)
+		add("AVX512BW", cpu.X86.HasAVX512BW)
+		add("AVX512-VNNI", cpu.X86.HasAVX512VNNI)
+		add("AVX512-BF16", cpu.X86.HasAVX512BF16)
+		add("AMX-INT8", cpu.X86.HasAMXInt8)
+	case "arm64":
+		// 部分系统不允许读取特性寄存器，由各平台补充
+		add("NEON", cpu.ARM64.HasASIMD)
+		add("FP16", cpu.ARM64.HasASIMDHP)
+		add("DOTPROD", cpu.ARM64.HasASIMDDP)
+		add("I8MM", cpu.ARM64.HasI8MM)
+		add("SVE", cpu.ARM64.HasSVE)
+		for _, name := range platformFeatures() {
+			if !slices.Contains(features, name) {
+				features = append(features, name)
+			}
+		}
+	}
+	return features
+}
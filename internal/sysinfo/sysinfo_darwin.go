@@ -0,0 +1,45 @@
+package sysinfo
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func cpuName() string {
+	name, err := unix.Sysctl("machdep.cpu.brand_string")
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// readMemory 可用内存按空闲、预读和可清除的页面计算
+func readMemory() (Memory, error) {
+	total, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return Memory{}, err
+	}
+	var pages uint64
+	for _, name := range []string{"vm.page_free_count", "vm.page_speculative_count", "vm.page_purgeable_count"} {
+		if count, err := unix.SysctlUint32(name); err == nil {
+			pages += uint64(count)
+		}
+	}
+	return Memory{Total: total, Available: pages * uint64(unix.Getpagesize())}, nil
+}
+
+// platformFeatures macOS 不允许直接读取ARM特性寄存器，通过 sysctl 查询
+func platformFeatures() []string {
+	var features []string
+	for name, key := range map[string]string{
+		"NEON":    "hw.optional.neon",
+		"FP16":    "hw.optional.arm.FEAT_FP16",
+		"DOTPROD": "hw.optional.arm.FEAT_DotProd",
+		"I8MM":    "hw.optional.arm.FEAT_I8MM",
+		"BF16":    "hw.optional.arm.FEAT_BF16",
+	} {
+		if value, err := unix.SysctlUint32(key); err == nil && value == 1 {
+			features = append(features, name)
+		}
+	}
+	return features
+}
@@ -0,0 +1,67 @@
+package sysinfo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func cpuName() string {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// readMemory 读取 /proc/meminfo
+func readMemory() (Memory, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return Memory{}, err
+	}
+	defer file.Close()
+	return parseMeminfo(file)
+}
+
+// parseMeminfo 解析 /proc/meminfo 格式的内容，数值单位为kB
+func parseMeminfo(r io.Reader) (Memory, error) {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		number, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err == nil {
+			values[key] = number * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Memory{}, err
+	}
+	if values["MemTotal"] == 0 {
+		return Memory{}, errors.New("无法读取内存大小")
+	}
+
+	memory := Memory{Total: values["MemTotal"], Available: values["MemAvailable"]}
+	if _, ok := values["MemAvailable"]; !ok {
+		// 3.14 之前的内核没有 MemAvailable
+		memory.Available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return memory, nil
+}
+
+func platformFeatures() []string {
+	return nil
+}
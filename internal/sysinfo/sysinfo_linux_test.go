@@ -0,0 +1,57 @@
+package sysinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMeminfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Memory
+		wantErr bool
+	}{
+		{
+			name: "16GB",
+			input: `MemTotal:       16384000 kB
+MemFree:         1024000 kB
+MemAvailable:    8192000 kB
+Buffers:          512000 kB
+Cached:          4096000 kB
+HugePages_Total:       0
+`,
+			want: Memory{Total: 16384000 * 1024, Available: 8192000 * 1024},
+		},
+		{
+			name: "没有 MemAvailable 的旧内核",
+			input: `MemTotal:        8192000 kB
+MemFree:         1000000 kB
+Buffers:          200000 kB
+Cached:          2000000 kB
+`,
+			want: Memory{Total: 8192000 * 1024, Available: 3200000 * 1024},
+		},
+		{
+			name:    "没有 MemTotal",
+			input:   "MemFree:         1000000 kB\n",
+			wantErr: true,
+		},
+		{
+			name:    "空内容",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseMeminfo(strings.NewReader(tt.input))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: 没有返回错误", tt.name)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: parseMeminfo = %+v, %v，期望 %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !windows
+
+package sysinfo
+
+import "errors"
+
+func cpuName() string {
+	return ""
+}
+
+func readMemory() (Memory, error) {
+	return Memory{}, errors.ErrUnsupported
+}
+
+func diskFree(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func platformFeatures() []string {
+	return nil
+}
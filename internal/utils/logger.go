@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -12,37 +13,61 @@ type Logger struct {
 	ctx context.Context
 }
 
-// NewLogger 创建新的日志工具实例
+// NewLogger 创建新的日志工具实例，ctx不是Wails传入的上下文时输出到标准日志，用于测试
 func NewLogger(ctx context.Context) *Logger {
 	return &Logger{ctx: ctx}
 }
 
+// standalone 是否不在Wails中运行，Wails传入的上下文中带有它的日志对象
+func (l *Logger) standalone() bool {
+	return l.ctx == nil || l.ctx.Value("logger") == nil
+}
+
 // Info 记录信息日志
 func (l *Logger) Info(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
+	if l.standalone() {
+		log.Printf("INFO: %s", msg)
+		return
+	}
 	runtime.LogInfo(l.ctx, msg)
 }
 
 // Error 记录错误日志
 func (l *Logger) Error(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
+	if l.standalone() {
+		log.Printf("ERROR: %s", msg)
+		return
+	}
 	runtime.LogError(l.ctx, msg)
 }
 
 // Debug 记录调试日志
 func (l *Logger) Debug(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
+	if l.standalone() {
+		log.Printf("DEBUG: %s", msg)
+		return
+	}
 	runtime.LogDebug(l.ctx, msg)
 }
 
 // Fatal 记录致命错误日志
 func (l *Logger) Fatal(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
+	if l.standalone() {
+		log.Fatalf("FATAL: %s", msg)
+	}
 	runtime.LogFatal(l.ctx, msg)
 }
 
 // Warning 记录警告日志
 func (l *Logger) Warning(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
+	if l.standalone() {
+		log.Printf("WARNING: %s", msg)
+		return
+	}
 	runtime.LogWarning(l.ctx, msg)
 }
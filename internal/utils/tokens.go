@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// EstimateTokens 离线估算文本的token数，不依赖具体模型的分词器。
+// 中日韩字符按每字1个token计算，其余字符按每4个字节1个token计算，结果偏保守。
+func EstimateTokens(text string) int {
+	cjk := 0
+	other := 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}